@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"web_AI/models"
+	"web_AI/services"
+)
+
+const cliUsage = `Usage: web_AI <command> [options]
+
+Commands:
+  (none)                     start the HTTP server
+  backup export [-o file]    write procedures, categories, revisions and uploads to a ZIP archive
+  backup import [-mode merge|replace] [-dry-run] [-new-ids] <file>
+                             restore a backup archive
+  migrate categories         link procedures to categories by ID, creating missing categories
+  migrate uploads [-from dir] [-keep] [-dry-run]
+                             move uploaded files from a local directory (default UPLOAD_DIR) into the
+                             configured STORAGE_BACKEND, renaming them to their content hash
+  webhook listen [-addr :9090] [-secret whsec_...]
+                             print webhook deliveries received locally, checking signatures when a secret is given
+`
+
+// runCLI executes a maintenance command and returns the process exit code
+func runCLI(args []string) int {
+	if len(args) >= 2 && args[0] == "backup" {
+		switch args[1] {
+		case "export":
+			return runBackupExport(args[2:])
+		case "import":
+			return runBackupImport(args[2:])
+		}
+	}
+	if len(args) == 2 && args[0] == "migrate" && args[1] == "categories" {
+		return runMigrateCategories()
+	}
+	if len(args) >= 2 && args[0] == "migrate" && args[1] == "uploads" {
+		return runMigrateUploads(args[2:])
+	}
+	if len(args) >= 2 && args[0] == "webhook" && args[1] == "listen" {
+		return runWebhookListen(args[2:])
+	}
+	fmt.Fprint(os.Stderr, cliUsage)
+	return 2
+}
+
+func runMigrateCategories() int {
+	report, err := services.MigrateProcedureCategories()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌ Migration failed:", err)
+		return 1
+	}
+	out, _ := json.MarshalIndent(report, "", "  ")
+	fmt.Println(string(out))
+	return 0
+}
+
+func runMigrateUploads(args []string) int {
+	fs := flag.NewFlagSet("migrate uploads", flag.ContinueOnError)
+	from := fs.String("from", services.UploadDir, "local directory holding the existing uploads")
+	keep := fs.Bool("keep", false, "copy instead of move")
+	dryRun := fs.Bool("dry-run", false, "only report what would be moved")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	report, err := services.MigrateUploads(services.NewLocalStorage(*from), *keep, *dryRun)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌ Migration failed:", err)
+		return 1
+	}
+	out, _ := json.MarshalIndent(report, "", "  ")
+	fmt.Println(string(out))
+	if report.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+func runBackupExport(args []string) int {
+	fs := flag.NewFlagSet("backup export", flag.ContinueOnError)
+	out := fs.String("o", fmt.Sprintf("web_ai_backup_%s.zip", time.Now().Format("20060102_150405")), "output file")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌", err)
+		return 1
+	}
+	defer f.Close()
+
+	manifest, err := services.ExportBackup(f)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌ Backup failed:", err)
+		os.Remove(*out)
+		return 1
+	}
+
+	fmt.Printf("✅ Backup written to %s (%d files)\n", *out, len(manifest.Files))
+	for name, count := range manifest.Collections {
+		fmt.Printf("   %s: %d\n", name, count)
+	}
+	return 0
+}
+
+func runBackupImport(args []string) int {
+	fs := flag.NewFlagSet("backup import", flag.ContinueOnError)
+	mode := fs.String("mode", models.BackupModeMerge, "merge or replace")
+	dryRun := fs.Bool("dry-run", false, "report what would change without writing")
+	newIDs := fs.Bool("new-ids", false, "import everything as copies with new IDs")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprint(os.Stderr, cliUsage)
+		return 2
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌", err)
+		return 1
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "❌", err)
+		return 1
+	}
+
+	report, importErr := services.ImportBackup(f, info.Size(), models.BackupImportOptions{Mode: *mode, DryRun: *dryRun, NewIDs: *newIDs})
+	if report != nil {
+		out, _ := json.MarshalIndent(report, "", "  ")
+		fmt.Println(string(out))
+	}
+	if importErr != nil {
+		fmt.Fprintln(os.Stderr, "❌ Import failed:", importErr)
+		return 1
+	}
+	return 0
+}
+
+// runWebhookListen starts a receiver to try webhooks against, e.g. with the URL http://localhost:9090/
+func runWebhookListen(args []string) int {
+	fs := flag.NewFlagSet("webhook listen", flag.ContinueOnError)
+	addr := fs.String("addr", ":9090", "listen address")
+	secret := fs.String("secret", "", "webhook secret used to verify signatures")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		signature := "not checked"
+		if *secret != "" {
+			if !services.VerifyWebhookSignature(*secret, r.Header.Get(services.WebhookTimestampHeader), body, r.Header.Get(services.WebhookSignatureHeader)) {
+				fmt.Printf("❌ %s %s: invalid signature\n", r.Header.Get(services.WebhookEventHeader), r.Header.Get(services.WebhookDeliveryHeader))
+				http.Error(w, "invalid signature", http.StatusUnauthorized)
+				return
+			}
+			signature = "valid"
+		}
+
+		fmt.Printf("📨 %s %s (signature %s)\n", r.Header.Get(services.WebhookEventHeader), r.Header.Get(services.WebhookDeliveryHeader), signature)
+		var pretty bytes.Buffer
+		if json.Indent(&pretty, body, "", "  ") == nil {
+			fmt.Println(pretty.String())
+		} else {
+			fmt.Println(string(body))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	fmt.Printf("👂 Listening for webhooks on %s\n", *addr)
+	if err := http.ListenAndServe(*addr, handler); err != nil {
+		fmt.Fprintln(os.Stderr, "❌", err)
+		return 1
+	}
+	return 0
+}
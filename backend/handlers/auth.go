@@ -4,6 +4,7 @@ package handlers
 import (
 	"fmt"
 	"net/http"
+	"web_AI/models"
 	"web_AI/services"
 	"web_AI/utils"
 
@@ -55,5 +56,6 @@ func Login(c *gin.Context) {
 	}
 
 	token, _ := utils.GenerateJWT(user.ID.Hex(), user.Role)
+	services.TrackEvent(models.Event{Type: models.EventLogin, UserID: user.ID.Hex()})
 	c.JSON(http.StatusOK, gin.H{"user": user, "token": token})
 }
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+	"web_AI/models"
+	"web_AI/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportBackup handles GET /api/admin/backup
+func ExportBackup(c *gin.Context) {
+	// Build the archive in a temp file first so a failure can still be reported as an error response
+	tmp, err := os.CreateTemp("", "web_ai_backup_*.zip")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create backup file"})
+		return
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if _, err := services.ExportBackup(tmp); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	filename := fmt.Sprintf("web_ai_backup_%s.zip", time.Now().Format("20060102_150405"))
+	c.FileAttachment(tmp.Name(), filename)
+}
+
+// RestoreBackup handles POST /api/admin/backup/restore (multipart: file, mode=merge|replace, dry_run, new_ids)
+func RestoreBackup(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, services.MaxBackupSize)
+	file, err := c.FormFile("file")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Backup file must not exceed %d bytes", int64(services.MaxBackupSize))})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Backup file is required"})
+		return
+	}
+
+	dryRun, _ := strconv.ParseBool(c.DefaultPostForm("dry_run", "false"))
+	newIDs, _ := strconv.ParseBool(c.DefaultPostForm("new_ids", "false"))
+	opts := models.BackupImportOptions{
+		Mode:   c.DefaultPostForm("mode", models.BackupModeMerge),
+		DryRun: dryRun,
+		NewIDs: newIDs,
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Could not read backup file"})
+		return
+	}
+	defer f.Close()
+
+	report, err := services.ImportBackup(f, file.Size, opts)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "report": report})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"web_AI/models"
+	"web_AI/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetCategoryTree handles GET /api/categories/tree
+func GetCategoryTree(c *gin.Context) {
+	tree, err := services.GetCategoryTree(getViewer(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch categories"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.CategoryTreeResponse{
+		Categories: tree,
+		Total:      countCategoryNodes(tree),
+	})
+}
+
+// countCategoryNodes counts the categories in a tree, at every level
+func countCategoryNodes(nodes []*models.CategoryNode) int64 {
+	count := int64(len(nodes))
+	for _, node := range nodes {
+		count += countCategoryNodes(node.Children)
+	}
+	return count
+}
+
+// UpdateCategory handles PUT /api/admin/categories/:id
+func UpdateCategory(c *gin.Context) {
+	var req models.UpdateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category, err := services.UpdateCategory(c.Param("id"), req)
+	if err != nil {
+		c.JSON(categoryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+// DeleteCategory handles DELETE /api/admin/categories/:id?reassign_to=<category id>
+// Without reassign_to the delete is refused with 409 while procedures still use the category.
+func DeleteCategory(c *gin.Context) {
+	result, err := services.DeleteCategory(c.Param("id"), c.Query("reassign_to"))
+	if errors.Is(err, services.ErrCategoryInUse) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":           err.Error(),
+			"procedure_count": result.BlockingProcedures,
+		})
+		return
+	} else if err != nil {
+		c.JSON(categoryErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Category deleted successfully",
+		"result":  result,
+	})
+}
+
+func categoryErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidCategory):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrCategoryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrCategoryInUse):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"web_AI/models"
@@ -44,16 +45,18 @@ func HandleAIChat(c *gin.Context) {
 	}
 
 	// 🤖 Use RAG-enhanced AI call
-	answer, err := services.CallMistralAPIWithRAG(userID, req.Message)
+	result, err := services.AnswerWithRAG(userID, req.Message, models.RAGOptions{BoostTags: req.Tags, Viewer: getViewer(c), Language: requestLanguage(c)})
 	if err != nil {
 		// Fallback to basic AI call if RAG fails
 		fmt.Printf("🔄 RAG failed, falling back to basic AI: %v\n", err)
-		answer, err = services.CallMistralAPIWithHistory(userID, req.Message)
+		answer, err := services.CallMistralAPIWithHistory(userID, req.Message)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
+		result = &models.RAGAnswer{Answer: answer}
 	}
+	answer := result.Answer
 
 	// 💾 Save conversation if user is authenticated
 	var conversation *models.ChatConversation
@@ -65,8 +68,11 @@ func HandleAIChat(c *gin.Context) {
 		}
 	}
 
+	services.TrackEvent(models.Event{Type: models.EventChat, UserID: userID})
+
 	response := models.ChatResponse{
-		Response: answer,
+		Response:   answer,
+		QuestionID: logQuestion(c, userID, req.Message, models.QuestionSourceChat, result, nil),
 	}
 
 	if conversation != nil {
@@ -119,7 +125,7 @@ func GetChatConversation(c *gin.Context) {
 	c.JSON(http.StatusOK, conversation)
 }
 
-// DeleteChatConversation deletes a conversation
+// DeleteChatConversation moves a conversation to the trash
 func DeleteChatConversation(c *gin.Context) {
 	hex, ok := getUserHexFromContext(c)
 	if !ok {
@@ -134,14 +140,16 @@ func DeleteChatConversation(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Conversation deleted successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Conversation moved to trash"})
 }
 
 // HandleProcedureAIChat handles AI questions specifically about procedures
 func HandleProcedureAIChat(c *gin.Context) {
 	var req struct {
-		Question    string `json:"question" binding:"required"`
-		ProcedureID string `json:"procedure_id,omitempty"`
+		Question    string   `json:"question" binding:"required"`
+		ProcedureID string   `json:"procedure_id,omitempty"`
+		Step        string   `json:"step,omitempty"` // e.g. "2" or "2.1", requires procedure_id
+		Tags        []string `json:"tags,omitempty"` // boost procedures with these tags when no procedure_id is given
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -159,36 +167,38 @@ func HandleProcedureAIChat(c *gin.Context) {
 
 	var answer string
 	var err error
+	event := models.Event{Type: models.EventChat, UserID: userID}
+	source := models.QuestionSourceChat
+	result := &models.RAGAnswer{}
 
 	// If specific procedure ID provided, get that procedure
 	if req.ProcedureID != "" {
-		procedure, procErr := services.GetProcedureByID(req.ProcedureID)
+		procedure, procErr := getLocalizedProcedure(c, req.ProcedureID)
 		if procErr != nil {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Không tìm thấy quy trình"})
 			return
 		}
 
-		// Create specific prompt for this procedure
-		specificPrompt := fmt.Sprintf(`Dựa trên quy trình "%s" sau:
-
-**Tiêu đề:** %s
-**Danh mục:** %s  
-**Mô tả:** %s
-**Nội dung:**
-%s
-
----
-
-**Câu hỏi:** %s
-
-Hãy trả lời câu hỏi dựa trên thông tin quy trình trên.`,
-			procedure.Title, procedure.Title, procedure.Category,
-			procedure.Description, procedure.Content, req.Question)
+		// Create specific prompt for this procedure (or one of its steps)
+		specificPrompt, promptErr := services.BuildProcedurePrompt(procedure, req.Step, req.Question, requestLanguage(c))
+		if promptErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": promptErr.Error()})
+			return
+		}
 
+		event.ProcedureID = &procedure.ID
+		source = models.QuestionSourceProcedureChat
+		result = &models.RAGAnswer{Hits: 1, TopScore: 1, ProcedureIDs: []primitive.ObjectID{procedure.ID}}
 		answer, err = services.CallMistralAPIWithHistory(userID, specificPrompt)
+		if err == nil {
+			answer += services.StaleProcedureWarning([]models.Procedure{*procedure})
+		}
 	} else {
 		// Use RAG for general procedure questions
-		answer, err = services.CallMistralAPIWithRAG(userID, req.Question)
+		result, err = services.AnswerWithRAG(userID, req.Question, models.RAGOptions{BoostTags: req.Tags, Viewer: getViewer(c), Language: requestLanguage(c)})
+		if err == nil {
+			answer = result.Answer
+		}
 	}
 
 	if err != nil {
@@ -196,7 +206,52 @@ Hãy trả lời câu hỏi dựa trên thông tin quy trình trên.`,
 		return
 	}
 
-	c.JSON(http.StatusOK, models.AskResponse{Answer: answer})
+	services.TrackEvent(event)
+	c.JSON(http.StatusOK, models.AskResponse{
+		Answer:     answer,
+		QuestionID: logQuestion(c, userID, req.Question, source, result, event.ProcedureID),
+	})
+}
+
+// logQuestion records a chat question for analytics and returns its ID for answer feedback,
+// or "" if it could not be stored
+func logQuestion(c *gin.Context, userID string, question string, source string, result *models.RAGAnswer, procedureID *primitive.ObjectID) string {
+	log := &models.QuestionLog{
+		UserID:       userID,
+		Question:     question,
+		Source:       source,
+		Language:     requestLanguage(c),
+		ProcedureID:  procedureID,
+		Hits:         result.Hits,
+		TopScore:     result.TopScore,
+		RetrievedIDs: result.ProcedureIDs,
+	}
+	if err := services.LogQuestion(log); err != nil {
+		fmt.Printf("⚠️ Failed to log question: %v\n", err)
+		return ""
+	}
+	return log.ID.Hex()
+}
+
+// SubmitQuestionFeedback handles POST /api/chat/questions/:id/feedback
+func SubmitQuestionFeedback(c *gin.Context) {
+	var req models.QuestionFeedbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID, _ := getUserHexFromContext(c)
+	if err := services.SubmitQuestionFeedback(c.Param("id"), userID, *req.Helpful, req.Comment); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrQuestionNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Feedback recorded"})
 }
 
 func GetHistory(c *gin.Context) {
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"web_AI/models"
+	"web_AI/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// checkDuplicates looks for existing procedures similar to the one being saved. Near-identical ones
+// answer 409 with the matches unless the request has ?force=true; it returns false when it responded.
+func checkDuplicates(c *gin.Context, procedure *models.Procedure, excludeID primitive.ObjectID) ([]models.DuplicateMatch, bool) {
+	force, _ := strconv.ParseBool(c.Query("force"))
+	matches, err := services.CheckDuplicates(procedure, excludeID, force)
+	if errors.Is(err, services.ErrDuplicateProcedure) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":      err.Error() + "; resend with ?force=true to save anyway",
+			"duplicates": matches,
+		})
+		return nil, false
+	} else if err != nil {
+		// The check is advisory, so it never stops the save
+		fmt.Printf("⚠️ Duplicate check failed: %v\n", err)
+	}
+	return matches, true
+}
+
+// GetDuplicateProcedures handles GET /api/admin/procedures/duplicates?threshold=0.5
+func GetDuplicateProcedures(c *gin.Context) {
+	threshold := services.DuplicateWarnThreshold()
+	if t, err := strconv.ParseFloat(c.Query("threshold"), 64); err == nil && t > 0 && t <= 1 {
+		threshold = t
+	}
+
+	pairs, err := services.GetDuplicatePairs(threshold)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.DuplicatePairsResponse{
+		Pairs:     pairs,
+		Total:     int64(len(pairs)),
+		Threshold: threshold,
+	})
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"web_AI/models"
+	"web_AI/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// shouldEnrich reports whether a saved procedure gets an AI enrichment: ?enrich= when sent, AUTO_ENRICH otherwise
+func shouldEnrich(c *gin.Context) bool {
+	if enrich, err := strconv.ParseBool(c.Query("enrich")); err == nil {
+		return enrich
+	}
+	return services.AutoEnrichEnabled()
+}
+
+// GetProcedureEnrichment handles GET /api/admin/procedures/:id/enrichment
+func GetProcedureEnrichment(c *gin.Context) {
+	enrichment, err := services.GetEnrichment(c.Param("id"))
+	if err != nil {
+		c.JSON(enrichmentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, enrichment)
+}
+
+// GenerateProcedureEnrichment handles POST /api/admin/procedures/:id/enrichment/generate
+// The AI runs in the background; poll GET .../enrichment until "generating" is gone.
+func GenerateProcedureEnrichment(c *gin.Context) {
+	enrichment, err := services.StartEnrichment(c.Param("id"), getActorFromContext(c))
+	if err != nil {
+		c.JSON(enrichmentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, enrichment)
+}
+
+// UpdateProcedureEnrichment handles PUT /api/admin/procedures/:id/enrichment
+func UpdateProcedureEnrichment(c *gin.Context) {
+	var req models.UpdateEnrichmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	enrichment, err := services.UpdateEnrichment(c.Param("id"), req, getActorFromContext(c))
+	if err != nil {
+		c.JSON(enrichmentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, enrichment)
+}
+
+// ApproveProcedureEnrichment handles POST /api/admin/procedures/:id/enrichment/approve
+func ApproveProcedureEnrichment(c *gin.Context) {
+	var req models.ApproveEnrichmentRequest
+	// The body is optional
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	enrichment, err := services.ApproveEnrichment(c.Param("id"), req.ReplaceDescription, getActorFromContext(c))
+	if err != nil {
+		c.JSON(enrichmentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, enrichment)
+}
+
+// DeleteProcedureEnrichment handles DELETE /api/admin/procedures/:id/enrichment
+func DeleteProcedureEnrichment(c *gin.Context) {
+	if err := services.DeleteEnrichment(c.Param("id")); err != nil {
+		c.JSON(enrichmentErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Enrichment deleted"})
+}
+
+func enrichmentErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrProcedureNotFound), errors.Is(err, services.ErrEnrichmentNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrEnrichmentInProgress):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrInvalidEnrichment):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"web_AI/models"
+	"web_AI/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateProcedure handles PUT /api/procedures/:id/rating
+func RateProcedure(c *gin.Context) {
+	var req models.RateProcedureRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rating, err := services.RateProcedure(c.Param("id"), getViewer(c), *req.Helpful)
+	if err != nil {
+		c.JSON(feedbackErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rating)
+}
+
+// RemoveProcedureRating handles DELETE /api/procedures/:id/rating
+func RemoveProcedureRating(c *gin.Context) {
+	rating, err := services.RemoveRating(c.Param("id"), getViewer(c))
+	if err != nil {
+		c.JSON(feedbackErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rating)
+}
+
+// GetProcedureComments handles GET /api/procedures/:id/comments?type=comment|clarification
+func GetProcedureComments(c *gin.Context) {
+	comments, err := services.GetComments(c.Param("id"), getViewer(c), c.Query("type"))
+	if err != nil {
+		c.JSON(feedbackErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.CommentsResponse{
+		Comments: comments,
+		Total:    int64(len(comments)),
+	})
+}
+
+// AddProcedureComment handles POST /api/procedures/:id/comments
+func AddProcedureComment(c *gin.Context) {
+	var req models.CreateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	comment, err := services.AddComment(c.Param("id"), getViewer(c), req)
+	if err != nil {
+		c.JSON(feedbackErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, comment)
+}
+
+// GetFeedbackReport handles GET /api/admin/feedback?sort=lowest_rated|most_commented&limit=
+func GetFeedbackReport(c *gin.Context) {
+	sortBy := c.DefaultQuery("sort", models.FeedbackSortLowestRated)
+	var limit int64 = 50
+	if l, err := strconv.ParseInt(c.Query("limit"), 10, 64); err == nil && l > 0 && l <= 500 {
+		limit = l
+	}
+
+	procedures, err := services.GetFeedbackReport(sortBy, limit)
+	if err != nil {
+		c.JSON(feedbackErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.FeedbackReportResponse{
+		Procedures: procedures,
+		Total:      int64(len(procedures)),
+		Sort:       sortBy,
+	})
+}
+
+// ResolveProcedureComment handles POST /api/admin/comments/:id/resolve
+func ResolveProcedureComment(c *gin.Context) {
+	comment, err := services.ResolveComment(c.Param("id"), getActorFromContext(c))
+	if err != nil {
+		c.JSON(feedbackErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, comment)
+}
+
+func feedbackErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrProcedureNotFound), errors.Is(err, services.ErrCommentNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInvalidFeedback):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"web_AI/models"
+	"web_AI/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetProcedureFile handles GET /api/procedures/:id/file?inline=true
+// Streams the originally uploaded file of a procedure the caller may see; Range requests are supported.
+// A link from GetProcedureFileLink (?expires=&signature=) works without authentication until it expires.
+func GetProcedureFile(c *gin.Context) {
+	id := c.Param("id")
+
+	var procedure *models.Procedure
+	var err error
+	if signature := c.Query("signature"); signature != "" {
+		// The link stands in for the caller's access, but only while the procedure is published
+		procedure, err = services.GetPublishedProcedureByID(id, models.Viewer{IsAdmin: true})
+		if err == nil {
+			err = services.VerifyFileLink(procedure, c.Query("expires"), signature)
+		}
+	} else {
+		procedure, err = services.GetPublishedProcedureByID(id, getViewer(c))
+	}
+	if errors.Is(err, services.ErrInvalidFileLink) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	file, info, err := services.OpenProcedureFile(procedure)
+	if err != nil {
+		c.JSON(fileErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	name := procedure.FileName
+	if name == "" {
+		name = path.Base(procedure.FileKey)
+	}
+	disposition := "attachment"
+	if inline, _ := strconv.ParseBool(c.Query("inline")); inline {
+		disposition = "inline"
+	}
+
+	c.Header("Content-Type", services.ContentTypeForFile(procedure.FileKey))
+	c.Header("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": name}))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Header("Cache-Control", "private, no-cache")
+	// Stored names are content hashes, so the name without extension is a strong ETag
+	c.Header("ETag", `"`+strings.TrimSuffix(path.Base(procedure.FileKey), path.Ext(procedure.FileKey))+`"`)
+	http.ServeContent(c.Writer, c.Request, name, info.ModTime, file)
+}
+
+// GetProcedureFileLink handles GET /api/procedures/:id/file/link
+// Returns a short-lived link to the original file, e.g. for opening it in a new browser tab.
+func GetProcedureFileLink(c *gin.Context) {
+	procedure, err := services.GetPublishedProcedureByID(c.Param("id"), getViewer(c))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	link, err := services.CreateFileLink(procedure)
+	if err != nil {
+		c.JSON(fileErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, link)
+}
+
+func fileErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidFileLink):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrNoProcedureFile):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
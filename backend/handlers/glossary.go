@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"web_AI/models"
+	"web_AI/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetGlossaryEntries handles GET /api/admin/glossary
+func GetGlossaryEntries(c *gin.Context) {
+	entries, err := services.GetGlossaryEntries()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch glossary"})
+		return
+	}
+
+	response := models.GlossaryResponse{
+		Entries: entries,
+		Total:   int64(len(entries)),
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateGlossaryEntry handles POST /api/admin/glossary
+func CreateGlossaryEntry(c *gin.Context) {
+	var req models.CreateGlossaryEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entry, err := services.CreateGlossaryEntry(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, entry)
+}
+
+// UpdateGlossaryEntry handles PUT /api/admin/glossary/:id
+func UpdateGlossaryEntry(c *gin.Context) {
+	id := c.Param("id")
+
+	var req models.UpdateGlossaryEntryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entry, err := services.UpdateGlossaryEntry(id, req)
+	if errors.Is(err, services.ErrGlossaryEntryNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+// DeleteGlossaryEntry handles DELETE /api/admin/glossary/:id
+func DeleteGlossaryEntry(c *gin.Context) {
+	id := c.Param("id")
+
+	err := services.DeleteGlossaryEntry(id)
+	if errors.Is(err, services.ErrGlossaryEntryNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Glossary entry deleted successfully"})
+}
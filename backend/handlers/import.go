@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"web_AI/models"
+	"web_AI/services"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GetProcedureImports handles GET /api/admin/imports?status=pending|accepted|rejected
+func GetProcedureImports(c *gin.Context) {
+	imports, err := services.GetProcedureImports(c.Query("status"))
+	if err != nil {
+		c.JSON(importErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ImportsResponse{
+		Imports: imports,
+		Total:   int64(len(imports)),
+	})
+}
+
+// GetProcedureImport handles GET /api/admin/imports/:id
+func GetProcedureImport(c *gin.Context) {
+	imp, err := services.GetProcedureImport(c.Param("id"))
+	if err != nil {
+		c.JSON(importErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, imp)
+}
+
+// AcceptProcedureImport handles POST /api/admin/imports/:id/accept
+// The body may correct the title, description, steps or category first. Near-duplicates of existing
+// procedures answer 409 unless ?force=true; ?enrich= works as for uploads.
+func AcceptProcedureImport(c *gin.Context) {
+	var req models.AcceptImportRequest
+	// The body is optional: without it the preview is accepted as is
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	actor := getActorFromContext(c)
+	imp, procedure, err := services.PrepareImportedProcedure(c.Param("id"), req, actor)
+	if err != nil {
+		c.JSON(importErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	excludeID := primitive.NilObjectID
+	if imp.ProcedureID != nil {
+		excludeID = *imp.ProcedureID
+	}
+	duplicates, ok := checkDuplicates(c, procedure, excludeID)
+	if !ok {
+		return
+	}
+
+	procedure, err = services.AcceptProcedureImport(imp, procedure, actor)
+	if err != nil {
+		c.JSON(importErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	if shouldEnrich(c) {
+		services.EnrichInBackground(procedure.ID, actor)
+	}
+
+	procedure.PossibleDuplicates = duplicates
+	setProcedureETag(c, procedure)
+	c.JSON(http.StatusOK, procedure)
+}
+
+// RejectProcedureImport handles POST /api/admin/imports/:id/reject
+func RejectProcedureImport(c *gin.Context) {
+	imp, err := services.RejectProcedureImport(c.Param("id"), getActorFromContext(c))
+	if err != nil {
+		c.JSON(importErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, imp)
+}
+
+func importErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrStructuringFailed):
+		return http.StatusBadGateway
+	case errors.Is(err, services.ErrImportNotFound), errors.Is(err, services.ErrProcedureNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInvalidImport), isProcedureInputError(err):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+	"web_AI/models"
+	"web_AI/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetNotifications handles GET /api/notifications?unread=true
+func GetNotifications(c *gin.Context) {
+	userID := getActorFromContext(c)
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	notifications, unread, err := services.GetNotifications(userID, c.Query("unread") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.NotificationsResponse{
+		Notifications: notifications,
+		Total:         int64(len(notifications)),
+		Unread:        unread,
+	})
+}
+
+// MarkNotificationRead handles POST /api/notifications/:id/read
+func MarkNotificationRead(c *gin.Context) {
+	userID := getActorFromContext(c)
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	if err := services.MarkNotificationRead(c.Param("id"), userID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification marked as read"})
+}
@@ -1,8 +1,11 @@
 package handlers
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"net/http"
-	"os"
+	"strconv"
 	"strings"
 	"web_AI/models"
 	"web_AI/services"
@@ -14,16 +17,20 @@ import (
 	"github.com/unidoc/unioffice/document"
 )
 
-// GetProcedures handles GET /api/procedures
+// GetProcedures handles GET /api/procedures?category=&tags=a,b&tag_mode=any|all
 func GetProcedures(c *gin.Context) {
-	category := c.Query("category")
-	limit := int64(0) // No limit by default
+	query, err := parseProcedureQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	procedures, err := services.GetProcedures(category, limit)
+	procedures, err := services.GetProcedures(query)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch procedures"})
 		return
 	}
+	services.LocalizeProcedures(procedures, query.Language)
 
 	response := models.ProceduresResponse{
 		Procedures: procedures,
@@ -37,19 +44,26 @@ func GetProcedures(c *gin.Context) {
 func GetProcedureById(c *gin.Context) {
 	id := c.Param("id")
 
-	procedure, err := services.GetProcedureByID(id)
+	procedure, err := getLocalizedProcedure(c, id)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
 
+	services.TrackEvent(models.Event{Type: models.EventProcedureView, ProcedureID: &procedure.ID, UserID: getActorFromContext(c)})
+	setProcedureETag(c, procedure)
 	c.JSON(http.StatusOK, procedure)
 }
 
-// SearchProcedures handles GET /api/procedures/search
+// SearchProcedures handles GET /api/procedures/search?q=&category=&tags=&tag_mode=
 func SearchProcedures(c *gin.Context) {
-	query := c.Query("q")
-	if query == "" {
+	query, err := parseProcedureQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	query.Search = c.Query("q")
+	if query.Search == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Search query is required"})
 		return
 	}
@@ -59,6 +73,11 @@ func SearchProcedures(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search procedures"})
 		return
 	}
+	services.TrackEvent(models.Event{Type: models.EventSearch, UserID: getActorFromContext(c), Query: query.Search})
+	if len(procedures) == 0 {
+		services.RecordSearchMiss(query.Search, getActorFromContext(c))
+	}
+	services.LocalizeProcedures(procedures, query.Language)
 
 	response := models.ProceduresResponse{
 		Procedures: procedures,
@@ -68,15 +87,64 @@ func SearchProcedures(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// SuggestProcedures handles GET /api/procedures/suggest
+func SuggestProcedures(c *gin.Context) {
+	query := c.Query("q")
+	if strings.TrimSpace(query) == "" {
+		c.JSON(http.StatusOK, models.SuggestionsResponse{Suggestions: []models.Suggestion{}, Total: 0})
+		return
+	}
+
+	limit := 10
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 && l <= 50 {
+		limit = l
+	}
+
+	suggestions, err := services.SuggestProcedures(query, limit, getViewer(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch suggestions"})
+		return
+	}
+
+	response := models.SuggestionsResponse{
+		Suggestions: suggestions,
+		Total:       int64(len(suggestions)),
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ExportProcedure handles GET /api/procedures/:id/export?format=docx|pdf|md
+func ExportProcedure(c *gin.Context) {
+	id := c.Param("id")
+	format := strings.ToLower(c.DefaultQuery("format", services.ExportFormatPDF))
+
+	procedure, err := getLocalizedProcedure(c, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	data, contentType, filename, err := services.ExportProcedure(procedure, format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Data(http.StatusOK, contentType, data)
+}
+
 // GetProceduresByCategory handles GET /api/procedures/category/:category
 func GetProceduresByCategory(c *gin.Context) {
 	category := c.Param("category")
 
-	procedures, err := services.GetProceduresByCategory(category)
+	procedures, err := services.GetProceduresByCategory(category, getViewer(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch procedures by category"})
 		return
 	}
+	services.LocalizeProcedures(procedures, requestLanguage(c))
 
 	response := models.ProceduresResponse{
 		Procedures: procedures,
@@ -87,40 +155,86 @@ func GetProceduresByCategory(c *gin.Context) {
 }
 
 // CreateProcedure handles POST /api/admin/procedures
+// With ?enrich=true (or AUTO_ENRICH) the AI drafts a description, summary and FAQ in the background.
 func CreateProcedure(c *gin.Context) {
 	var req models.CreateProcedureRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if strings.TrimSpace(req.Content) == "" && len(req.Steps) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Content or steps are required"})
+		return
+	}
+	if req.Category == "" && req.CategoryID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Category or category_id is required"})
+		return
+	}
+	categoryID, err := parseCategoryID(req.CategoryID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// Get admin user ID from context (set by JWT middleware)
-	var createdBy primitive.ObjectID
-	if userID, exists := c.Get("user_id"); exists {
-		if objID, ok := userID.(primitive.ObjectID); ok {
-			createdBy = objID
-		}
-	}
+	actor := getActorFromContext(c)
+	createdBy, _ := primitive.ObjectIDFromHex(actor)
 
 	// Convert request to procedure model
 	procedure := &models.Procedure{
 		Title:       req.Title,
 		Content:     req.Content,
+		Steps:       req.Steps,
 		Category:    req.Category,
+		CategoryID:  categoryID,
+		Tags:        req.Tags,
 		Description: req.Description,
 		CreatedBy:   createdBy,
+		UpdatedBy:   actor,
+		ReviewerID:  req.ReviewerID,
+
+		OwnerID:            req.OwnerID,
+		EffectiveDate:      req.EffectiveDate,
+		ExpiryDate:         req.ExpiryDate,
+		ReviewIntervalDays: req.ReviewIntervalDays,
+
+		ProcedureAccess: models.ProcedureAccess{
+			Visibility:         req.Visibility,
+			VisibleDepartments: req.VisibleDepartments,
+			VisibleRoles:       req.VisibleRoles,
+		},
+		SourceLanguage: req.SourceLanguage,
+	}
+	if procedure.OwnerID == "" {
+		procedure.OwnerID = actor
 	}
 
-	err := services.CreateProcedure(procedure)
-	if err != nil {
+	duplicates, ok := checkDuplicates(c, procedure, primitive.NilObjectID)
+	if !ok {
+		return
+	}
+
+	err = services.CreateProcedure(procedure)
+	if isProcedureInputError(err) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create procedure"})
 		return
 	}
 
+	if shouldEnrich(c) {
+		services.EnrichInBackground(procedure.ID, actor)
+	}
+
+	procedure.PossibleDuplicates = duplicates
+	setProcedureETag(c, procedure)
 	c.JSON(http.StatusCreated, procedure)
 }
 
 // UpdateProcedure handles PUT /api/admin/procedures/:id
+// Send If-Match with the ETag (or "version" in the body) to fail with 409 instead of overwriting someone else's changes.
+// Editing a procedure that is in review takes it back to draft, so it has to be submitted again.
 func UpdateProcedure(c *gin.Context) {
 	id := c.Param("id")
 
@@ -129,18 +243,38 @@ func UpdateProcedure(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	categoryID, err := parseCategoryID(req.CategoryID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	expectedVersion, err := parseIfMatch(c, req.Version)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// Convert request to procedure model
 	procedure := &models.Procedure{
 		Title:       req.Title,
 		Content:     req.Content,
+		Steps:       req.Steps,
 		Category:    req.Category,
+		CategoryID:  categoryID,
+		Tags:        req.Tags,
 		Description: req.Description,
+		UpdatedBy:   getActorFromContext(c),
+	}
+
+	objID, _ := primitive.ObjectIDFromHex(id)
+	duplicates, ok := checkDuplicates(c, procedure, objID)
+	if !ok {
+		return
 	}
 
-	err := services.UpdateProcedure(id, procedure)
+	err = services.UpdateProcedure(id, procedure, expectedVersion)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondProcedureSaveError(c, id, err)
 		return
 	}
 
@@ -151,29 +285,166 @@ func UpdateProcedure(c *gin.Context) {
 		return
 	}
 
+	updatedProcedure.PossibleDuplicates = duplicates
+	setProcedureETag(c, updatedProcedure)
 	c.JSON(http.StatusOK, updatedProcedure)
 }
 
+// PatchProcedure handles PATCH /api/admin/procedures/:id
+// Only the fields present in the body are changed; If-Match works as for PUT.
+func PatchProcedure(c *gin.Context) {
+	id := c.Param("id")
+
+	var req models.PatchProcedureRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	expectedVersion, err := parseIfMatch(c, req.Version)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Only a changed text can make the procedure a duplicate of another one
+	var duplicates []models.DuplicateMatch
+	if req.Title != nil || req.Content != nil || req.Steps != nil {
+		current, err := services.GetProcedureByID(id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		if req.Title != nil {
+			current.Title = *req.Title
+		}
+		if req.Content != nil {
+			current.Content = *req.Content
+		}
+		if req.Steps != nil {
+			// Content generated from the old steps is regenerated from the new ones unless content was sent
+			if req.Content == nil && current.Content == services.RenderStepsContent(current.Steps) {
+				current.Content = ""
+			}
+			current.Steps = *req.Steps
+		}
+		var ok bool
+		if duplicates, ok = checkDuplicates(c, current, current.ID); !ok {
+			return
+		}
+	}
+
+	procedure, err := services.PatchProcedure(id, req, getActorFromContext(c), expectedVersion)
+	if err != nil {
+		respondProcedureSaveError(c, id, err)
+		return
+	}
+
+	procedure.PossibleDuplicates = duplicates
+	setProcedureETag(c, procedure)
+	c.JSON(http.StatusOK, procedure)
+}
+
+// setProcedureETag sends the procedure version as a strong ETag
+func setProcedureETag(c *gin.Context, procedure *models.Procedure) {
+	c.Header("ETag", strconv.Quote(strconv.FormatInt(procedure.Version, 10)))
+}
+
+// parseIfMatch returns the version the client expects from the If-Match header, falling back to
+// the version sent in the body; nil means the client did not ask for a check
+func parseIfMatch(c *gin.Context, bodyVersion *int64) (*int64, error) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return bodyVersion, nil
+	}
+
+	tag := strings.TrimPrefix(header, "W/")
+	if unquoted, err := strconv.Unquote(tag); err == nil {
+		tag = unquoted
+	}
+	version, err := strconv.ParseInt(tag, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid If-Match header")
+	}
+	return &version, nil
+}
+
+// respondProcedureSaveError maps errors from saving a procedure to a response; version
+// conflicts get 409 with the current version so the client can reload and merge
+func respondProcedureSaveError(c *gin.Context, id string, err error) {
+	switch {
+	case errors.Is(err, services.ErrVersionConflict):
+		current, getErr := services.GetProcedureByID(id)
+		if getErr != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		setProcedureETag(c, current)
+		c.JSON(http.StatusConflict, gin.H{
+			"error":           err.Error(),
+			"current_version": current.Version,
+		})
+	case isProcedureInputError(err):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrProcedureNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
 // DeleteProcedure handles DELETE /api/admin/procedures/:id
+// The procedure goes to the trash and can be restored until it is purged.
 func DeleteProcedure(c *gin.Context) {
 	id := c.Param("id")
 
-	err := services.DeleteProcedure(id)
-	if err != nil {
+	err := services.DeleteProcedure(id, getActorFromContext(c))
+	if errors.Is(err, services.ErrProcedureNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Procedure deleted successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Procedure moved to trash"})
 }
 
 // UploadProcedureFile handles POST /api/admin/procedures/upload
+// When procedure_id is sent, the file replaces the content of that procedure as a new revision.
+// With ?enrich=true (or AUTO_ENRICH) the AI drafts a description, summary and FAQ in the background.
+// With ?structure=true the AI converts the file into steps instead, returning an import to review
+// (see AcceptProcedureImport); the title is then optional.
 func UploadProcedureFile(c *gin.Context) {
 	// Get form values
 	title := c.PostForm("title")
 	category := c.PostForm("category")
+	procedureID := c.PostForm("procedure_id")
+	categoryID, err := parseCategoryID(c.PostForm("category_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var existing *models.Procedure
+	if procedureID != "" {
+		var err error
+		existing, err = services.GetProcedureByID(procedureID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Procedure not found"})
+			return
+		}
+		if title == "" {
+			title = existing.Title
+		}
+		if category == "" && categoryID.IsZero() {
+			category = existing.Category
+			categoryID = existing.CategoryID
+		}
+	}
 
-	if title == "" || category == "" {
+	// Khi chuyển đổi bằng AI, tiêu đề có thể lấy từ nội dung file
+	structure, _ := strconv.ParseBool(c.Query("structure"))
+	if (title == "" && !structure) || (category == "" && categoryID.IsZero()) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Title and category are required"})
 		return
 	}
@@ -185,68 +456,46 @@ func UploadProcedureFile(c *gin.Context) {
 		return
 	}
 
-	// Validate file type
-	allowedTypes := map[string]bool{
-		"application/pdf":    true,
-		"application/msword": true,
-		"application/vnd.openxmlformats-officedocument.wordprocessingml.document": true,
-	}
-
-	fileHeader := file.Header.Get("Content-Type")
-	if !allowedTypes[fileHeader] {
+	// Kiểm tra loại file theo nội dung (không tin Content-Type và tên file do client gửi),
+	// lưu theo mã băm SHA-256 nên file trùng nội dung chỉ được lưu một lần
+	stored, data, err := services.SaveUpload(file, getActorFromContext(c))
+	if errors.Is(err, services.ErrUnsupportedFileType) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Only PDF and Word documents are allowed"})
 		return
-	}
-
-	// Check file size (10MB limit)
-	maxSize := int64(10 * 1024 * 1024) // 10MB
-	if file.Size > maxSize {
+	} else if errors.Is(err, services.ErrFileTooLarge) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "File size cannot exceed 10MB"})
 		return
-	}
-
-	// Save file to uploads directory
-	uploadDir := "uploads"
-	if err := ensureDir(uploadDir); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not create upload directory"})
-		return
-	}
-	filePath := uploadDir + "/" + file.Filename
-	if err := c.SaveUploadedFile(file, filePath); err != nil {
+	} else if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save file"})
 		return
 	}
 
 	// Trích xuất nội dung file nếu là PDF hoặc Word
 	contentText := ""
-	switch fileHeader {
-	case "application/pdf":
-		f, err := os.Open(filePath)
+	switch stored.ContentType {
+	case services.ContentTypePDF:
+		r, err := pdf.NewReader(bytes.NewReader(data), stored.Size)
 		if err == nil {
-			defer f.Close()
-			r, err := pdf.NewReader(f, file.Size)
-			if err == nil {
-				var sb strings.Builder
-				n := r.NumPage()
-				for i := 1; i <= n; i++ {
-					p := r.Page(i)
-					if p.V.IsNull() {
-						continue
-					}
-					text, _ := p.GetPlainText(nil)
-					// Xử lý: thêm khoảng trắng sau bullet, loại ký tự lạ, giữ xuống dòng
-					text = strings.ReplaceAll(text, "", "\n- ")
-					text = strings.ReplaceAll(text, "-[]", "- [ ] ")
-					text = strings.ReplaceAll(text, "\u00a0", " ") // non-breaking space
-					text = strings.ReplaceAll(text, "\u200b", "")  // zero-width space
-					text = strings.ReplaceAll(text, "\n", "\n")    // giữ xuống dòng
-					sb.WriteString(text)
+			var sb strings.Builder
+			n := r.NumPage()
+			for i := 1; i <= n; i++ {
+				p := r.Page(i)
+				if p.V.IsNull() {
+					continue
 				}
-				contentText = sb.String()
+				text, _ := p.GetPlainText(nil)
+				// Xử lý: thêm khoảng trắng sau bullet, loại ký tự lạ, giữ xuống dòng
+				text = strings.ReplaceAll(text, "", "\n- ")
+				text = strings.ReplaceAll(text, "-[]", "- [ ] ")
+				text = strings.ReplaceAll(text, "\u00a0", " ") // non-breaking space
+				text = strings.ReplaceAll(text, "\u200b", "")  // zero-width space
+				text = strings.ReplaceAll(text, "\n", "\n")    // giữ xuống dòng
+				sb.WriteString(text)
 			}
+			contentText = sb.String()
 		}
-	case "application/vnd.openxmlformats-officedocument.wordprocessingml.document":
-		doc, err := document.Open(filePath)
+	case services.ContentTypeDOCX:
+		doc, err := document.Read(bytes.NewReader(data), stored.Size)
 		if err == nil {
 			var sb strings.Builder
 			for _, para := range doc.Paragraphs() {
@@ -256,35 +505,199 @@ func UploadProcedureFile(c *gin.Context) {
 				sb.WriteString("\n")
 			}
 			contentText = sb.String()
+		} else if text, err := services.ExtractDOCXText(data); err == nil {
+			// unioffice cần license key; đọc trực tiếp XML của file Word
+			contentText = text
 		}
 	}
-	if contentText == "" {
-		contentText = filePath // fallback: lưu đường dẫn file nếu không trích xuất được
+	extracted := contentText != ""
+	actor := getActorFromContext(c)
+
+	// Chuyển đổi bằng AI: chỉ tạo bản xem trước, quy trình được tạo khi admin chấp nhận
+	if structure {
+		if !extracted {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Could not read any text from the file"})
+			return
+		}
+		imp := &models.ProcedureImport{
+			FileName:      stored.FileName,
+			StorageName:   stored.StorageName,
+			Title:         c.PostForm("title"),
+			Category:      category,
+			CategoryID:    categoryID,
+			ExtractedText: contentText,
+			CreatedBy:     actor,
+		}
+		if existing != nil {
+			imp.ProcedureID = &existing.ID
+		}
+		if err := services.CreateProcedureImport(imp); err != nil {
+			c.JSON(importErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, imp)
+		return
+	}
+
+	if !extracted {
+		contentText = stored.StorageName // fallback: lưu tên file đã lưu nếu không trích xuất được
 	}
 	procedure := &models.Procedure{
 		Title:       title,
 		Content:     contentText,
 		Category:    category,
-		Description: "File upload: " + file.Filename,
+		CategoryID:  categoryID,
+		Description: services.UploadDescriptionPrefix + stored.FileName,
+		FileKey:     stored.StorageName,
+		FileName:    stored.FileName,
+		UpdatedBy:   actor,
+	}
+
+	// Kiểm tra trùng lặp với các quy trình khác (file đã lưu được giữ lại vì có thể đang được quy trình trùng sử dụng)
+	var duplicates []models.DuplicateMatch
+	if extracted {
+		excludeID := primitive.NilObjectID
+		if existing != nil {
+			excludeID = existing.ID
+		}
+		var ok bool
+		if duplicates, ok = checkDuplicates(c, procedure, excludeID); !ok {
+			return
+		}
 	}
-	err = services.CreateProcedure(procedure)
-	if err != nil {
+
+	if existing != nil {
+		if existing.Description != "" && !strings.HasPrefix(existing.Description, services.UploadDescriptionPrefix) {
+			procedure.Description = existing.Description
+		}
+		err = services.UpdateProcedureFromUpload(procedureID, procedure)
+		if err == nil {
+			procedure, err = services.GetProcedureByID(procedureID)
+		}
+	} else {
+		procedure.CreatedBy, _ = primitive.ObjectIDFromHex(actor)
+		procedure.OwnerID = actor
+		err = services.CreateProcedure(procedure)
+	}
+	if isProcedureInputError(err) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	} else if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save procedure info"})
 		return
 	}
 
+	// Tạo mô tả, tóm tắt và FAQ bằng AI ở chế độ nền (nếu bật)
+	if shouldEnrich(c) {
+		services.EnrichInBackground(procedure.ID, actor)
+	}
+
+	procedure.PossibleDuplicates = duplicates
 	c.JSON(http.StatusOK, gin.H{
 		"message":   "File uploaded successfully",
 		"procedure": procedure,
 	})
 }
 
-// ensureDir creates the directory if not exists
-func ensureDir(dirName string) error {
-	if _, err := os.Stat(dirName); os.IsNotExist(err) {
-		return os.MkdirAll(dirName, 0755)
+// getActorFromContext returns the ID of the authenticated user for audit fields.
+// Unlike getUserHexFromContext it also accepts non-ObjectID IDs such as the built-in "admin" account.
+func getActorFromContext(c *gin.Context) string {
+	if hex, ok := getUserHexFromContext(c); ok {
+		return hex
+	}
+	if uid, exists := c.Get("user_id"); exists {
+		if s, ok := uid.(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+// parseProcedureQuery reads the category and tag filters shared by listing and search.
+// Tags can be repeated (?tags=a&tags=b) or comma separated (?tags=a,b).
+func parseProcedureQuery(c *gin.Context) (models.ProcedureQuery, error) {
+	query := models.ProcedureQuery{
+		Category: c.Query("category"),
+		TagMode:  c.DefaultQuery("tag_mode", models.TagModeAny),
+		Viewer:   getViewer(c),
+		Language: requestLanguage(c),
+	}
+	for _, value := range c.QueryArray("tags") {
+		query.Tags = append(query.Tags, strings.Split(value, ",")...)
+	}
+	if query.TagMode != models.TagModeAny && query.TagMode != models.TagModeAll {
+		return query, fmt.Errorf("tag_mode must be 'any' or 'all'")
 	}
-	return nil
+	return query, nil
+}
+
+// parseCategoryID parses an optional category ID from a request
+func parseCategoryID(id string) (primitive.ObjectID, error) {
+	if id == "" {
+		return primitive.NilObjectID, nil
+	}
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("invalid category_id")
+	}
+	return objID, nil
+}
+
+// isProcedureInputError reports whether saving a procedure failed because of the request
+// (invalid steps, an unknown category, bad review dates, visibility or language) rather than a server error
+func isProcedureInputError(err error) bool {
+	return errors.Is(err, services.ErrInvalidSteps) ||
+		errors.Is(err, services.ErrInvalidVisibility) ||
+		errors.Is(err, services.ErrInvalidLanguage) ||
+		errors.Is(err, services.ErrCategoryNotFound) ||
+		errors.Is(err, services.ErrInvalidCategory) ||
+		errors.Is(err, services.ErrInvalidReviewSchedule) ||
+		errors.Is(err, services.ErrAuthorAsReviewer)
+}
+
+// getViewer describes the caller for procedure visibility checks; anonymous callers get the zero Viewer.
+// The result is cached on the request since handlers may need it more than once.
+func getViewer(c *gin.Context) models.Viewer {
+	if cached, exists := c.Get("viewer"); exists {
+		return cached.(models.Viewer)
+	}
+
+	userID := getActorFromContext(c)
+	if userID == "" {
+		c.Set("viewer", models.Viewer{})
+		return models.Viewer{}
+	}
+
+	viewer := models.Viewer{
+		UserID:        userID,
+		Role:          c.GetString("role"),
+		Authenticated: true,
+		IsAdmin:       c.GetBool("is_admin"),
+	}
+	if !viewer.IsAdmin {
+		if user, err := services.GetUserByID(userID); err == nil {
+			viewer.Department = user.Department
+			viewer.Language = user.Language
+		}
+	}
+	c.Set("viewer", viewer)
+	return viewer
+}
+
+// requestLanguage negotiates the reader's language from ?lang=, their profile and Accept-Language
+func requestLanguage(c *gin.Context) string {
+	return services.NegotiateLanguage(c.Query("lang"), getViewer(c).Language, c.GetHeader("Accept-Language"))
+}
+
+// getLocalizedProcedure loads a published procedure the caller may see, in the caller's language
+func getLocalizedProcedure(c *gin.Context, id string) (*models.Procedure, error) {
+	procedure, err := services.GetPublishedProcedureByID(id, getViewer(c))
+	if err != nil {
+		return nil, err
+	}
+	services.LocalizeProcedure(procedure, requestLanguage(c))
+	c.Header("Content-Language", procedure.Language)
+	return procedure, nil
 }
 
 // GetCategories handles GET /api/categories
@@ -313,16 +726,25 @@ func CreateCategory(c *gin.Context) {
 
 	category, err := services.CreateCategory(req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(categoryErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
 	c.JSON(http.StatusCreated, category)
 }
 
-// GetAdminStats handles GET /api/admin/stats
+// GetAdminStats handles GET /api/admin/stats?days=30&top=10
 func GetAdminStats(c *gin.Context) {
-	stats, err := services.GetAdminStats()
+	days := 30
+	if d, err := strconv.Atoi(c.Query("days")); err == nil && d > 0 && d <= 365 {
+		days = d
+	}
+	top := 10
+	if t, err := strconv.Atoi(c.Query("top")); err == nil && t > 0 && t <= 50 {
+		top = t
+	}
+
+	stats, err := services.GetAdminStats(days, top)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stats"})
 		return
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"web_AI/models"
+	"web_AI/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// analyticsDays reads the ?days= period of the question analytics endpoints
+func analyticsDays(c *gin.Context, fallback int) int {
+	if d, err := strconv.Atoi(c.Query("days")); err == nil && d > 0 && d <= 365 {
+		return d
+	}
+	return fallback
+}
+
+// analyticsLimit reads the ?limit= of the question analytics endpoints
+func analyticsLimit(c *gin.Context) int {
+	if l, err := strconv.Atoi(c.Query("limit")); err == nil && l > 0 && l <= 200 {
+		return l
+	}
+	return 20
+}
+
+// GetTrendingQuestions handles GET /api/admin/questions/trending?days=7&limit=20
+func GetTrendingQuestions(c *gin.Context) {
+	days := analyticsDays(c, 7)
+	questions, err := services.GetTrendingQuestions(days, analyticsLimit(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch trending questions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TrendingQuestionsResponse{
+		Questions: questions,
+		Total:     int64(len(questions)),
+		Days:      days,
+	})
+}
+
+// GetUnansweredQuestions handles GET /api/admin/questions/unanswered?days=30
+func GetUnansweredQuestions(c *gin.Context) {
+	days := analyticsDays(c, 30)
+	clusters, err := services.GetUnansweredClusters(days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch unanswered questions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.QuestionClustersResponse{
+		Clusters: clusters,
+		Total:    int64(len(clusters)),
+		Days:     days,
+	})
+}
+
+// GetProcedureSuggestions handles GET /api/admin/questions/suggestions?days=30
+func GetProcedureSuggestions(c *gin.Context) {
+	days := analyticsDays(c, 30)
+	suggestions, err := services.GetProcedureSuggestions(days)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch procedure suggestions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ProcedureSuggestionsResponse{
+		Suggestions: suggestions,
+		Total:       int64(len(suggestions)),
+		Days:        days,
+	})
+}
+
+// GetSearchMisses handles GET /api/admin/questions/search-misses?days=30&limit=20
+func GetSearchMisses(c *gin.Context) {
+	days := analyticsDays(c, 30)
+	queries, err := services.GetSearchMisses(days, analyticsLimit(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch search misses"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SearchMissesResponse{
+		Queries: queries,
+		Total:   int64(len(queries)),
+		Days:    days,
+	})
+}
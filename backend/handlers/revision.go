@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"web_AI/models"
+	"web_AI/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetProcedureRevisions handles GET /api/admin/procedures/:id/revisions
+func GetProcedureRevisions(c *gin.Context) {
+	id := c.Param("id")
+
+	revisions, err := services.GetProcedureRevisions(id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := models.RevisionsResponse{
+		Revisions: revisions,
+		Total:     int64(len(revisions)),
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetProcedureRevision handles GET /api/admin/procedures/:id/revisions/:rev
+func GetProcedureRevision(c *gin.Context) {
+	id := c.Param("id")
+	rev, err := strconv.Atoi(c.Param("rev"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision number"})
+		return
+	}
+
+	revision, err := services.GetProcedureRevision(id, rev)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, revision)
+}
+
+// DiffProcedureRevisions handles GET /api/admin/procedures/:id/revisions/diff?from=1&to=2
+func DiffProcedureRevisions(c *gin.Context) {
+	id := c.Param("id")
+	from, errFrom := strconv.Atoi(c.Query("from"))
+	to, errTo := strconv.Atoi(c.Query("to"))
+	if errFrom != nil || errTo != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameters 'from' and 'to' must be revision numbers"})
+		return
+	}
+
+	diff, err := services.DiffProcedureRevisions(id, from, to)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
+
+// RestoreProcedureRevision handles POST /api/admin/procedures/:id/revisions/:rev/restore
+func RestoreProcedureRevision(c *gin.Context) {
+	id := c.Param("id")
+	rev, err := strconv.Atoi(c.Param("rev"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision number"})
+		return
+	}
+
+	if err := services.RestoreProcedureRevision(id, rev, getActorFromContext(c)); err != nil {
+		if errors.Is(err, services.ErrRevisionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		respondProcedureSaveError(c, id, err)
+		return
+	}
+
+	procedure, err := services.GetProcedureByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get restored procedure"})
+		return
+	}
+
+	c.JSON(http.StatusOK, procedure)
+}
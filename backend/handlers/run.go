@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"web_AI/models"
+	"web_AI/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// StartProcedureRun handles POST /api/procedures/:id/runs
+func StartProcedureRun(c *gin.Context) {
+	var req models.StartRunRequest
+	// The body is optional: a run needs no title or due date
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	run, err := services.StartRun(c.Param("id"), getViewer(c), req)
+	if err != nil {
+		c.JSON(runErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, run)
+}
+
+// GetMyRuns handles GET /api/runs?status=in_progress|completed|cancelled
+func GetMyRuns(c *gin.Context) {
+	runs, err := services.GetMyRuns(getViewer(c).UserID, c.Query("status"))
+	if err != nil {
+		c.JSON(runErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.RunsResponse{
+		Runs:  runs,
+		Total: int64(len(runs)),
+	})
+}
+
+// GetRun handles GET /api/runs/:id
+func GetRun(c *gin.Context) {
+	run, err := services.GetRun(c.Param("id"), getViewer(c))
+	if err != nil {
+		c.JSON(runErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+
+// UpdateRunStep handles PUT /api/runs/:id/steps/:number
+func UpdateRunStep(c *gin.Context) {
+	var req models.UpdateRunStepRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	run, err := services.UpdateRunStep(c.Param("id"), c.Param("number"), getViewer(c), req)
+	if err != nil {
+		c.JSON(runErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+
+// AssignRunStep handles PUT /api/runs/:id/steps/:number/assignee
+func AssignRunStep(c *gin.Context) {
+	var req models.AssignRunStepRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	run, err := services.AssignRunStep(c.Param("id"), c.Param("number"), getViewer(c), req)
+	if err != nil {
+		c.JSON(runErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+
+// CancelRun handles POST /api/runs/:id/cancel
+func CancelRun(c *gin.Context) {
+	run, err := services.CancelRun(c.Param("id"), getViewer(c))
+	if err != nil {
+		c.JSON(runErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+
+// GetAdminRuns handles GET /api/admin/runs?procedure_id=&status=in_progress|completed|cancelled|overdue
+func GetAdminRuns(c *gin.Context) {
+	runs, err := services.GetRuns(c.Query("procedure_id"), c.Query("status"))
+	if err != nil {
+		c.JSON(runErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.RunsResponse{
+		Runs:  runs,
+		Total: int64(len(runs)),
+	})
+}
+
+// GetRunSummary handles GET /api/admin/runs/summary
+func GetRunSummary(c *gin.Context) {
+	procedures, err := services.GetRunSummary()
+	if err != nil {
+		c.JSON(runErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.RunSummaryResponse{
+		Procedures: procedures,
+		Total:      int64(len(procedures)),
+	})
+}
+
+func runErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrProcedureNotFound), errors.Is(err, services.ErrRunNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrRunForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrInvalidRun):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"web_AI/models"
+	"web_AI/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetStaleProcedures handles GET /api/admin/procedures/stale
+func GetStaleProcedures(c *gin.Context) {
+	procedures, err := services.GetStaleProcedures()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch stale procedures"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.StaleProceduresResponse{
+		Procedures: procedures,
+		Total:      int64(len(procedures)),
+	})
+}
+
+// UpdateProcedureOwnership handles PUT /api/admin/procedures/:id/ownership
+func UpdateProcedureOwnership(c *gin.Context) {
+	var req models.UpdateOwnershipRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	procedure, err := services.UpdateProcedureOwnership(c.Param("id"), req, getActorFromContext(c))
+	if err != nil {
+		c.JSON(stalenessErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, procedure)
+}
+
+// MarkProcedureReviewed handles POST /api/admin/procedures/:id/reviewed
+func MarkProcedureReviewed(c *gin.Context) {
+	var req struct {
+		Comment string `json:"comment"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	procedure, err := services.MarkProcedureReviewed(c.Param("id"), req.Comment, getActorFromContext(c))
+	if err != nil {
+		c.JSON(stalenessErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, procedure)
+}
+
+func stalenessErrorStatus(err error) int {
+	if errors.Is(err, services.ErrProcedureNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"web_AI/models"
+	"web_AI/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetProcedureSteps handles GET /api/procedures/:id/steps
+func GetProcedureSteps(c *gin.Context) {
+	id := c.Param("id")
+
+	procedure, err := getLocalizedProcedure(c, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	steps := procedure.Steps
+	if steps == nil {
+		steps = []models.ProcedureStep{}
+	}
+
+	response := models.StepsResponse{
+		ProcedureID: id,
+		Steps:       steps,
+		Total:       int64(len(steps)),
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetProcedureStep handles GET /api/procedures/:id/steps/:number (number like "2" or "2.1")
+func GetProcedureStep(c *gin.Context) {
+	id := c.Param("id")
+	number := c.Param("number")
+
+	procedure, err := getLocalizedProcedure(c, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	step, err := services.FindStep(procedure.Steps, number)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.StepResponse{ProcedureID: id, Number: number, Step: *step})
+}
+
+// UpdateProcedureSteps handles PUT /api/admin/procedures/:id/steps
+func UpdateProcedureSteps(c *gin.Context) {
+	var req models.UpdateStepsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	expectedVersion, err := parseIfMatch(c, nil)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	current, err := services.GetProcedureByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	// Content generated from the old steps is regenerated from the new ones on save
+	if len(req.Steps) > 0 && current.Content == services.RenderStepsContent(current.Steps) {
+		current.Content = ""
+	}
+	current.Steps = req.Steps
+	duplicates, ok := checkDuplicates(c, current, current.ID)
+	if !ok {
+		return
+	}
+
+	procedure, err := services.UpdateProcedureSteps(c.Param("id"), req.Steps, getActorFromContext(c), expectedVersion)
+	if err != nil {
+		respondStepError(c, err)
+		return
+	}
+
+	procedure.PossibleDuplicates = duplicates
+	setProcedureETag(c, procedure)
+	c.JSON(http.StatusOK, procedure)
+}
+
+// UpdateProcedureStep handles PUT /api/admin/procedures/:id/steps/:number
+func UpdateProcedureStep(c *gin.Context) {
+	var step models.ProcedureStep
+	if err := c.ShouldBindJSON(&step); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	expectedVersion, err := parseIfMatch(c, nil)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	procedure, err := services.UpdateProcedureStep(c.Param("id"), c.Param("number"), step, getActorFromContext(c), expectedVersion)
+	if err != nil {
+		respondStepError(c, err)
+		return
+	}
+
+	setProcedureETag(c, procedure)
+	c.JSON(http.StatusOK, procedure)
+}
+
+func respondStepError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrVersionConflict):
+		respondProcedureSaveError(c, c.Param("id"), err)
+	case errors.Is(err, services.ErrProcedureNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
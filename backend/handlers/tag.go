@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+	"web_AI/models"
+	"web_AI/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTags handles GET /api/tags
+func GetTags(c *gin.Context) {
+	tags, err := services.GetTags(getViewer(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TagsResponse{
+		Tags:  tags,
+		Total: int64(len(tags)),
+	})
+}
+
+// BulkUpdateProcedureTags handles POST /api/admin/procedures/tags
+func BulkUpdateProcedureTags(c *gin.Context) {
+	var req models.BulkTagsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := services.BulkUpdateTags(req, getActorFromContext(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
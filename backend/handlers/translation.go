@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"web_AI/models"
+	"web_AI/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetProcedureTranslations handles GET /api/admin/procedures/:id/translations
+func GetProcedureTranslations(c *gin.Context) {
+	translations, err := services.GetTranslations(c.Param("id"))
+	if err != nil {
+		c.JSON(translationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, translations)
+}
+
+// UpsertProcedureTranslation handles PUT /api/admin/procedures/:id/translations/:lang
+func UpsertProcedureTranslation(c *gin.Context) {
+	var req models.UpsertTranslationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	translation, err := services.UpsertTranslation(c.Param("id"), c.Param("lang"), req, getActorFromContext(c))
+	if err != nil {
+		c.JSON(translationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, translation)
+}
+
+// DraftProcedureTranslation handles POST /api/admin/procedures/:id/translations/:lang/draft
+func DraftProcedureTranslation(c *gin.Context) {
+	translation, err := services.DraftTranslation(c.Param("id"), c.Param("lang"), getActorFromContext(c))
+	if err != nil {
+		c.JSON(translationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, translation)
+}
+
+// ApproveProcedureTranslation handles POST /api/admin/procedures/:id/translations/:lang/approve
+func ApproveProcedureTranslation(c *gin.Context) {
+	translation, err := services.ApproveTranslation(c.Param("id"), c.Param("lang"), getActorFromContext(c))
+	if err != nil {
+		c.JSON(translationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, translation)
+}
+
+// DeleteProcedureTranslation handles DELETE /api/admin/procedures/:id/translations/:lang
+func DeleteProcedureTranslation(c *gin.Context) {
+	if err := services.DeleteTranslation(c.Param("id"), c.Param("lang")); err != nil {
+		c.JSON(translationErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Translation deleted"})
+}
+
+// UpdateMyLanguage handles PUT /api/me/language
+func UpdateMyLanguage(c *gin.Context) {
+	userID, ok := getUserHexFromContext(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only user accounts have a language preference"})
+		return
+	}
+
+	var req models.UpdateLanguageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	lang := services.NormalizeLanguage(req.Language)
+	if lang == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported language", "supported": models.SupportedLanguages})
+		return
+	}
+
+	if err := services.UpdateUserLanguage(userID, lang); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"language": lang})
+}
+
+func translationErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrProcedureNotFound), errors.Is(err, services.ErrTranslationNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrTranslationFailed):
+		return http.StatusBadGateway
+	case errors.Is(err, services.ErrInvalidLanguage), errors.Is(err, services.ErrInvalidSteps):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"web_AI/models"
+	"web_AI/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTrash handles GET /api/admin/trash
+func GetTrash(c *gin.Context) {
+	procedures, err := services.GetDeletedProcedures()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch trash"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TrashResponse{
+		Procedures:    procedures,
+		Total:         int64(len(procedures)),
+		RetentionDays: services.TrashRetentionDays(),
+	})
+}
+
+// RestoreProcedure handles POST /api/admin/trash/procedures/:id/restore
+func RestoreProcedure(c *gin.Context) {
+	id := c.Param("id")
+	if err := services.RestoreProcedure(id, getActorFromContext(c)); err != nil {
+		c.JSON(trashErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	procedure, err := services.GetProcedureByID(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get restored procedure"})
+		return
+	}
+
+	c.JSON(http.StatusOK, procedure)
+}
+
+// PurgeProcedure handles DELETE /api/admin/trash/procedures/:id
+func PurgeProcedure(c *gin.Context) {
+	if err := services.PurgeProcedure(c.Param("id")); err != nil {
+		c.JSON(trashErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Procedure permanently deleted"})
+}
+
+// PurgeExpiredTrash handles POST /api/admin/trash/purge
+func PurgeExpiredTrash(c *gin.Context) {
+	result, err := services.PurgeExpiredTrash()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetConversationTrash handles GET /api/chat/trash
+func GetConversationTrash(c *gin.Context) {
+	hex, ok := getUserHexFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	conversations, err := services.GetDeletedChatConversations(hex)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ConversationTrashResponse{
+		Conversations: conversations,
+		Total:         int64(len(conversations)),
+		RetentionDays: services.TrashRetentionDays(),
+	})
+}
+
+// RestoreChatConversation handles POST /api/chat/trash/:id/restore
+func RestoreChatConversation(c *gin.Context) {
+	hex, ok := getUserHexFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	if err := services.RestoreChatConversation(hex, c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Conversation restored"})
+}
+
+// PurgeChatConversation handles DELETE /api/chat/trash/:id
+func PurgeChatConversation(c *gin.Context) {
+	hex, ok := getUserHexFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	if err := services.PurgeChatConversation(hex, c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Conversation permanently deleted"})
+}
+
+func trashErrorStatus(err error) int {
+	if errors.Is(err, services.ErrProcedureNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
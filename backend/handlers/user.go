@@ -38,13 +38,13 @@ func CreateUser(c *gin.Context) {
 // UpdateUser handles PUT /api/admin/users/:id
 func UpdateUser(c *gin.Context) {
 	id := c.Param("id")
-	var user models.User
-	if err := c.ShouldBindJSON(&user); err != nil {
+	var req models.UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
 		return
 	}
 
-	err := services.UpdateUser(id, user)
+	err := services.UpdateUser(id, req)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
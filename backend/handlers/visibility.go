@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"web_AI/models"
+	"web_AI/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UpdateProcedureVisibility handles PUT /api/admin/procedures/:id/visibility
+func UpdateProcedureVisibility(c *gin.Context) {
+	var req models.UpdateVisibilityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	procedure, err := services.UpdateProcedureVisibility(c.Param("id"), req, getActorFromContext(c))
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrProcedureNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	setProcedureETag(c, procedure)
+	c.JSON(http.StatusOK, procedure)
+}
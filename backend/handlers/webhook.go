@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"web_AI/models"
+	"web_AI/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetWebhooks handles GET /api/admin/webhooks
+func GetWebhooks(c *gin.Context) {
+	webhooks, err := services.GetWebhooks()
+	if err != nil {
+		c.JSON(webhookErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.WebhooksResponse{
+		Webhooks: webhooks,
+		Total:    int64(len(webhooks)),
+	})
+}
+
+// CreateWebhook handles POST /api/admin/webhooks
+// The response is the only one that includes the signing secret.
+func CreateWebhook(c *gin.Context) {
+	var req models.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	webhook, err := services.CreateWebhook(req, getActorFromContext(c))
+	if err != nil {
+		c.JSON(webhookErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, webhook)
+}
+
+// GetWebhook handles GET /api/admin/webhooks/:id
+func GetWebhook(c *gin.Context) {
+	webhook, err := services.GetWebhook(c.Param("id"))
+	if err != nil {
+		c.JSON(webhookErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// UpdateWebhook handles PUT /api/admin/webhooks/:id
+func UpdateWebhook(c *gin.Context) {
+	var req models.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	webhook, err := services.UpdateWebhook(c.Param("id"), req)
+	if err != nil {
+		c.JSON(webhookErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// DeleteWebhook handles DELETE /api/admin/webhooks/:id
+func DeleteWebhook(c *gin.Context) {
+	if err := services.DeleteWebhook(c.Param("id")); err != nil {
+		c.JSON(webhookErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook deleted"})
+}
+
+// PingWebhook handles POST /api/admin/webhooks/:id/ping
+// The test event is sent right away and the resulting delivery is returned.
+func PingWebhook(c *gin.Context) {
+	delivery, err := services.PingWebhook(c.Param("id"))
+	if err != nil {
+		c.JSON(webhookErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+// GetWebhookDeliveries handles GET /api/admin/webhooks/:id/deliveries?status=pending|succeeded|failed&limit=
+func GetWebhookDeliveries(c *gin.Context) {
+	var limit int64 = 50
+	if l, err := strconv.ParseInt(c.Query("limit"), 10, 64); err == nil && l > 0 && l <= 500 {
+		limit = l
+	}
+
+	deliveries, err := services.GetWebhookDeliveries(c.Param("id"), c.Query("status"), limit)
+	if err != nil {
+		c.JSON(webhookErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.WebhookDeliveriesResponse{
+		Deliveries: deliveries,
+		Total:      int64(len(deliveries)),
+	})
+}
+
+// GetWebhookDelivery handles GET /api/admin/webhook-deliveries/:id
+func GetWebhookDelivery(c *gin.Context) {
+	delivery, err := services.GetWebhookDelivery(c.Param("id"))
+	if err != nil {
+		c.JSON(webhookErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+// RedeliverWebhookDelivery handles POST /api/admin/webhook-deliveries/:id/redeliver
+// The original payload is sent again as a new delivery.
+func RedeliverWebhookDelivery(c *gin.Context) {
+	delivery, err := services.RedeliverWebhookDelivery(c.Param("id"))
+	if err != nil {
+		c.JSON(webhookErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+func webhookErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrWebhookNotFound), errors.Is(err, services.ErrDeliveryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInvalidWebhook):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"web_AI/models"
+	"web_AI/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAdminProcedures handles GET /api/admin/procedures?status=&reviewer_id=
+func GetAdminProcedures(c *gin.Context) {
+	procedures, err := services.GetAdminProcedures(c.Query("status"), c.Query("reviewer_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch procedures"})
+		return
+	}
+
+	response := models.ProceduresResponse{
+		Procedures: procedures,
+		Total:      int64(len(procedures)),
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetAdminProcedureByID handles GET /api/admin/procedures/:id
+func GetAdminProcedureByID(c *gin.Context) {
+	procedure, err := services.GetProcedureByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	setProcedureETag(c, procedure)
+	c.JSON(http.StatusOK, procedure)
+}
+
+// SubmitProcedure handles POST /api/admin/procedures/:id/submit
+func SubmitProcedure(c *gin.Context) {
+	handleWorkflowAction(c, models.WorkflowActionSubmit)
+}
+
+// ApproveProcedure handles POST /api/admin/procedures/:id/approve
+func ApproveProcedure(c *gin.Context) {
+	handleWorkflowAction(c, models.WorkflowActionApprove)
+}
+
+// RejectProcedure handles POST /api/admin/procedures/:id/reject
+func RejectProcedure(c *gin.Context) {
+	handleWorkflowAction(c, models.WorkflowActionReject)
+}
+
+// ArchiveProcedure handles POST /api/admin/procedures/:id/archive
+func ArchiveProcedure(c *gin.Context) {
+	handleWorkflowAction(c, models.WorkflowActionArchive)
+}
+
+// ReopenProcedure handles POST /api/admin/procedures/:id/reopen
+func ReopenProcedure(c *gin.Context) {
+	handleWorkflowAction(c, models.WorkflowActionReopen)
+}
+
+func handleWorkflowAction(c *gin.Context, action string) {
+	var req models.WorkflowActionRequest
+	// The body is optional for actions that need no comment
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	procedure, err := services.TransitionProcedure(c.Param("id"), action, req, getActorFromContext(c))
+	if err != nil {
+		c.JSON(workflowErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, procedure)
+}
+
+// AssignProcedureReviewer handles PUT /api/admin/procedures/:id/reviewer
+func AssignProcedureReviewer(c *gin.Context) {
+	var req models.AssignReviewerRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	procedure, err := services.AssignProcedureReviewer(c.Param("id"), req.ReviewerID, getActorFromContext(c))
+	if err != nil {
+		c.JSON(workflowErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, procedure)
+}
+
+func workflowErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidTransition):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrNotAssignedReviewer), errors.Is(err, services.ErrSelfApproval):
+		return http.StatusForbidden
+	case errors.Is(err, services.ErrProcedureNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusBadRequest
+	}
+}
@@ -3,9 +3,11 @@ package main
 import (
 	"log"
 	"os"
+	"time"
 
 	"web_AI/config"
 	"web_AI/routes"
+	"web_AI/services"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -21,6 +23,64 @@ func main() {
 	// Kết nối MongoDB
 	config.InitMongoDB()
 
+	// Nơi lưu file tải lên: thư mục cục bộ, S3/MinIO hoặc bộ nhớ (STORAGE_BACKEND)
+	if err := services.InitFileStorage(); err != nil {
+		log.Fatal("❌ Không thể khởi tạo nơi lưu file: ", err)
+	}
+
+	// Lệnh bảo trì (backup, ...) chạy xong thì thoát, không khởi động server
+	if len(os.Args) > 1 {
+		os.Exit(runCLI(os.Args[1:]))
+	}
+
+	// Nhắc chủ sở hữu rà soát các quy trình quá hạn
+	staleCheckInterval := 24 * time.Hour
+	if d, err := time.ParseDuration(os.Getenv("STALE_CHECK_INTERVAL")); err == nil && d > 0 {
+		staleCheckInterval = d
+	}
+	services.StartStalenessScheduler(staleCheckInterval)
+
+	// Index cho thống kê truy cập (sự kiện thô tự hết hạn, số liệu theo ngày được giữ lại)
+	if err := services.EnsureEventIndexes(); err != nil {
+		log.Println("⚠️ Không thể tạo index cho thống kê:", err)
+	}
+
+	// Câu hỏi chatbot và tìm kiếm không có kết quả tự hết hạn sau thời gian lưu giữ
+	if err := services.EnsureQuestionIndexes(); err != nil {
+		log.Println("⚠️ Không thể tạo index cho thống kê câu hỏi:", err)
+	}
+
+	// Số phiên bản lịch sử là duy nhất trong mỗi quy trình
+	if err := services.EnsureRevisionIndexes(); err != nil {
+		log.Println("⚠️ Không thể tạo index cho lịch sử phiên bản:", err)
+	}
+
+	// Mỗi người đọc chỉ có một lượt đánh giá cho mỗi quy trình
+	if err := services.EnsureFeedbackIndexes(); err != nil {
+		log.Println("⚠️ Không thể tạo index cho đánh giá:", err)
+	}
+
+	// Xóa vĩnh viễn các mục trong thùng rác quá thời hạn lưu giữ
+	trashPurgeInterval := 24 * time.Hour
+	if d, err := time.ParseDuration(os.Getenv("TRASH_PURGE_INTERVAL")); err == nil && d > 0 {
+		trashPurgeInterval = d
+	}
+	services.StartTrashPurgeScheduler(trashPurgeInterval)
+
+	// Mỗi nội dung file tải lên chỉ có một bản ghi (theo mã băm)
+	if err := services.EnsureUploadIndexes(); err != nil {
+		log.Println("⚠️ Không thể tạo index cho file tải lên:", err)
+	}
+
+	// Gửi lại các webhook giao thất bại khi đến hạn thử lại
+	if err := services.EnsureWebhookIndexes(); err != nil {
+		log.Println("⚠️ Không thể tạo index cho webhook:", err)
+	}
+	services.StartWebhookRetryWorker(30 * time.Second)
+
+	// Báo webhook khi quy trình được hẹn giờ xuất bản thực sự hiển thị
+	services.StartPublishScheduler(time.Minute)
+
 	// Khởi tạo Gin và route
 	router := gin.Default()
 	routes.SetupRoutes(router)
@@ -61,6 +61,36 @@ func JWTAuth() gin.HandlerFunc {
 	}
 }
 
+// OptionalJWTAuth sets the user context like JWTAuth when a valid token is sent,
+// and lets the request through anonymously otherwise (used by public routes)
+func OptionalJWTAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenParts := strings.Split(c.GetHeader("Authorization"), " ")
+		// Real tokens are far longer than 20 characters; shorter ones would trip ValidateJWT's debug log
+		if len(tokenParts) != 2 || tokenParts[0] != "Bearer" || len(tokenParts[1]) < 20 {
+			c.Next()
+			return
+		}
+
+		userID, role, err := utils.ValidateJWT(tokenParts[1])
+		if err != nil {
+			c.Next()
+			return
+		}
+
+		if userID == "admin" || role == "admin" {
+			c.Set("user_id", userID)
+			c.Set("role", "admin")
+			c.Set("is_admin", true)
+		} else if objID, err := primitive.ObjectIDFromHex(userID); err == nil {
+			c.Set("user_id", objID)
+			c.Set("role", role)
+			c.Set("is_admin", false)
+		}
+		c.Next()
+	}
+}
+
 // AdminAuth middleware - requires admin role
 func AdminAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+// BackupFormatVersion is bumped whenever the archive layout changes incompatibly
+const BackupFormatVersion = 1
+
+// Backup import modes
+const (
+	BackupModeMerge   = "merge"   // keep existing data, update matching documents and add new ones
+	BackupModeReplace = "replace" // delete existing knowledge-base data before importing
+)
+
+// BackupManifest is stored as manifest.json at the root of a backup archive
+type BackupManifest struct {
+	FormatVersion int            `json:"format_version"`
+	App           string         `json:"app"`
+	CreatedAt     time.Time      `json:"created_at"`
+	Collections   map[string]int `json:"collections"` // collection name -> document count
+	Files         []BackupFile   `json:"files"`
+}
+
+type BackupFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+type BackupImportOptions struct {
+	Mode   string `json:"mode"`
+	DryRun bool   `json:"dry_run"`
+	NewIDs bool   `json:"new_ids"` // import everything as copies with freshly generated IDs
+}
+
+type BackupImportReport struct {
+	Mode          string                             `json:"mode"`
+	DryRun        bool                               `json:"dry_run"`
+	FormatVersion int                                `json:"format_version"`
+	BackupDate    time.Time                          `json:"backup_date"`
+	Collections   map[string]*BackupCollectionReport `json:"collections"`
+	IDRemaps      []BackupIDRemap                    `json:"id_remaps"`
+	Files         BackupFileReport                   `json:"files"`
+	Warnings      []string                           `json:"warnings"`
+}
+
+type BackupCollectionReport struct {
+	Deleted int `json:"deleted"`
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Skipped int `json:"skipped"`
+}
+
+type BackupIDRemap struct {
+	Collection string `json:"collection"`
+	OldID      string `json:"old_id"`
+	NewID      string `json:"new_id"`
+	Reason     string `json:"reason"`
+}
+
+type BackupFileReport struct {
+	Written int `json:"written"`
+	Skipped int `json:"skipped"`
+}
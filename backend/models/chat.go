@@ -20,18 +20,21 @@ type ChatConversation struct {
 	Messages  []ChatMessage      `bson:"messages" json:"messages"`
 	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	DeletedAt *time.Time         `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
 }
 
 // Request/Response models for Chat API
 type ChatRequest struct {
-	ConversationID string `json:"conversation_id,omitempty"`
-	Message        string `json:"message" binding:"required"`
+	ConversationID string   `json:"conversation_id,omitempty"`
+	Message        string   `json:"message" binding:"required"`
+	Tags           []string `json:"tags,omitempty"` // boost procedures with these tags when retrieving context
 }
 
 type ChatResponse struct {
 	ConversationID string            `json:"conversation_id"`
 	Conversation   *ChatConversation `json:"conversation,omitempty"`
 	Response       string            `json:"response"`
+	QuestionID     string            `json:"question_id,omitempty"` // send answer feedback for this ID
 }
 
 type ChatHistoryResponse struct {
@@ -0,0 +1,24 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// DuplicateMatch is an existing procedure whose content is similar to the one being saved
+type DuplicateMatch struct {
+	ProcedureID primitive.ObjectID `json:"procedure_id"`
+	Title       string             `json:"title"`
+	Status      string             `json:"status,omitempty"`
+	Similarity  float64            `json:"similarity"` // estimated Jaccard similarity of the character shingles, 0-1
+}
+
+// DuplicatePair is two existing procedures that are likely duplicates of each other
+type DuplicatePair struct {
+	First      DuplicateMatch `json:"first"`
+	Second     DuplicateMatch `json:"second"`
+	Similarity float64        `json:"similarity"`
+}
+
+type DuplicatePairsResponse struct {
+	Pairs     []DuplicatePair `json:"pairs"`
+	Total     int64           `json:"total"`
+	Threshold float64         `json:"threshold"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Enrichment statuses; only approved enrichments are shown to readers and used by search and chat
+const (
+	EnrichmentStatusDraft    = "draft"
+	EnrichmentStatusApproved = "approved"
+)
+
+// ProcedureEnrichment is a short description, bullet summary and likely questions generated by the AI
+// for a procedure, which admins review and edit before approving
+type ProcedureEnrichment struct {
+	Description  string     `bson:"description,omitempty" json:"description,omitempty"`
+	Summary      []string   `bson:"summary,omitempty" json:"summary,omitempty"`
+	FAQ          []FAQPair  `bson:"faq,omitempty" json:"faq,omitempty"`
+	Status       string     `bson:"status,omitempty" json:"status,omitempty"`
+	MachineDraft bool       `bson:"machine_draft,omitempty" json:"machine_draft,omitempty"` // generated by the AI, not yet edited by a person
+	SourceHash   string     `bson:"source_hash,omitempty" json:"-"`                         // fingerprint of the text it was made from
+	Outdated     bool       `bson:"-" json:"outdated,omitempty"`                            // the procedure text changed since
+	GeneratedAt  *time.Time `bson:"generated_at,omitempty" json:"generated_at,omitempty"`
+	UpdatedAt    *time.Time `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+	UpdatedBy    string     `bson:"updated_by,omitempty" json:"updated_by,omitempty"`
+	ApprovedAt   *time.Time `bson:"approved_at,omitempty" json:"approved_at,omitempty"`
+	ApprovedBy   string     `bson:"approved_by,omitempty" json:"approved_by,omitempty"`
+
+	// Background generation; a failed run leaves the previous text in place and records why
+	Generating      bool       `bson:"generating,omitempty" json:"generating,omitempty"`
+	GeneratingSince *time.Time `bson:"generating_since,omitempty" json:"generating_since,omitempty"`
+	Error           string     `bson:"error,omitempty" json:"error,omitempty"`
+}
+
+type FAQPair struct {
+	Question string `bson:"question" json:"question"`
+	Answer   string `bson:"answer" json:"answer"`
+}
+
+// UpdateEnrichmentRequest saves an admin's edits as a draft
+type UpdateEnrichmentRequest struct {
+	Description string    `json:"description"`
+	Summary     []string  `json:"summary"`
+	FAQ         []FAQPair `json:"faq"`
+}
+
+// ApproveEnrichmentRequest optionally replaces a hand-written procedure description with the generated one;
+// empty descriptions and the "File upload: ..." placeholder are always replaced
+type ApproveEnrichmentRequest struct {
+	ReplaceDescription bool `json:"replace_description"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Tracked event types
+const (
+	EventProcedureView = "procedure_view"
+	EventSearch        = "search"
+	EventChat          = "chat"
+	EventLogin         = "login"
+)
+
+// EventTypes lists every tracked event type
+var EventTypes = []string{EventProcedureView, EventSearch, EventChat, EventLogin}
+
+// Event is a single tracked action; raw events expire after EVENT_RETENTION_DAYS, rollups are kept
+type Event struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Type        string              `bson:"type" json:"type"`
+	ProcedureID *primitive.ObjectID `bson:"procedure_id,omitempty" json:"procedure_id,omitempty"`
+	UserID      string              `bson:"user_id,omitempty" json:"user_id,omitempty"`
+	Query       string              `bson:"query,omitempty" json:"query,omitempty"` // search text
+	CreatedAt   time.Time           `bson:"created_at" json:"created_at"`
+}
+
+// EventRollup counts events of one type on one day, optionally for a single procedure
+type EventRollup struct {
+	Day         time.Time           `bson:"day" json:"day"` // midnight UTC
+	Type        string              `bson:"type" json:"type"`
+	ProcedureID *primitive.ObjectID `bson:"procedure_id" json:"procedure_id,omitempty"` // nil for the all-procedures total
+	Count       int64               `bson:"count" json:"count"`
+}
+
+// StatsPoint holds the event counts of one day ("2006-01-02") or ISO week ("2006-W01")
+type StatsPoint struct {
+	Period   string `json:"period"`
+	Views    int64  `json:"views"`
+	Searches int64  `json:"searches"`
+	Chats    int64  `json:"chats"`
+	Logins   int64  `json:"logins"`
+}
+
+type TopProcedure struct {
+	ProcedureID primitive.ObjectID `json:"procedureId"`
+	Title       string             `json:"title"`
+	Views       int64              `json:"views"`
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Feedback comment types
+const (
+	FeedbackTypeComment       = "comment"
+	FeedbackTypeClarification = "clarification" // the reader asks for part of the procedure to be explained
+)
+
+// Admin feedback sort orders
+const (
+	FeedbackSortLowestRated   = "lowest_rated"
+	FeedbackSortMostCommented = "most_commented"
+)
+
+// FeedbackSummary aggregates the ratings and comments of a procedure
+type FeedbackSummary struct {
+	HelpfulCount       int64      `bson:"helpful_count" json:"helpful_count"`
+	NotHelpfulCount    int64      `bson:"not_helpful_count" json:"not_helpful_count"`
+	RatingCount        int64      `bson:"rating_count" json:"rating_count"`
+	Score              float64    `bson:"score" json:"score"` // share of helpful ratings, 0..1; 0 when unrated
+	CommentCount       int64      `bson:"comment_count" json:"comment_count"`
+	ClarificationCount int64      `bson:"clarification_count" json:"clarification_count"` // open clarification requests
+	UpdatedAt          *time.Time `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+}
+
+// ProcedureRating is one user's helpful / not helpful vote; users can change their vote
+type ProcedureRating struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ProcedureID primitive.ObjectID `bson:"procedure_id" json:"procedure_id"`
+	UserID      string             `bson:"user_id" json:"user_id"`
+	Helpful     bool               `bson:"helpful" json:"helpful"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// ProcedureComment is a comment or clarification request on a procedure
+type ProcedureComment struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ProcedureID primitive.ObjectID `bson:"procedure_id" json:"procedure_id"`
+	UserID      string             `bson:"user_id" json:"user_id,omitempty"` // only shown to admins
+	AuthorName  string             `bson:"-" json:"author_name,omitempty"`
+	Type        string             `bson:"type" json:"type"`
+	Comment     string             `bson:"comment" json:"comment"`
+	StepNumber  string             `bson:"step_number,omitempty" json:"step_number,omitempty"` // optional step the comment is about
+	Resolved    bool               `bson:"resolved" json:"resolved"`
+	ResolvedBy  string             `bson:"resolved_by,omitempty" json:"resolved_by,omitempty"`
+	ResolvedAt  *time.Time         `bson:"resolved_at,omitempty" json:"resolved_at,omitempty"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+}
+
+type RateProcedureRequest struct {
+	Helpful *bool `json:"helpful" binding:"required"`
+}
+
+type CreateCommentRequest struct {
+	Comment    string `json:"comment" binding:"required"`
+	Type       string `json:"type"` // "comment" (default) or "clarification"
+	StepNumber string `json:"step_number"`
+}
+
+type CommentsResponse struct {
+	Comments []ProcedureComment `json:"comments"`
+	Total    int64              `json:"total"`
+}
+
+// RatingResponse returns the caller's vote with the updated totals
+type RatingResponse struct {
+	Helpful  *bool           `json:"helpful,omitempty"`
+	Feedback FeedbackSummary `json:"feedback"`
+}
+
+// ProcedureFeedback is a row of the admin feedback report
+type ProcedureFeedback struct {
+	ProcedureID primitive.ObjectID `json:"procedure_id"`
+	Title       string             `json:"title"`
+	Category    string             `json:"category"`
+	OwnerID     string             `json:"owner_id,omitempty"`
+	Feedback    FeedbackSummary    `json:"feedback"`
+}
+
+type FeedbackReportResponse struct {
+	Procedures []ProcedureFeedback `json:"procedures"`
+	Total      int64               `json:"total"`
+	Sort       string              `json:"sort"`
+}
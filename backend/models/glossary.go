@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GlossaryEntry groups a company term or acronym with its synonyms and meaning
+type GlossaryEntry struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Term       string             `bson:"term" json:"term"`
+	Synonyms   []string           `bson:"synonyms" json:"synonyms"`
+	Definition string             `bson:"definition" json:"definition"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// Variants returns the term followed by all of its synonyms
+func (g GlossaryEntry) Variants() []string {
+	return append([]string{g.Term}, g.Synonyms...)
+}
+
+// Request/Response models
+type CreateGlossaryEntryRequest struct {
+	Term       string   `json:"term" binding:"required"`
+	Synonyms   []string `json:"synonyms"`
+	Definition string   `json:"definition"`
+}
+
+type UpdateGlossaryEntryRequest struct {
+	Term       string   `json:"term" binding:"required"`
+	Synonyms   []string `json:"synonyms"`
+	Definition string   `json:"definition"`
+}
+
+type GlossaryResponse struct {
+	Entries []GlossaryEntry `json:"entries"`
+	Total   int64           `json:"total"`
+}
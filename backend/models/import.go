@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Import statuses; a pending import waits for an admin to accept or reject the preview
+const (
+	ImportStatusPending  = "pending"
+	ImportStatusAccepted = "accepted"
+	ImportStatusRejected = "rejected"
+)
+
+// StructuredProcedure is what the AI extracts from an uploaded document
+type StructuredProcedure struct {
+	Title       string          `bson:"title" json:"title"`
+	Description string          `bson:"description,omitempty" json:"description,omitempty"`
+	Steps       []ProcedureStep `bson:"steps" json:"steps"`
+}
+
+// ProcedureImport is the preview of a procedure converted from an uploaded document. Nothing is
+// created until an admin accepts it, possibly after editing the result.
+type ProcedureImport struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Status      string              `bson:"status" json:"status"`
+	FileName    string              `bson:"file_name" json:"file_name"`
+	StorageName string              `bson:"storage_name" json:"-"`                  // name of the uploaded file in file storage
+	Title       string              `bson:"title,omitempty" json:"title,omitempty"` // title given with the upload, overrides the AI's
+	Category    string              `bson:"category,omitempty" json:"category,omitempty"`
+	CategoryID  primitive.ObjectID  `bson:"category_id,omitempty" json:"category_id,omitempty"`
+	ProcedureID *primitive.ObjectID `bson:"procedure_id,omitempty" json:"procedure_id,omitempty"` // procedure to update instead of creating one
+
+	ExtractedText string              `bson:"extracted_text" json:"extracted_text"`
+	Truncated     bool                `bson:"truncated,omitempty" json:"truncated,omitempty"` // only the start of the text was sent to the AI
+	Result        StructuredProcedure `bson:"result" json:"result"`
+
+	// Every role and document mentioned in the steps, for a quick review
+	ResponsibleRoles  []string `bson:"responsible_roles,omitempty" json:"responsible_roles,omitempty"`
+	RequiredDocuments []string `bson:"required_documents,omitempty" json:"required_documents,omitempty"`
+
+	CreatedBy  string     `bson:"created_by" json:"created_by"`
+	CreatedAt  time.Time  `bson:"created_at" json:"created_at"`
+	ReviewedBy string     `bson:"reviewed_by,omitempty" json:"reviewed_by,omitempty"`
+	ReviewedAt *time.Time `bson:"reviewed_at,omitempty" json:"reviewed_at,omitempty"`
+}
+
+// AcceptImportRequest optionally corrects the preview before the procedure is created
+type AcceptImportRequest struct {
+	Title       string          `json:"title"`
+	Description *string         `json:"description"`
+	Steps       []ProcedureStep `json:"steps"`
+	Category    string          `json:"category"`
+	CategoryID  string          `json:"category_id"`
+}
+
+type ImportsResponse struct {
+	Imports []ProcedureImport `json:"imports"`
+	Total   int64             `json:"total"`
+}
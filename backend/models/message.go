@@ -5,7 +5,8 @@ type AskRequest struct {
 }
 
 type AskResponse struct {
-	Answer string `json:"answer"`
+	Answer     string `json:"answer"`
+	QuestionID string `json:"question_id,omitempty"` // send answer feedback for this ID
 }
 
 type MistralRequest struct {
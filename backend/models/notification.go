@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Notification types
+const (
+	NotificationTypeProcedureStale         = "procedure_stale"
+	NotificationTypeClarificationRequested = "clarification_requested"
+	NotificationTypeRunStepAssigned        = "run_step_assigned"
+)
+
+// Notification is an in-app message for a single user
+type Notification struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID      string             `bson:"user_id" json:"user_id"`
+	Type        string             `bson:"type" json:"type"`
+	Title       string             `bson:"title" json:"title"`
+	Message     string             `bson:"message" json:"message"`
+	ProcedureID primitive.ObjectID `bson:"procedure_id,omitempty" json:"procedure_id,omitempty"`
+	Read        bool               `bson:"read" json:"read"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	ReadAt      *time.Time         `bson:"read_at,omitempty" json:"read_at,omitempty"`
+}
+
+type NotificationsResponse struct {
+	Notifications []Notification `json:"notifications"`
+	Total         int64          `json:"total"`
+	Unread        int64          `json:"unread"`
+}
@@ -10,41 +10,246 @@ type Procedure struct {
 	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
 	Title       string             `bson:"title" json:"title"`
 	Content     string             `bson:"content" json:"content"`
-	Category    string             `bson:"category" json:"category"`
+	Steps       []ProcedureStep    `bson:"steps,omitempty" json:"steps,omitempty"`
+	Category    string             `bson:"category" json:"category"` // category name, kept in sync with CategoryID
+	CategoryID  primitive.ObjectID `bson:"category_id,omitempty" json:"category_id,omitempty"`
+	Tags        []string           `bson:"tags,omitempty" json:"tags,omitempty"`
 	Description string             `bson:"description" json:"description"`
-	FileURL     string             `bson:"file_url,omitempty" json:"file_url,omitempty"`
-	FileName    string             `bson:"file_name,omitempty" json:"file_name,omitempty"`
+	FileURL     string             `bson:"file_url,omitempty" json:"file_url,omitempty"`   // download path of the uploaded original
+	FileName    string             `bson:"file_name,omitempty" json:"file_name,omitempty"` // name the original was uploaded with
+	FileKey     string             `bson:"file_key,omitempty" json:"-"`                    // name of the original in file storage
 	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
 	CreatedBy   primitive.ObjectID `bson:"created_by,omitempty" json:"created_by,omitempty"`
+	UpdatedBy   string             `bson:"updated_by,omitempty" json:"updated_by,omitempty"`
+	Version     int64              `bson:"version" json:"version"` // incremented on every change; sent as the ETag
+
+	// Review workflow; procedures saved before the workflow existed have no status and count as published
+	Status         string          `bson:"status,omitempty" json:"status,omitempty"`
+	ReviewerID     string          `bson:"reviewer_id,omitempty" json:"reviewer_id,omitempty"`
+	ReviewComments []ReviewComment `bson:"review_comments,omitempty" json:"review_comments,omitempty"`
+	PublishAt      *time.Time      `bson:"publish_at,omitempty" json:"publish_at,omitempty"`
+	PublishedAt    *time.Time      `bson:"published_at,omitempty" json:"published_at,omitempty"`
+	PublishPending bool            `bson:"publish_pending,omitempty" json:"-"` // approved for a later publish_at that has not been announced yet
+
+	// Ownership and review cadence; a zero interval uses the default from REVIEW_INTERVAL_DAYS
+	OwnerID            string     `bson:"owner_id,omitempty" json:"owner_id,omitempty"`
+	EffectiveDate      *time.Time `bson:"effective_date,omitempty" json:"effective_date,omitempty"`
+	ExpiryDate         *time.Time `bson:"expiry_date,omitempty" json:"expiry_date,omitempty"`
+	ReviewIntervalDays int        `bson:"review_interval_days,omitempty" json:"review_interval_days,omitempty"`
+	LastReviewedAt     *time.Time `bson:"last_reviewed_at,omitempty" json:"last_reviewed_at,omitempty"`
+	StaleNotifiedAt    *time.Time `bson:"stale_notified_at,omitempty" json:"stale_notified_at,omitempty"`
+
+	// Who can see the procedure
+	ProcedureAccess `bson:",inline"`
+
+	// Languages: the text above is written in SourceLanguage; Language is the language it is served in
+	SourceLanguage string                          `bson:"source_language,omitempty" json:"source_language,omitempty"`
+	Translations   map[string]ProcedureTranslation `bson:"translations,omitempty" json:"translations,omitempty"`
+	Language       string                          `bson:"-" json:"language,omitempty"`
+
+	// Reader ratings and comments, recomputed whenever feedback changes; not part of the content version
+	Feedback *FeedbackSummary `bson:"feedback,omitempty" json:"feedback,omitempty"`
+
+	// AI-generated description, summary and FAQ; readers only get it once approved
+	Enrichment *ProcedureEnrichment `bson:"enrichment,omitempty" json:"enrichment,omitempty"`
+
+	// Similar procedures found when this one was saved; only set in save responses
+	PossibleDuplicates []DuplicateMatch `bson:"-" json:"possible_duplicates,omitempty"`
+	// MinHash signature of the content, stored on save so duplicate checks do not read every procedure's
+	// text; null when there is no text, missing for procedures saved before signatures were stored
+	DuplicateSignature []int64 `bson:"duplicate_signature" json:"-"`
+
+	// Soft delete; deleted procedures stay in the trash until restored or purged
+	DeletedAt *time.Time `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	DeletedBy string     `bson:"deleted_by,omitempty" json:"deleted_by,omitempty"`
+}
+
+// ProcedureStep is one ordered step of a procedure; steps are numbered by their position ("2", "2.1", ...)
+type ProcedureStep struct {
+	Title             string          `bson:"title" json:"title"`
+	Instructions      string          `bson:"instructions,omitempty" json:"instructions,omitempty"`
+	ResponsibleRole   string          `bson:"responsible_role,omitempty" json:"responsible_role,omitempty"`
+	ExpectedDuration  string          `bson:"expected_duration,omitempty" json:"expected_duration,omitempty"` // e.g. "2 ngày", "4h"
+	RequiredDocuments []string        `bson:"required_documents,omitempty" json:"required_documents,omitempty"`
+	SubSteps          []ProcedureStep `bson:"sub_steps,omitempty" json:"sub_steps,omitempty"`
+}
+
+// Procedure statuses
+const (
+	ProcedureStatusDraft     = "draft"
+	ProcedureStatusInReview  = "in_review"
+	ProcedureStatusPublished = "published"
+	ProcedureStatusArchived  = "archived"
+)
+
+// Workflow actions
+const (
+	WorkflowActionSubmit  = "submit"
+	WorkflowActionApprove = "approve"
+	WorkflowActionReject  = "reject"
+	WorkflowActionArchive = "archive"
+	WorkflowActionReopen  = "reopen"
+
+	// ReviewActionReviewed marks a periodic content review; it does not change the status
+	ReviewActionReviewed = "reviewed"
+)
+
+// ReviewComment records a workflow transition and what the reviewer said about it
+type ReviewComment struct {
+	AuthorID  string    `bson:"author_id" json:"author_id"`
+	Action    string    `bson:"action" json:"action"`
+	Comment   string    `bson:"comment,omitempty" json:"comment,omitempty"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
 }
 
 type Category struct {
-	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Name        string             `bson:"name" json:"name"`
-	Description string             `bson:"description" json:"description"`
-	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Name        string              `bson:"name" json:"name"`
+	Description string              `bson:"description" json:"description"`
+	ParentID    *primitive.ObjectID `bson:"parent_id,omitempty" json:"parent_id,omitempty"`
+	CreatedAt   time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// CategoryNode is a category with its sub-categories, as returned by the tree endpoint
+type CategoryNode struct {
+	Category       `bson:",inline"`
+	ProcedureCount int64           `json:"procedure_count"`
+	Children       []*CategoryNode `json:"children"`
 }
 
 // Request/Response models
+// CreateProcedureRequest needs either content or steps; content is generated from steps when omitted.
+// The category can be given by ID or by name and must exist.
 type CreateProcedureRequest struct {
-	Title       string `json:"title" binding:"required"`
-	Content     string `json:"content" binding:"required"`
-	Category    string `json:"category" binding:"required"`
-	Description string `json:"description"`
+	Title       string          `json:"title" binding:"required"`
+	Content     string          `json:"content"`
+	Steps       []ProcedureStep `json:"steps"`
+	Category    string          `json:"category"`
+	CategoryID  string          `json:"category_id"`
+	Tags        []string        `json:"tags"`
+	Description string          `json:"description"`
+	ReviewerID  string          `json:"reviewer_id"`
+
+	OwnerID            string     `json:"owner_id"`
+	EffectiveDate      *time.Time `json:"effective_date"`
+	ExpiryDate         *time.Time `json:"expiry_date"`
+	ReviewIntervalDays int        `json:"review_interval_days"`
+
+	Visibility         string   `json:"visibility"` // public (default), authenticated or restricted
+	VisibleDepartments []string `json:"visible_departments"`
+	VisibleRoles       []string `json:"visible_roles"`
+
+	SourceLanguage string `json:"source_language"` // language the procedure is written in, "vi" by default
+}
+
+type WorkflowActionRequest struct {
+	Comment    string     `json:"comment"`
+	ReviewerID string     `json:"reviewer_id"`
+	PublishAt  *time.Time `json:"publish_at"` // approve only; publishes at this time instead of immediately
+}
+
+// UpdateOwnershipRequest sets the owner and review cadence of a procedure; omitted fields are unchanged
+type UpdateOwnershipRequest struct {
+	OwnerID            *string    `json:"owner_id"`
+	EffectiveDate      *time.Time `json:"effective_date"`
+	ExpiryDate         *time.Time `json:"expiry_date"`
+	ReviewIntervalDays *int       `json:"review_interval_days"`
+}
+
+// StaleProcedure is one row of the stale procedures report
+type StaleProcedure struct {
+	ID             primitive.ObjectID `json:"id"`
+	Title          string             `json:"title"`
+	Category       string             `json:"category"`
+	OwnerID        string             `json:"owner_id,omitempty"`
+	LastReviewedAt *time.Time         `json:"last_reviewed_at,omitempty"`
+	ReviewDueAt    time.Time          `json:"review_due_at"`
+	ExpiryDate     *time.Time         `json:"expiry_date,omitempty"`
+	Expired        bool               `json:"expired"`
+	DaysOverdue    int                `json:"days_overdue"`
+}
+
+type StaleProceduresResponse struct {
+	Procedures []StaleProcedure `json:"procedures"`
+	Total      int64            `json:"total"`
 }
 
+type AssignReviewerRequest struct {
+	ReviewerID string `json:"reviewer_id" binding:"required"`
+}
+
+// UpdateProcedureRequest keeps the existing steps and tags when they are omitted
 type UpdateProcedureRequest struct {
-	Title       string `json:"title"`
-	Content     string `json:"content"`
-	Category    string `json:"category"`
-	Description string `json:"description"`
+	Title       string          `json:"title"`
+	Content     string          `json:"content"`
+	Steps       []ProcedureStep `json:"steps"`
+	Category    string          `json:"category"`
+	CategoryID  string          `json:"category_id"`
+	Tags        []string        `json:"tags"`
+	Description string          `json:"description"`
+	Version     *int64          `json:"version"` // alternative to the If-Match header
+}
+
+// PatchProcedureRequest changes only the fields that are present in the request body
+type PatchProcedureRequest struct {
+	Title       *string          `json:"title"`
+	Content     *string          `json:"content"`
+	Steps       *[]ProcedureStep `json:"steps"`
+	Category    *string          `json:"category"`
+	CategoryID  *string          `json:"category_id"`
+	Tags        *[]string        `json:"tags"`
+	Description *string          `json:"description"`
+	Version     *int64           `json:"version"` // alternative to the If-Match header
+}
+
+type UpdateStepsRequest struct {
+	Steps []ProcedureStep `json:"steps" binding:"required"`
+}
+
+type StepsResponse struct {
+	ProcedureID string          `json:"procedure_id"`
+	Steps       []ProcedureStep `json:"steps"`
+	Total       int64           `json:"total"`
+}
+
+type StepResponse struct {
+	ProcedureID string        `json:"procedure_id"`
+	Number      string        `json:"number"`
+	Step        ProcedureStep `json:"step"`
 }
 
 type CreateCategoryRequest struct {
 	Name        string `json:"name" binding:"required"`
 	Description string `json:"description" binding:"required"`
+	ParentID    string `json:"parent_id"`
+}
+
+// UpdateCategoryRequest leaves empty fields unchanged; parent_id "" moves the category to the top level
+type UpdateCategoryRequest struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description"`
+	ParentID    *string `json:"parent_id"`
+}
+
+type DeleteCategoryResponse struct {
+	BlockingProcedures   int64  `json:"blocking_procedures,omitempty"` // procedures that prevent deleting without reassign_to
+	ReassignedProcedures int64  `json:"reassigned_procedures"`
+	MovedChildren        int64  `json:"moved_children"`
+	ReassignedTo         string `json:"reassigned_to,omitempty"`
+}
+
+type CategoryTreeResponse struct {
+	Categories []*CategoryNode `json:"categories"`
+	Total      int64           `json:"total"` // all categories, not only the top-level ones
+}
+
+// CategoryMigrationReport summarises linking legacy procedures to categories by ID
+type CategoryMigrationReport struct {
+	LinkedProcedures  int64    `json:"linked_procedures"`
+	CreatedCategories []string `json:"created_categories"`
+	Unresolved        int64    `json:"unresolved"`
 }
 
 type ProceduresResponse struct {
@@ -60,5 +265,15 @@ type CategoriesResponse struct {
 type AdminStatsResponse struct {
 	TotalProcedures int64 `json:"totalProcedures"`
 	TotalCategories int64 `json:"totalCategories"`
-	TotalVisits     int64 `json:"totalVisits"`
+	TotalVisits     int64 `json:"totalVisits"` // procedure views
+
+	TotalSearches int64 `json:"totalSearches"`
+	TotalChats    int64 `json:"totalChats"`
+	TotalLogins   int64 `json:"totalLogins"`
+
+	// Time series over the last Days days, and the most viewed procedures in that period
+	Days          int            `json:"days"`
+	Daily         []StatsPoint   `json:"daily"`
+	Weekly        []StatsPoint   `json:"weekly"`
+	TopProcedures []TopProcedure `json:"topProcedures"`
 }
@@ -0,0 +1,115 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Where a logged question came from
+const (
+	QuestionSourceChat          = "chat"           // general chat answered with RAG
+	QuestionSourceProcedureChat = "procedure_chat" // question about one specific procedure
+	QuestionSourceSearch        = "search"         // search query that found nothing
+)
+
+// RAGAnswer is a chat answer with what retrieval found for it
+type RAGAnswer struct {
+	Answer       string
+	Hits         int     // procedures retrieved as context
+	TopScore     float64 // share of the question's words found in the best procedure, 0..1
+	ProcedureIDs []primitive.ObjectID
+}
+
+// QuestionLog records a chat question, how well retrieval did and what the asker thought of the answer
+type QuestionLog struct {
+	ID           primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	UserID       string               `bson:"user_id,omitempty" json:"user_id,omitempty"`
+	Question     string               `bson:"question" json:"question"`
+	Normalized   string               `bson:"normalized" json:"-"`
+	Source       string               `bson:"source" json:"source"`
+	Language     string               `bson:"language,omitempty" json:"language,omitempty"`
+	ProcedureID  *primitive.ObjectID  `bson:"procedure_id,omitempty" json:"procedure_id,omitempty"`
+	Hits         int                  `bson:"hits" json:"hits"`
+	TopScore     float64              `bson:"top_score" json:"top_score"`
+	RetrievedIDs []primitive.ObjectID `bson:"retrieved_ids,omitempty" json:"retrieved_ids,omitempty"`
+
+	Helpful         *bool      `bson:"helpful,omitempty" json:"helpful,omitempty"`
+	FeedbackComment string     `bson:"feedback_comment,omitempty" json:"feedback_comment,omitempty"`
+	FeedbackAt      *time.Time `bson:"feedback_at,omitempty" json:"feedback_at,omitempty"`
+
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
+
+// SearchMiss records a search query that returned no procedures
+type SearchMiss struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Query      string             `bson:"query" json:"query"`
+	Normalized string             `bson:"normalized" json:"-"`
+	UserID     string             `bson:"user_id,omitempty" json:"user_id,omitempty"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+}
+
+type QuestionFeedbackRequest struct {
+	Helpful *bool  `json:"helpful" binding:"required"`
+	Comment string `json:"comment"`
+}
+
+// TrendingQuestion is a question asked in the period, compared with the period before it
+type TrendingQuestion struct {
+	Question      string    `json:"question"`
+	Count         int       `json:"count"`
+	PreviousCount int       `json:"previous_count"`
+	Unanswered    int       `json:"unanswered"`  // asked without a good retrieval match
+	NotHelpful    int       `json:"not_helpful"` // answers the askers marked as not helpful
+	LastAskedAt   time.Time `json:"last_asked_at"`
+}
+
+// QuestionCluster groups similar questions and search queries the knowledge base could not answer
+type QuestionCluster struct {
+	Label        string    `json:"label"` // the most asked wording
+	Keywords     []string  `json:"keywords"`
+	Count        int       `json:"count"`
+	SearchMisses int       `json:"search_misses"` // how many of Count are searches rather than chat questions
+	Samples      []string  `json:"samples"`
+	LastAskedAt  time.Time `json:"last_asked_at"`
+}
+
+// ProcedureSuggestion proposes a procedure to write for a recurring unanswered topic
+type ProcedureSuggestion struct {
+	SuggestedTitle  string   `json:"suggested_title"` // the most asked wording of the topic, for the admin to rephrase
+	Keywords        []string `json:"keywords"`
+	Demand          int      `json:"demand"`
+	SampleQuestions []string `json:"sample_questions"`
+}
+
+// SearchMissCount groups identical zero-result searches
+type SearchMissCount struct {
+	Query        string    `json:"query"`
+	Count        int       `json:"count"`
+	LastSearched time.Time `json:"last_searched"`
+}
+
+type TrendingQuestionsResponse struct {
+	Questions []TrendingQuestion `json:"questions"`
+	Total     int64              `json:"total"`
+	Days      int                `json:"days"`
+}
+
+type QuestionClustersResponse struct {
+	Clusters []QuestionCluster `json:"clusters"`
+	Total    int64             `json:"total"`
+	Days     int               `json:"days"`
+}
+
+type ProcedureSuggestionsResponse struct {
+	Suggestions []ProcedureSuggestion `json:"suggestions"`
+	Total       int64                 `json:"total"`
+	Days        int                   `json:"days"`
+}
+
+type SearchMissesResponse struct {
+	Queries []SearchMissCount `json:"queries"`
+	Total   int64             `json:"total"`
+	Days    int               `json:"days"`
+}
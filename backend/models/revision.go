@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Revision actions
+const (
+	RevisionActionBaseline = "baseline" // state of a procedure created before revisions existed
+	RevisionActionCreate   = "create"
+	RevisionActionUpdate   = "update"
+	RevisionActionUpload   = "upload"
+	RevisionActionRestore  = "restore"
+	RevisionActionAccess   = "visibility" // only who may see the procedure changed
+)
+
+// ProcedureRevision is an immutable snapshot of a procedure after a change
+type ProcedureRevision struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ProcedureID  primitive.ObjectID `bson:"procedure_id" json:"procedure_id"`
+	Revision     int                `bson:"revision" json:"revision"`
+	Action       string             `bson:"action" json:"action"`
+	Title        string             `bson:"title" json:"title"`
+	Content      string             `bson:"content" json:"content"`
+	Steps        []ProcedureStep    `bson:"steps,omitempty" json:"steps,omitempty"`
+	Category     string             `bson:"category" json:"category"`
+	CategoryID   primitive.ObjectID `bson:"category_id,omitempty" json:"category_id,omitempty"`
+	Description  string             `bson:"description" json:"description"`
+	Access       ProcedureAccess    `bson:"access" json:"access"`
+	AuthorID     string             `bson:"author_id,omitempty" json:"author_id,omitempty"`
+	RestoredFrom int                `bson:"restored_from,omitempty" json:"restored_from,omitempty"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// DiffLine is one line of a line-level diff
+type DiffLine struct {
+	Op      string `json:"op"` // "equal", "insert" or "delete"
+	Text    string `json:"text"`
+	OldLine int    `json:"old_line,omitempty"`
+	NewLine int    `json:"new_line,omitempty"`
+}
+
+type FieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// Request/Response models
+type RevisionsResponse struct {
+	Revisions []ProcedureRevision `json:"revisions"`
+	Total     int64               `json:"total"`
+}
+
+type RevisionDiffResponse struct {
+	ProcedureID string        `json:"procedure_id"`
+	From        int           `json:"from"`
+	To          int           `json:"to"`
+	Changes     []FieldChange `json:"changes"`
+	ContentDiff []DiffLine    `json:"content_diff"`
+	StepsDiff   []DiffLine    `json:"steps_diff"`
+	Added       int           `json:"added"`
+	Removed     int           `json:"removed"`
+}
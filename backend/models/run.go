@@ -0,0 +1,95 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Procedure run statuses
+const (
+	RunStatusInProgress = "in_progress"
+	RunStatusCompleted  = "completed"
+	RunStatusCancelled  = "cancelled"
+)
+
+// ProcedureRun is one person working through a procedure as a checklist. The steps are copied
+// when the run starts so later edits of the procedure do not change a run in progress.
+type ProcedureRun struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ProcedureID      primitive.ObjectID `bson:"procedure_id" json:"procedure_id"`
+	ProcedureTitle   string             `bson:"procedure_title" json:"procedure_title"`
+	ProcedureVersion int64              `bson:"procedure_version" json:"procedure_version"`
+	Title            string             `bson:"title,omitempty" json:"title,omitempty"` // e.g. "Onboarding Nguyễn Văn A"
+	StartedBy        string             `bson:"started_by" json:"started_by"`
+	Status           string             `bson:"status" json:"status"`
+	Steps            []RunStep          `bson:"steps" json:"steps"`
+	Assignees        []string           `bson:"assignees,omitempty" json:"assignees,omitempty"` // everyone with a step, for lookups
+	DueDate          *time.Time         `bson:"due_date,omitempty" json:"due_date,omitempty"`
+	StartedAt        time.Time          `bson:"started_at" json:"started_at"`
+	UpdatedAt        time.Time          `bson:"updated_at" json:"updated_at"`
+	CompletedAt      *time.Time         `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+	Version          int64              `bson:"version" json:"version"` // incremented on every save, guards against lost check-offs
+
+	Progress RunProgress `bson:"progress" json:"progress"`
+	Overdue  bool        `bson:"-" json:"overdue"`
+}
+
+// RunStep is a step of a run; sub-steps are flattened and keep their number ("2.1")
+type RunStep struct {
+	Number     string     `bson:"number" json:"number"`
+	Title      string     `bson:"title" json:"title"`
+	AssigneeID string     `bson:"assignee_id,omitempty" json:"assignee_id,omitempty"`
+	DueDate    *time.Time `bson:"due_date,omitempty" json:"due_date,omitempty"`
+	Done       bool       `bson:"done" json:"done"`
+	DoneAt     *time.Time `bson:"done_at,omitempty" json:"done_at,omitempty"`
+	DoneBy     string     `bson:"done_by,omitempty" json:"done_by,omitempty"`
+	Notes      []RunNote  `bson:"notes,omitempty" json:"notes,omitempty"`
+}
+
+type RunNote struct {
+	UserID    string    `bson:"user_id" json:"user_id"`
+	Text      string    `bson:"text" json:"text"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+}
+
+type RunProgress struct {
+	Done    int `bson:"done" json:"done"`
+	Total   int `bson:"total" json:"total"`
+	Percent int `bson:"percent" json:"percent"`
+}
+
+type StartRunRequest struct {
+	Title   string     `json:"title"`
+	DueDate *time.Time `json:"due_date"`
+}
+
+// UpdateRunStepRequest checks or unchecks a step and/or adds a note to it
+type UpdateRunStepRequest struct {
+	Done *bool  `json:"done"`
+	Note string `json:"note"`
+}
+
+// AssignRunStepRequest assigns a step to a colleague; an empty assignee unassigns it
+type AssignRunStepRequest struct {
+	AssigneeID string     `json:"assignee_id"`
+	DueDate    *time.Time `json:"due_date"`
+}
+
+type RunsResponse struct {
+	Runs  []ProcedureRun `json:"runs"`
+	Total int64          `json:"total"`
+}
+
+// ProcedureRunSummary counts the open runs of one procedure
+type ProcedureRunSummary struct {
+	ProcedureID    primitive.ObjectID `bson:"_id" json:"procedure_id"`
+	ProcedureTitle string             `bson:"procedure_title" json:"procedure_title"`
+	InProgress     int64              `bson:"in_progress" json:"in_progress"`
+	Overdue        int64              `bson:"overdue" json:"overdue"`
+}
+
+type RunSummaryResponse struct {
+	Procedures []ProcedureRunSummary `json:"procedures"`
+	Total      int64                 `json:"total"`
+}
@@ -0,0 +1,20 @@
+package models
+
+// Suggestion types
+const (
+	SuggestionTypeTitle    = "title"
+	SuggestionTypeCategory = "category"
+	SuggestionTypeFAQ      = "faq"
+)
+
+type Suggestion struct {
+	Text        string `json:"text"`
+	Type        string `json:"type"` // "title", "category" or "faq"
+	ProcedureID string `json:"procedure_id,omitempty"`
+	Score       int    `json:"score"`
+}
+
+type SuggestionsResponse struct {
+	Suggestions []Suggestion `json:"suggestions"`
+	Total       int64        `json:"total"`
+}
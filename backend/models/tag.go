@@ -0,0 +1,47 @@
+package models
+
+// Tag filter modes
+const (
+	TagModeAny = "any" // procedures with at least one of the tags
+	TagModeAll = "all" // procedures with every tag
+)
+
+// ProcedureQuery holds the optional filters for listing and searching published procedures
+type ProcedureQuery struct {
+	Search   string
+	Category string // category ID or name, sub-categories included
+	Tags     []string
+	TagMode  string // "any" (default) or "all"
+	Limit    int64
+	Viewer   Viewer // only procedures this viewer may see are returned
+	Language string // search also matches approved translations in this language
+}
+
+// RAGOptions tunes how procedures are retrieved for a chat answer
+type RAGOptions struct {
+	BoostTags []string // procedures with these tags are ranked first
+	Viewer    Viewer   // only procedures this viewer may see are used as context
+	Language  string   // answer language; procedures are quoted in it when translated
+}
+
+type TagCount struct {
+	Tag   string `bson:"_id" json:"tag"`
+	Count int64  `bson:"count" json:"count"`
+}
+
+type TagsResponse struct {
+	Tags  []TagCount `json:"tags"`
+	Total int64      `json:"total"`
+}
+
+// BulkTagsRequest adds and removes tags on several procedures at once
+type BulkTagsRequest struct {
+	ProcedureIDs []string `json:"procedure_ids" binding:"required"`
+	Add          []string `json:"add"`
+	Remove       []string `json:"remove"`
+}
+
+type BulkTagsResponse struct {
+	Matched  int64 `json:"matched"`
+	Modified int64 `json:"modified"`
+}
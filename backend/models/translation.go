@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// Supported content languages; procedures without a source language are written in DefaultLanguage
+const (
+	LanguageVietnamese = "vi"
+	LanguageEnglish    = "en"
+	DefaultLanguage    = LanguageVietnamese
+)
+
+// SupportedLanguages lists the languages procedures can be translated into and answers given in
+var SupportedLanguages = []string{LanguageVietnamese, LanguageEnglish}
+
+// Translation statuses; only approved translations are shown to readers
+const (
+	TranslationStatusDraft    = "draft"
+	TranslationStatusApproved = "approved"
+)
+
+// ProcedureTranslation is a procedure's text in another language than its source language
+type ProcedureTranslation struct {
+	Title        string          `bson:"title" json:"title"`
+	Description  string          `bson:"description,omitempty" json:"description,omitempty"`
+	Content      string          `bson:"content,omitempty" json:"content,omitempty"`
+	Steps        []ProcedureStep `bson:"steps,omitempty" json:"steps,omitempty"`
+	Status       string          `bson:"status" json:"status"`
+	MachineDraft bool            `bson:"machine_draft,omitempty" json:"machine_draft,omitempty"` // drafted by the AI, not yet edited by a person
+	SourceHash   string          `bson:"source_hash" json:"-"`                                   // fingerprint of the source text the translation was made from
+	Outdated     bool            `bson:"-" json:"outdated,omitempty"`                            // the source text changed since the translation was made
+	UpdatedAt    time.Time       `bson:"updated_at" json:"updated_at"`
+	UpdatedBy    string          `bson:"updated_by,omitempty" json:"updated_by,omitempty"`
+	ApprovedAt   *time.Time      `bson:"approved_at,omitempty" json:"approved_at,omitempty"`
+	ApprovedBy   string          `bson:"approved_by,omitempty" json:"approved_by,omitempty"`
+}
+
+// UpsertTranslationRequest saves a translation as a draft
+type UpsertTranslationRequest struct {
+	Title       string          `json:"title" binding:"required"`
+	Description string          `json:"description"`
+	Content     string          `json:"content"`
+	Steps       []ProcedureStep `json:"steps"`
+}
+
+type TranslationsResponse struct {
+	SourceLanguage string                          `json:"source_language"`
+	Translations   map[string]ProcedureTranslation `json:"translations"`
+	Total          int64                           `json:"total"`
+}
+
+type UpdateLanguageRequest struct {
+	Language string `json:"language" binding:"required"`
+}
@@ -0,0 +1,19 @@
+package models
+
+type TrashResponse struct {
+	Procedures []Procedure `json:"procedures"`
+	Total      int64       `json:"total"`
+	// RetentionDays is how long items stay in the trash before they are purged; 0 means never
+	RetentionDays int `json:"retention_days"`
+}
+
+type ConversationTrashResponse struct {
+	Conversations []ChatConversation `json:"conversations"`
+	Total         int64              `json:"total"`
+	RetentionDays int                `json:"retention_days"`
+}
+
+type PurgeTrashResponse struct {
+	Procedures    int64 `json:"procedures"`
+	Conversations int64 `json:"conversations"`
+}
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// StoredFile is an uploaded file, stored once under the SHA-256 of its content whatever it was called
+type StoredFile struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Hash         string             `bson:"hash" json:"hash"`                 // hex SHA-256 of the content
+	StorageName  string             `bson:"storage_name" json:"storage_name"` // hash plus extension, the name in the upload directory
+	OriginalName string             `bson:"original_name" json:"original_name"`
+	ContentType  string             `bson:"content_type" json:"content_type"` // detected from the content, not the request
+	Size         int64              `bson:"size" json:"size"`
+	UploadCount  int                `bson:"upload_count" json:"upload_count"`
+	UploadedBy   string             `bson:"uploaded_by,omitempty" json:"uploaded_by,omitempty"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
+
+	// Set on the upload result: the sanitized name of this upload and whether the content was stored before
+	FileName  string `bson:"-" json:"file_name,omitempty"`
+	Duplicate bool   `bson:"-" json:"duplicate,omitempty"`
+}
+
+// UploadMigrationReport summarizes moving uploads into the configured file storage
+type UploadMigrationReport struct {
+	Source   string   `json:"source"`
+	Target   string   `json:"target"`
+	DryRun   bool     `json:"dry_run,omitempty"`
+	Moved    int      `json:"moved"`
+	Renamed  int      `json:"renamed"` // stored under their content hash instead of their original name
+	Skipped  int      `json:"skipped"` // already in the target
+	Failed   int      `json:"failed"`
+	Warnings []string `json:"warnings"`
+}
+
+// FileLinkResponse is a short-lived download link that works without authentication
+type FileLinkResponse struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
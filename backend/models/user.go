@@ -4,9 +4,19 @@ package models
 import "go.mongodb.org/mongo-driver/bson/primitive"
 
 type User struct {
-	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Name     string             `bson:"name" json:"name"`
-	Email    string             `bson:"email" json:"email"`
-	Password string             `bson:"password" json:"-"`
-	Role     string             `bson:"role" json:"role"` // "user" or "admin"
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	Name       string             `bson:"name" json:"name"`
+	Email      string             `bson:"email" json:"email"`
+	Password   string             `bson:"password" json:"-"`
+	Role       string             `bson:"role" json:"role"` // "user" or "admin"
+	Department string             `bson:"department,omitempty" json:"department,omitempty"`
+	Language   string             `bson:"language,omitempty" json:"language,omitempty"` // preferred answer language
+}
+
+// UpdateUserRequest is what an admin can change about a user; an omitted department is left unchanged
+type UpdateUserRequest struct {
+	Name       string  `json:"name"`
+	Email      string  `json:"email"`
+	Role       string  `json:"role"`
+	Department *string `json:"department"`
 }
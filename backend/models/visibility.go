@@ -0,0 +1,31 @@
+package models
+
+// Procedure visibility levels
+const (
+	VisibilityPublic        = "public"        // anyone, including anonymous visitors
+	VisibilityAuthenticated = "authenticated" // any logged-in user
+	VisibilityRestricted    = "restricted"    // only the listed departments or roles (and admins)
+)
+
+// ProcedureAccess controls who can see a procedure; procedures without a level are public
+type ProcedureAccess struct {
+	Visibility         string   `bson:"visibility,omitempty" json:"visibility,omitempty"`
+	VisibleDepartments []string `bson:"visible_departments,omitempty" json:"visible_departments,omitempty"`
+	VisibleRoles       []string `bson:"visible_roles,omitempty" json:"visible_roles,omitempty"`
+}
+
+// Viewer describes who is reading procedures; the zero value is an anonymous visitor
+type Viewer struct {
+	UserID        string
+	Role          string
+	Department    string
+	Authenticated bool
+	IsAdmin       bool
+	Language      string // preferred language from the user profile
+}
+
+type UpdateVisibilityRequest struct {
+	Visibility         string   `json:"visibility" binding:"required"`
+	VisibleDepartments []string `json:"visible_departments"`
+	VisibleRoles       []string `json:"visible_roles"`
+}
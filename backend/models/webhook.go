@@ -0,0 +1,132 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Webhook events
+const (
+	WebhookEventProcedureCreated   = "procedure.created"
+	WebhookEventProcedureUpdated   = "procedure.updated"
+	WebhookEventProcedureDeleted   = "procedure.deleted" // moved to the trash
+	WebhookEventProcedurePublished = "procedure.published"
+	WebhookEventUserCreated        = "user.created"
+	WebhookEventChatEscalated      = "chat.escalated" // an employee marked a chat answer as not helpful
+	WebhookEventPing               = "ping"           // sent on request to test a subscription
+)
+
+// WebhookEvents lists the events a webhook can subscribe to; "*" subscribes to all of them
+var WebhookEvents = []string{
+	WebhookEventProcedureCreated,
+	WebhookEventProcedureUpdated,
+	WebhookEventProcedureDeleted,
+	WebhookEventProcedurePublished,
+	WebhookEventUserCreated,
+	WebhookEventChatEscalated,
+}
+
+// Webhook delivery statuses
+const (
+	DeliveryStatusPending   = "pending" // waiting for its first attempt or a retry
+	DeliveryStatusSucceeded = "succeeded"
+	DeliveryStatusFailed    = "failed" // gave up after the last attempt
+)
+
+// Webhook is a URL that receives the events it subscribes to, signed with its secret
+type Webhook struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	URL         string             `bson:"url" json:"url"`
+	Events      []string           `bson:"events" json:"events"`
+	Description string             `bson:"description,omitempty" json:"description,omitempty"`
+	Secret      string             `bson:"secret" json:"secret,omitempty"` // only returned when the webhook is created
+	Active      bool               `bson:"active" json:"active"`
+	CreatedBy   string             `bson:"created_by,omitempty" json:"created_by,omitempty"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// WebhookPayload is the JSON body posted to webhooks
+type WebhookPayload struct {
+	ID        string      `json:"id"` // the same for every delivery of one event, so receivers can ignore repeats
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// WebhookDelivery is one attempt series to deliver an event to a webhook
+type WebhookDelivery struct {
+	ID             primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	WebhookID      primitive.ObjectID  `bson:"webhook_id" json:"webhook_id"`
+	Event          string              `bson:"event" json:"event"`
+	EventID        string              `bson:"event_id" json:"event_id"`
+	Payload        string              `bson:"payload" json:"payload"` // the exact body that is signed and sent
+	Status         string              `bson:"status" json:"status"`
+	Attempts       int                 `bson:"attempts" json:"attempts"`
+	NextAttemptAt  *time.Time          `bson:"next_attempt_at,omitempty" json:"next_attempt_at,omitempty"`
+	LastAttemptAt  *time.Time          `bson:"last_attempt_at,omitempty" json:"last_attempt_at,omitempty"`
+	ResponseStatus int                 `bson:"response_status,omitempty" json:"response_status,omitempty"`
+	ResponseBody   string              `bson:"response_body,omitempty" json:"response_body,omitempty"`
+	Error          string              `bson:"error,omitempty" json:"error,omitempty"`
+	RedeliveryOf   *primitive.ObjectID `bson:"redelivery_of,omitempty" json:"redelivery_of,omitempty"`
+	CreatedAt      time.Time           `bson:"created_at" json:"created_at"`
+	DeliveredAt    *time.Time          `bson:"delivered_at,omitempty" json:"delivered_at,omitempty"`
+}
+
+// WebhookProcedure is the procedure data sent with procedure events
+type WebhookProcedure struct {
+	ID          primitive.ObjectID `json:"id"`
+	Title       string             `json:"title"`
+	Category    string             `json:"category,omitempty"`
+	Status      string             `json:"status,omitempty"`
+	Version     int64              `json:"version"`
+	Action      string             `json:"action,omitempty"` // how it changed, e.g. update, upload, restore, visibility, tags or restore_from_trash
+	PublishedAt *time.Time         `json:"published_at,omitempty"`
+	ActorID     string             `json:"actor_id,omitempty"`
+}
+
+// WebhookUser is the user data sent with user events
+type WebhookUser struct {
+	ID         primitive.ObjectID `json:"id"`
+	Name       string             `json:"name"`
+	Email      string             `json:"email"`
+	Role       string             `json:"role"`
+	Department string             `json:"department,omitempty"`
+}
+
+// WebhookChatEscalation is the question data sent with chat.escalated
+type WebhookChatEscalation struct {
+	QuestionID   primitive.ObjectID   `json:"question_id"`
+	Question     string               `json:"question"`
+	UserID       string               `json:"user_id,omitempty"`
+	Comment      string               `json:"comment,omitempty"`
+	Source       string               `json:"source,omitempty"`
+	ProcedureIDs []primitive.ObjectID `json:"procedure_ids,omitempty"`
+}
+
+type CreateWebhookRequest struct {
+	URL         string   `json:"url" binding:"required"`
+	Events      []string `json:"events" binding:"required"`
+	Description string   `json:"description"`
+	Secret      string   `json:"secret"` // generated when empty
+	Active      *bool    `json:"active"` // true by default
+}
+
+// UpdateWebhookRequest changes only the fields that are sent
+type UpdateWebhookRequest struct {
+	URL         *string  `json:"url"`
+	Events      []string `json:"events"`
+	Description *string  `json:"description"`
+	Active      *bool    `json:"active"`
+}
+
+type WebhooksResponse struct {
+	Webhooks []Webhook `json:"webhooks"`
+	Total    int64     `json:"total"`
+}
+
+type WebhookDeliveriesResponse struct {
+	Deliveries []WebhookDelivery `json:"deliveries"`
+	Total      int64             `json:"total"`
+}
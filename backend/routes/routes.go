@@ -13,14 +13,27 @@ func SetupRoutes(router *gin.Engine) {
 	router.POST("/api/auth/register", handlers.Register)
 	router.POST("/api/auth/login", handlers.Login)
 
-	// Public routes (no authentication required)
-	router.GET("/api/procedures", handlers.GetProcedures)
-	router.GET("/api/procedures/:id", handlers.GetProcedureById)
-	router.GET("/api/procedures/search", handlers.SearchProcedures)
-	router.GET("/api/procedures/category/:category", handlers.GetProceduresByCategory)
-	router.GET("/api/categories", handlers.GetCategories)
-	router.POST("/api/chat/public", handlers.HandleAIChat)
-	router.POST("/api/chat/procedures", handlers.HandleProcedureAIChat) // New AI endpoint
+	// Public routes (no authentication required; a token, when sent, widens procedure visibility)
+	publicGroup := router.Group("/api")
+	publicGroup.Use(middleware.OptionalJWTAuth())
+	{
+		publicGroup.GET("/procedures", handlers.GetProcedures)
+		publicGroup.GET("/procedures/:id", handlers.GetProcedureById)
+		publicGroup.GET("/procedures/search", handlers.SearchProcedures)
+		publicGroup.GET("/procedures/suggest", handlers.SuggestProcedures)
+		publicGroup.GET("/procedures/category/:category", handlers.GetProceduresByCategory)
+		publicGroup.GET("/procedures/:id/steps", handlers.GetProcedureSteps)
+		publicGroup.GET("/procedures/:id/export", handlers.ExportProcedure)
+		publicGroup.GET("/procedures/:id/file", handlers.GetProcedureFile)
+		publicGroup.GET("/procedures/:id/file/link", handlers.GetProcedureFileLink)
+		publicGroup.GET("/procedures/:id/steps/:number", handlers.GetProcedureStep)
+		publicGroup.GET("/categories", handlers.GetCategories)
+		publicGroup.GET("/categories/tree", handlers.GetCategoryTree)
+		publicGroup.GET("/tags", handlers.GetTags)
+		publicGroup.POST("/chat/public", handlers.HandleAIChat)
+		publicGroup.POST("/chat/procedures", handlers.HandleProcedureAIChat) // New AI endpoint
+		publicGroup.POST("/chat/questions/:id/feedback", handlers.SubmitQuestionFeedback)
+	}
 
 	// Protected routes (require authentication)
 	authGroup := router.Group("/api")
@@ -30,7 +43,27 @@ func SetupRoutes(router *gin.Engine) {
 		authGroup.GET("/chat/history", handlers.GetChatHistory)
 		authGroup.GET("/chat/conversations/:id", handlers.GetChatConversation)
 		authGroup.DELETE("/chat/conversations/:id", handlers.DeleteChatConversation)
+		authGroup.GET("/chat/trash", handlers.GetConversationTrash)
+		authGroup.POST("/chat/trash/:id/restore", handlers.RestoreChatConversation)
+		authGroup.DELETE("/chat/trash/:id", handlers.PurgeChatConversation)
 		authGroup.GET("/history", handlers.GetHistory)
+		authGroup.GET("/notifications", handlers.GetNotifications)
+		authGroup.POST("/notifications/:id/read", handlers.MarkNotificationRead)
+		authGroup.PUT("/me/language", handlers.UpdateMyLanguage)
+
+		// Procedure feedback (helpfulness ratings, comments and clarification requests)
+		authGroup.PUT("/procedures/:id/rating", handlers.RateProcedure)
+		authGroup.DELETE("/procedures/:id/rating", handlers.RemoveProcedureRating)
+		authGroup.GET("/procedures/:id/comments", handlers.GetProcedureComments)
+		authGroup.POST("/procedures/:id/comments", handlers.AddProcedureComment)
+
+		// Procedure runs (a procedure worked through as a checklist)
+		authGroup.POST("/procedures/:id/runs", handlers.StartProcedureRun)
+		authGroup.GET("/runs", handlers.GetMyRuns)
+		authGroup.GET("/runs/:id", handlers.GetRun)
+		authGroup.PUT("/runs/:id/steps/:number", handlers.UpdateRunStep)
+		authGroup.PUT("/runs/:id/steps/:number/assignee", handlers.AssignRunStep)
+		authGroup.POST("/runs/:id/cancel", handlers.CancelRun)
 	}
 
 	// Admin protected routes (require admin role)
@@ -38,10 +71,59 @@ func SetupRoutes(router *gin.Engine) {
 	adminGroup.Use(middleware.AdminAuth())
 	{
 		// Procedure management
+		adminGroup.GET("/procedures", handlers.GetAdminProcedures)
+		adminGroup.GET("/procedures/stale", handlers.GetStaleProcedures)
+		adminGroup.GET("/procedures/duplicates", handlers.GetDuplicateProcedures)
+		adminGroup.GET("/procedures/:id", handlers.GetAdminProcedureByID)
 		adminGroup.POST("/procedures", handlers.CreateProcedure)
 		adminGroup.PUT("/procedures/:id", handlers.UpdateProcedure)
+		adminGroup.PATCH("/procedures/:id", handlers.PatchProcedure)
 		adminGroup.DELETE("/procedures/:id", handlers.DeleteProcedure)
 		adminGroup.POST("/procedures/upload", handlers.UploadProcedureFile)
+		adminGroup.POST("/procedures/tags", handlers.BulkUpdateProcedureTags)
+		adminGroup.PUT("/procedures/:id/steps", handlers.UpdateProcedureSteps)
+		adminGroup.PUT("/procedures/:id/steps/:number", handlers.UpdateProcedureStep)
+
+		// Review workflow (draft -> in_review -> published -> archived)
+		adminGroup.POST("/procedures/:id/submit", handlers.SubmitProcedure)
+		adminGroup.POST("/procedures/:id/approve", handlers.ApproveProcedure)
+		adminGroup.POST("/procedures/:id/reject", handlers.RejectProcedure)
+		adminGroup.POST("/procedures/:id/archive", handlers.ArchiveProcedure)
+		adminGroup.POST("/procedures/:id/reopen", handlers.ReopenProcedure)
+		adminGroup.PUT("/procedures/:id/reviewer", handlers.AssignProcedureReviewer)
+
+		// Ownership and periodic review
+		adminGroup.PUT("/procedures/:id/ownership", handlers.UpdateProcedureOwnership)
+		adminGroup.POST("/procedures/:id/reviewed", handlers.MarkProcedureReviewed)
+
+		// Visibility (public, authenticated or restricted to departments/roles)
+		adminGroup.PUT("/procedures/:id/visibility", handlers.UpdateProcedureVisibility)
+
+		// Translations (drafts, optionally AI-generated, are shown to readers once approved)
+		adminGroup.GET("/procedures/:id/translations", handlers.GetProcedureTranslations)
+		adminGroup.PUT("/procedures/:id/translations/:lang", handlers.UpsertProcedureTranslation)
+		adminGroup.POST("/procedures/:id/translations/:lang/draft", handlers.DraftProcedureTranslation)
+		adminGroup.POST("/procedures/:id/translations/:lang/approve", handlers.ApproveProcedureTranslation)
+		adminGroup.DELETE("/procedures/:id/translations/:lang", handlers.DeleteProcedureTranslation)
+
+		// AI-generated description, summary and FAQ (used by readers, search and chat once approved)
+		adminGroup.GET("/procedures/:id/enrichment", handlers.GetProcedureEnrichment)
+		adminGroup.POST("/procedures/:id/enrichment/generate", handlers.GenerateProcedureEnrichment)
+		adminGroup.PUT("/procedures/:id/enrichment", handlers.UpdateProcedureEnrichment)
+		adminGroup.POST("/procedures/:id/enrichment/approve", handlers.ApproveProcedureEnrichment)
+		adminGroup.DELETE("/procedures/:id/enrichment", handlers.DeleteProcedureEnrichment)
+
+		// Documents converted into procedures by the AI (upload with ?structure=true), awaiting review
+		adminGroup.GET("/imports", handlers.GetProcedureImports)
+		adminGroup.GET("/imports/:id", handlers.GetProcedureImport)
+		adminGroup.POST("/imports/:id/accept", handlers.AcceptProcedureImport)
+		adminGroup.POST("/imports/:id/reject", handlers.RejectProcedureImport)
+
+		// Procedure revision history
+		adminGroup.GET("/procedures/:id/revisions", handlers.GetProcedureRevisions)
+		adminGroup.GET("/procedures/:id/revisions/diff", handlers.DiffProcedureRevisions)
+		adminGroup.GET("/procedures/:id/revisions/:rev", handlers.GetProcedureRevision)
+		adminGroup.POST("/procedures/:id/revisions/:rev/restore", handlers.RestoreProcedureRevision)
 
 		// User management
 		adminGroup.GET("/users", handlers.GetAllUsers)
@@ -51,8 +133,51 @@ func SetupRoutes(router *gin.Engine) {
 
 		// Category management
 		adminGroup.POST("/categories", handlers.CreateCategory)
+		adminGroup.PUT("/categories/:id", handlers.UpdateCategory)
+		adminGroup.DELETE("/categories/:id", handlers.DeleteCategory)
+
+		// Glossary (synonyms and acronyms used by search and chat)
+		adminGroup.GET("/glossary", handlers.GetGlossaryEntries)
+		adminGroup.POST("/glossary", handlers.CreateGlossaryEntry)
+		adminGroup.PUT("/glossary/:id", handlers.UpdateGlossaryEntry)
+		adminGroup.DELETE("/glossary/:id", handlers.DeleteGlossaryEntry)
+
+		// Trash (soft-deleted procedures)
+		adminGroup.GET("/trash", handlers.GetTrash)
+		adminGroup.POST("/trash/purge", handlers.PurgeExpiredTrash)
+		adminGroup.POST("/trash/procedures/:id/restore", handlers.RestoreProcedure)
+		adminGroup.DELETE("/trash/procedures/:id", handlers.PurgeProcedure)
 
 		// Statistics
 		adminGroup.GET("/stats", handlers.GetAdminStats)
+
+		// Reader feedback
+		adminGroup.GET("/feedback", handlers.GetFeedbackReport)
+		adminGroup.POST("/comments/:id/resolve", handlers.ResolveProcedureComment)
+
+		// Question analytics (what employees ask and what the knowledge base cannot answer)
+		adminGroup.GET("/questions/trending", handlers.GetTrendingQuestions)
+		adminGroup.GET("/questions/unanswered", handlers.GetUnansweredQuestions)
+		adminGroup.GET("/questions/suggestions", handlers.GetProcedureSuggestions)
+		adminGroup.GET("/questions/search-misses", handlers.GetSearchMisses)
+
+		// Procedure runs in progress and overdue
+		adminGroup.GET("/runs", handlers.GetAdminRuns)
+		adminGroup.GET("/runs/summary", handlers.GetRunSummary)
+
+		// Outbound webhooks and their delivery log
+		adminGroup.GET("/webhooks", handlers.GetWebhooks)
+		adminGroup.POST("/webhooks", handlers.CreateWebhook)
+		adminGroup.GET("/webhooks/:id", handlers.GetWebhook)
+		adminGroup.PUT("/webhooks/:id", handlers.UpdateWebhook)
+		adminGroup.DELETE("/webhooks/:id", handlers.DeleteWebhook)
+		adminGroup.POST("/webhooks/:id/ping", handlers.PingWebhook)
+		adminGroup.GET("/webhooks/:id/deliveries", handlers.GetWebhookDeliveries)
+		adminGroup.GET("/webhook-deliveries/:id", handlers.GetWebhookDelivery)
+		adminGroup.POST("/webhook-deliveries/:id/redeliver", handlers.RedeliverWebhookDelivery)
+
+		// Knowledge-base backup and restore
+		adminGroup.GET("/backup", handlers.ExportBackup)
+		adminGroup.POST("/backup/restore", handlers.RestoreBackup)
 	}
 }
@@ -11,6 +11,7 @@ import (
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"web_AI/models"
 
@@ -30,24 +31,96 @@ func CallMistralAPI(question string) (string, error) {
 
 // CallMistralAPIWithRAG calls AI with relevant procedures context
 func CallMistralAPIWithRAG(userID string, question string) (string, error) {
+	return CallMistralAPIWithRAGOptions(userID, question, models.RAGOptions{})
+}
+
+// CallMistralAPIWithRAGOptions calls AI with relevant procedures context, ranking procedures
+// tagged with the boost tags (or tags mentioned in the question) first, and answers in opts.Language
+func CallMistralAPIWithRAGOptions(userID string, question string, opts models.RAGOptions) (string, error) {
+	result, err := AnswerWithRAG(userID, question, opts)
+	if err != nil {
+		return "", err
+	}
+	return result.Answer, nil
+}
+
+// AnswerWithRAG answers like CallMistralAPIWithRAGOptions and also reports what retrieval found,
+// so question analytics can tell well-covered questions from unanswered ones
+func AnswerWithRAG(userID string, question string, opts models.RAGOptions) (*models.RAGAnswer, error) {
+	lang := NormalizeLanguage(opts.Language)
+	if lang == "" {
+		lang = models.DefaultLanguage
+	}
+
 	// 1. Search for relevant procedures based on question
-	relevantProcedures, err := SearchProcedures(question)
+	// Only procedures the asker may see are used, so restricted content never reaches the answer
+	relevantProcedures, err := SearchProcedures(models.ProcedureQuery{Search: question, Viewer: opts.Viewer, Language: lang})
 	if err != nil {
 		fmt.Printf("🔍 RAG Search Error: %v\n", err)
 		// Fallback to normal AI call if search fails
-		return CallMistralAPIWithHistory(userID, question)
+		answer, err := CallMistralAPIWithHistory(userID, question)
+		if err != nil {
+			return nil, err
+		}
+		return &models.RAGAnswer{Answer: answer}, nil
+	}
+
+	boostTags := NormalizeTags(append(append([]string{}, opts.BoostTags...), findTagsInText(question, opts.Viewer)...))
+	if len(boostTags) > 0 {
+		// Procedures with a boost tag are candidates even when the question text does not match them
+		tagged, err := GetProcedures(models.ProcedureQuery{Tags: boostTags, Limit: 5, Viewer: opts.Viewer})
+		if err != nil {
+			fmt.Printf("🔍 RAG Tag Search Error: %v\n", err)
+		}
+		relevantProcedures = mergeProcedures(relevantProcedures, tagged)
+		rankProceduresByTags(relevantProcedures, boostTags)
 	}
 
-	// 2. Build context from relevant procedures
+	// 2. Build context from relevant procedures (translated when an approved translation exists) and the company glossary
+	LocalizeProcedures(relevantProcedures, lang)
 	context := buildProcedureContext(relevantProcedures)
+	context += buildGlossaryContext(FindGlossaryMatches(question))
 
 	// 3. Create enhanced prompt with context
-	enhancedQuestion := buildRAGPrompt(context, question)
+	enhancedQuestion := buildRAGPrompt(context, question, lang)
 
 	fmt.Printf("🤖 RAG Enhanced Question: %s\n", enhancedQuestion[:200]+"...")
 
 	// 4. Call AI with enhanced context
-	return CallMistralAPIWithHistory(userID, enhancedQuestion)
+	answer, err := CallMistralAPIWithHistory(userID, enhancedQuestion)
+	if err != nil {
+		return nil, err
+	}
+
+	// 5. Warn when the answer may rely on outdated procedures
+	cited := relevantProcedures
+	if len(cited) > 5 {
+		cited = cited[:5]
+	}
+
+	result := &models.RAGAnswer{Answer: answer + StaleProcedureWarning(cited), Hits: len(cited)}
+	for i := range cited {
+		result.ProcedureIDs = append(result.ProcedureIDs, cited[i].ID)
+		if score := retrievalScore(question, &cited[i]); score > result.TopScore {
+			result.TopScore = score
+		}
+	}
+	return result, nil
+}
+
+// mergeProcedures appends procedures from extra that are not already in list
+func mergeProcedures(list []models.Procedure, extra []models.Procedure) []models.Procedure {
+	seen := make(map[primitive.ObjectID]bool, len(list))
+	for _, p := range list {
+		seen[p.ID] = true
+	}
+	for _, p := range extra {
+		if !seen[p.ID] {
+			seen[p.ID] = true
+			list = append(list, p)
+		}
+	}
+	return list
 }
 
 // buildProcedureContext creates context string from procedures
@@ -65,32 +138,136 @@ func buildProcedureContext(procedures []models.Procedure) string {
 		}
 
 		contextBuilder.WriteString(fmt.Sprintf("**%d. %s** (Danh mục: %s)\n", i+1, procedure.Title, procedure.Category))
+		if len(procedure.Tags) > 0 {
+			contextBuilder.WriteString(fmt.Sprintf("Thẻ: %s\n", strings.Join(procedure.Tags, ", ")))
+		}
 
 		if procedure.Description != "" {
 			contextBuilder.WriteString(fmt.Sprintf("Mô tả: %s\n", procedure.Description))
 		}
+		if enrichment := approvedEnrichment(&procedure); enrichment != nil {
+			contextBuilder.WriteString(buildEnrichmentContext(enrichment))
+		}
 
-		// Truncate content if too long
-		content := procedure.Content
-		if len(content) > 500 {
-			content = content[:500] + "..."
+		// Structured steps carry roles and documents, so they get more room than free text
+		if len(procedure.Steps) > 0 {
+			steps := truncateText(RenderStepsContent(procedure.Steps), 1500)
+			contextBuilder.WriteString(fmt.Sprintf("Các bước:\n%s\n\n", steps))
+			continue
 		}
+
+		// Truncate content if too long
+		content := truncateText(procedure.Content, 500)
 		contextBuilder.WriteString(fmt.Sprintf("Nội dung:\n%s\n\n", content))
 	}
 
 	return contextBuilder.String()
 }
 
+// buildEnrichmentContext adds a procedure's approved summary and FAQ to the RAG context
+func buildEnrichmentContext(enrichment *models.ProcedureEnrichment) string {
+	var sb strings.Builder
+	if len(enrichment.Summary) > 0 {
+		sb.WriteString("Tóm tắt:\n")
+		for _, point := range enrichment.Summary {
+			sb.WriteString(fmt.Sprintf("- %s\n", point))
+		}
+	}
+	if len(enrichment.FAQ) > 0 {
+		sb.WriteString("Câu hỏi thường gặp:\n")
+		for _, pair := range enrichment.FAQ {
+			sb.WriteString(fmt.Sprintf("H: %s\nĐ: %s\n", pair.Question, pair.Answer))
+		}
+	}
+	return truncateText(sb.String(), 1000)
+}
+
+// truncateText shortens text to at most max bytes without cutting a UTF-8 character in half
+func truncateText(text string, max int) string {
+	if len(text) <= max {
+		return text
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut] + "..."
+}
+
+// BuildProcedurePrompt creates a prompt for a question about one procedure, optionally focused on a single step,
+// to be answered in lang
+func BuildProcedurePrompt(procedure *models.Procedure, stepNumber string, question string, lang string) (string, error) {
+	body := fmt.Sprintf("**Nội dung:**\n%s", procedure.Content)
+	if len(procedure.Steps) > 0 {
+		body = fmt.Sprintf("**Các bước:**\n%s", RenderStepsContent(procedure.Steps))
+	}
+
+	focus := ""
+	if stepNumber != "" {
+		step, err := FindStep(procedure.Steps, stepNumber)
+		if err != nil {
+			return "", err
+		}
+		focus = fmt.Sprintf("\n\nCâu hỏi liên quan đến bước %s:\n%s", stepNumber, RenderStepsContent([]models.ProcedureStep{*step}))
+	}
+
+	return fmt.Sprintf(`Dựa trên quy trình "%s" sau:
+
+**Tiêu đề:** %s
+**Danh mục:** %s  
+**Mô tả:** %s
+%s%s
+
+---
+
+**Câu hỏi:** %s
+
+Hãy trả lời câu hỏi bằng %s dựa trên thông tin quy trình trên.`,
+		procedure.Title, procedure.Title, procedure.Category,
+		procedure.Description, body, focus, question, answerLanguageName(lang)), nil
+}
+
+// buildGlossaryContext explains the company terms and acronyms used in the question
+func buildGlossaryContext(entries []models.GlossaryEntry) string {
+	if len(entries) == 0 {
+		return ""
+	}
+
+	var contextBuilder strings.Builder
+	contextBuilder.WriteString("\n📖 Thuật ngữ nội bộ:\n")
+	for _, entry := range entries {
+		contextBuilder.WriteString("- " + entry.Term)
+		if len(entry.Synonyms) > 0 {
+			contextBuilder.WriteString(" (còn gọi là: " + strings.Join(entry.Synonyms, ", ") + ")")
+		}
+		if entry.Definition != "" {
+			contextBuilder.WriteString(": " + entry.Definition)
+		}
+		contextBuilder.WriteString("\n")
+	}
+
+	return contextBuilder.String()
+}
+
+// answerLanguageName names the answer language in prompts, defaulting to Vietnamese
+func answerLanguageName(lang string) string {
+	if name, ok := languageNames[lang]; ok {
+		return name
+	}
+	return languageNames[models.DefaultLanguage]
+}
+
 // buildRAGPrompt creates enhanced prompt with context
-func buildRAGPrompt(context string, question string) string {
+func buildRAGPrompt(context string, question string, lang string) string {
 	systemPrompt := `Bạn là AI Assistant cho hệ thống quản lý quy trình nội bộ của công ty. 
 Nhiệm vụ của bạn là trả lời câu hỏi dựa trên thông tin quy trình được cung cấp.
 
 HƯỚNG DẪN TRẢ LỜI:
 1. Ưu tiên sử dụng thông tin từ quy trình được cung cấp
-2. Trả lời bằng tiếng Việt, rõ ràng và chi tiết
+2. Trả lời bằng ` + answerLanguageName(lang) + `, rõ ràng và chi tiết
 3. Nếu không có thông tin liên quan, hãy thông báo và đưa ra gợi ý chung
 4. Luôn thân thiện và hỗ trợ tối đa
+5. Quy trình chưa có bản dịch được cung cấp bằng ngôn ngữ gốc; hãy dịch phần cần thiết sang ngôn ngữ trả lời
 
 THÔNG TIN QUY TRÌNH:
 ` + context + `
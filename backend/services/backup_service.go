@@ -0,0 +1,705 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+	"web_AI/config"
+	"web_AI/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// UploadDir is where uploaded procedure files are stored
+var UploadDir = uploadDirFromEnv()
+
+func uploadDirFromEnv() string {
+	if dir := os.Getenv("UPLOAD_DIR"); dir != "" {
+		return dir
+	}
+	return "uploads"
+}
+
+// backupCollection describes how one collection is exported and merged back
+type backupCollection struct {
+	name string
+	// naturalKey finds an existing document that is the same entity under a different _id; nil if there is none
+	naturalKey func(doc bson.M) bson.M
+	// immutable documents are never overwritten when merging
+	immutable bool
+	// versioned documents carry an optimistic-lock version that must change whenever they are overwritten
+	versioned bool
+	// checkUnique rejects a document whose unique name another document (not selfID) already uses
+	checkUnique func(ctx context.Context, doc bson.M, selfID primitive.ObjectID) error
+}
+
+// backupCollections are imported in this order so referenced IDs are remapped before they are used
+var backupCollections = []backupCollection{
+	{name: "categories", naturalKey: func(doc bson.M) bson.M { return bson.M{"name": doc["name"]} },
+		checkUnique: func(ctx context.Context, doc bson.M, selfID primitive.ObjectID) error {
+			name, _ := doc["name"].(string)
+			return checkCategoryNameAvailable(ctx, name, selfID)
+		}},
+	{name: "glossary", naturalKey: func(doc bson.M) bson.M { return bson.M{"term": doc["term"]} },
+		checkUnique: func(ctx context.Context, doc bson.M, selfID primitive.ObjectID) error {
+			term, _ := doc["term"].(string)
+			return checkGlossaryTermAvailable(ctx, term, selfID)
+		}},
+	{name: "procedures", versioned: true},
+	{name: "procedure_revisions", immutable: true, naturalKey: func(doc bson.M) bson.M {
+		return bson.M{"procedure_id": doc["procedure_id"], "revision": doc["revision"]}
+	}},
+	{name: "uploaded_files", naturalKey: func(doc bson.M) bson.M { return bson.M{"hash": doc["hash"]} }},
+}
+
+// backupReferenceFields are fields holding IDs of documents from other backed-up collections
+var backupReferenceFields = []string{"procedure_id", "category_id", "parent_id"}
+
+const (
+	backupManifestName  = "manifest.json"
+	backupDataDir       = "data/"
+	backupUploadsDir    = "uploads/"
+	backupStagingSuffix = "_restore"
+
+	// MaxBackupSize is the largest archive accepted for import
+	MaxBackupSize = 1 << 30
+	// Limits on what a single archive entry may expand to, so a small archive cannot exhaust memory
+	maxBackupJSONSize = 256 << 20
+	maxBackupFileSize = MaxUploadSize * 10
+)
+
+// ExportBackup writes procedures, categories, revisions, the glossary and uploaded files into a ZIP archive
+func ExportBackup(w io.Writer) (*models.BackupManifest, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	manifest := &models.BackupManifest{
+		FormatVersion: models.BackupFormatVersion,
+		App:           "web_AI",
+		CreatedAt:     time.Now().UTC(),
+		Collections:   map[string]int{},
+		Files:         []models.BackupFile{},
+	}
+	zw := zip.NewWriter(w)
+
+	for _, coll := range backupCollections {
+		count, err := exportCollection(ctx, zw, coll.name)
+		if err != nil {
+			return nil, fmt.Errorf("export %s: %v", coll.name, err)
+		}
+		manifest.Collections[coll.name] = count
+	}
+
+	err := Files.List(ctx, func(info FileInfo) error {
+		file, err := exportFile(ctx, zw, info.Name, backupUploadsDir+info.Name)
+		if err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, *file)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("export uploads: %v", err)
+	}
+
+	mw, err := zw.Create(backupManifestName)
+	if err != nil {
+		return nil, err
+	}
+	enc := json.NewEncoder(mw)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(manifest); err != nil {
+		return nil, err
+	}
+
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// exportCollection writes every document as canonical Extended JSON so types like ObjectID and dates survive the round trip
+func exportCollection(ctx context.Context, zw *zip.Writer, name string) (int, error) {
+	cursor, err := config.GetCollection(name).Find(ctx, bson.M{}, options.Find().SetSort(bson.D{bson.E{Key: "_id", Value: 1}}))
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(ctx)
+
+	w, err := zw.Create(backupDataDir + name + ".json")
+	if err != nil {
+		return 0, err
+	}
+	if _, err := io.WriteString(w, "["); err != nil {
+		return 0, err
+	}
+	count := 0
+	for cursor.Next(ctx) {
+		doc, err := bson.MarshalExtJSON(cursor.Current, true, false)
+		if err != nil {
+			return 0, err
+		}
+		sep := "\n"
+		if count > 0 {
+			sep = ",\n"
+		}
+		if _, err := io.WriteString(w, sep); err != nil {
+			return 0, err
+		}
+		if _, err := w.Write(doc); err != nil {
+			return 0, err
+		}
+		count++
+	}
+	if err := cursor.Err(); err != nil {
+		return 0, err
+	}
+	_, err = io.WriteString(w, "\n]\n")
+	return count, err
+}
+
+func exportFile(ctx context.Context, zw *zip.Writer, src string, name string) (*models.BackupFile, error) {
+	f, _, err := Files.Open(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	w, err := zw.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(w, hash), f)
+	if err != nil {
+		return nil, err
+	}
+	return &models.BackupFile{Path: name, Size: size, SHA256: hex.EncodeToString(hash.Sum(nil))}, nil
+}
+
+// ImportBackup restores an archive created by ExportBackup. The whole archive is read and checked
+// against its manifest before anything is written. Replace mode restores into staging collections
+// that only take the place of the live ones once every document is in, so a failed import leaves
+// the existing data untouched. With DryRun nothing is written but the report shows what would happen.
+func ImportBackup(r io.ReaderAt, size int64, opts models.BackupImportOptions) (*models.BackupImportReport, error) {
+	if opts.Mode == "" {
+		opts.Mode = models.BackupModeMerge
+	}
+	if opts.Mode != models.BackupModeMerge && opts.Mode != models.BackupModeReplace {
+		return nil, fmt.Errorf("invalid import mode '%s' (use merge or replace)", opts.Mode)
+	}
+
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup archive: %v", err)
+	}
+	entries := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		entries[f.Name] = f
+	}
+
+	var manifest models.BackupManifest
+	if err := readZipJSON(entries[backupManifestName], &manifest); err != nil {
+		return nil, fmt.Errorf("invalid backup manifest: %v", err)
+	}
+	if manifest.FormatVersion < 1 || manifest.FormatVersion > models.BackupFormatVersion {
+		return nil, fmt.Errorf("unsupported backup format version %d (this server supports up to %d)", manifest.FormatVersion, models.BackupFormatVersion)
+	}
+
+	report := &models.BackupImportReport{
+		Mode:          opts.Mode,
+		DryRun:        opts.DryRun,
+		FormatVersion: manifest.FormatVersion,
+		BackupDate:    manifest.CreatedAt,
+		Collections:   map[string]*models.BackupCollectionReport{},
+		IDRemaps:      []models.BackupIDRemap{},
+		Warnings:      []string{},
+	}
+
+	collections := make(map[string][]bson.M, len(backupCollections))
+	for _, coll := range backupCollections {
+		docs, err := readBackupDocuments(entries[backupDataDir+coll.name+".json"])
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %v", coll.name, err)
+		}
+		if expected, listed := manifest.Collections[coll.name]; listed && expected != len(docs) {
+			return nil, fmt.Errorf("read %s: the archive holds %d documents but the manifest lists %d", coll.name, len(docs), expected)
+		}
+		collections[coll.name] = docs
+	}
+	uploads, err := verifyBackupFiles(zr.File, manifest.Files, report)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup archive: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	previousVersions := make(map[primitive.ObjectID]int64)
+	targets := make(map[string]*mongo.Collection, len(backupCollections))
+	for _, coll := range backupCollections {
+		collReport := &models.BackupCollectionReport{}
+		report.Collections[coll.name] = collReport
+		targets[coll.name] = config.GetCollection(coll.name)
+		if opts.Mode != models.BackupModeReplace {
+			continue
+		}
+		if err := readReplacedCollection(ctx, coll, collReport, previousVersions); err != nil {
+			return report, fmt.Errorf("read %s: %v", coll.name, err)
+		}
+		if !opts.DryRun {
+			staging, err := createStagingCollection(ctx, coll.name)
+			if err != nil {
+				dropStagingCollections(targets)
+				return report, fmt.Errorf("prepare %s: %v", coll.name, err)
+			}
+			targets[coll.name] = staging
+		}
+	}
+
+	remaps := make(map[primitive.ObjectID]primitive.ObjectID)
+	for _, coll := range backupCollections {
+		if err := importCollection(ctx, coll, targets[coll.name], collections[coll.name], opts, report.Collections[coll.name], report, remaps, previousVersions); err != nil {
+			if opts.Mode == models.BackupModeReplace {
+				dropStagingCollections(targets)
+			}
+			return report, fmt.Errorf("import %s: %v", coll.name, err)
+		}
+	}
+
+	if opts.Mode == models.BackupModeReplace && !opts.DryRun {
+		for _, coll := range backupCollections {
+			if err := swapStagingCollection(ctx, coll.name); err != nil {
+				dropStagingCollections(targets)
+				return report, fmt.Errorf("replace %s: %v", coll.name, err)
+			}
+		}
+	}
+
+	if err := importFiles(ctx, uploads, opts, report); err != nil {
+		return report, err
+	}
+
+	if !opts.DryRun {
+		InvalidateSuggestIndex()
+		invalidateGlossaryCache()
+	}
+	return report, nil
+}
+
+func readZipJSON(f *zip.File, v interface{}) error {
+	if f == nil {
+		return fmt.Errorf("missing file")
+	}
+	data, err := readZipEntry(f, maxBackupJSONSize)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// readBackupDocuments parses a collection dump; a missing dump is treated as an empty collection
+func readBackupDocuments(f *zip.File) ([]bson.M, error) {
+	if f == nil {
+		return nil, nil
+	}
+	var raws []json.RawMessage
+	if err := readZipJSON(f, &raws); err != nil {
+		return nil, err
+	}
+	docs := make([]bson.M, 0, len(raws))
+	for i, raw := range raws {
+		var doc bson.M
+		if err := bson.UnmarshalExtJSON(raw, true, &doc); err != nil {
+			return nil, fmt.Errorf("document %d: %v", i+1, err)
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// verifyBackupFiles checks every upload in the archive against the size and SHA-256 recorded in the
+// manifest and returns the ones to restore. Unsafe paths are skipped with a warning.
+func verifyBackupFiles(files []*zip.File, listed []models.BackupFile, report *models.BackupImportReport) ([]backupUpload, error) {
+	expected := make(map[string]models.BackupFile, len(listed))
+	for _, file := range listed {
+		expected[file.Path] = file
+	}
+
+	var uploads []backupUpload
+	for _, f := range files {
+		if !strings.HasPrefix(f.Name, backupUploadsDir) || strings.HasSuffix(f.Name, "/") {
+			continue
+		}
+		want, ok := expected[f.Name]
+		delete(expected, f.Name)
+		rel := path.Clean(strings.TrimPrefix(f.Name, backupUploadsDir))
+		if rel == "." || strings.HasPrefix(rel, "../") || rel == ".." || path.IsAbs(rel) {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("skipped unsafe file path %q", f.Name))
+			report.Files.Skipped++
+			continue
+		}
+		if !ok {
+			return nil, fmt.Errorf("%s is not listed in the manifest", f.Name)
+		}
+		if want.Size > maxBackupFileSize {
+			return nil, fmt.Errorf("%s is larger than %d bytes", f.Name, int64(maxBackupFileSize))
+		}
+		if err := verifyZipEntry(f, want); err != nil {
+			return nil, fmt.Errorf("%s: %v", f.Name, err)
+		}
+		uploads = append(uploads, backupUpload{entry: f, name: rel})
+	}
+	for name := range expected {
+		return nil, fmt.Errorf("%s is listed in the manifest but missing from the archive", name)
+	}
+	return uploads, nil
+}
+
+// backupUpload is a verified upload in an archive and its name in file storage
+type backupUpload struct {
+	entry *zip.File
+	name  string
+}
+
+func verifyZipEntry(f *zip.File, want models.BackupFile) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	// Reading one byte past the listed size is enough to tell it is wrong
+	hash := sha256.New()
+	size, err := io.Copy(hash, io.LimitReader(rc, want.Size+1))
+	if err != nil {
+		return err
+	}
+	if size != want.Size {
+		return fmt.Errorf("size is %d bytes but the manifest lists %d", size, want.Size)
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); sum != want.SHA256 {
+		return fmt.Errorf("SHA-256 is %s but the manifest lists %s", sum, want.SHA256)
+	}
+	return nil
+}
+
+// readReplacedCollection counts the documents a replace import removes, remembering the versions of
+// versioned documents so the restored ones can move past them
+func readReplacedCollection(ctx context.Context, coll backupCollection, collReport *models.BackupCollectionReport, previousVersions map[primitive.ObjectID]int64) error {
+	collection := config.GetCollection(coll.name)
+
+	existing, err := collection.CountDocuments(ctx, bson.M{})
+	if err != nil {
+		return err
+	}
+	collReport.Deleted = int(existing)
+
+	if !coll.versioned {
+		return nil
+	}
+	cursor, err := collection.Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{"version": 1}))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID      primitive.ObjectID `bson:"_id"`
+			Version int64              `bson:"version"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+		previousVersions[doc.ID] = doc.Version
+	}
+	return cursor.Err()
+}
+
+// createStagingCollection creates an empty copy of a collection with the same indexes, so documents
+// that would violate a unique index are rejected before the live collection is replaced
+func createStagingCollection(ctx context.Context, name string) (*mongo.Collection, error) {
+	staging := config.GetCollection(name + backupStagingSuffix)
+	if err := staging.Drop(ctx); err != nil {
+		return nil, err
+	}
+	if err := config.DB.CreateCollection(ctx, staging.Name()); err != nil {
+		return nil, err
+	}
+
+	// Listing the indexes of a collection that was never created fails
+	existing, err := config.DB.ListCollectionNames(ctx, bson.M{"name": name})
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) == 0 {
+		return staging, nil
+	}
+	cursor, err := config.GetCollection(name).Indexes().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var specs []bson.M
+	if err := cursor.All(ctx, &specs); err != nil {
+		return nil, err
+	}
+	var indexes bson.A
+	for _, spec := range specs {
+		if spec["name"] == "_id_" {
+			continue
+		}
+		delete(spec, "ns")
+		indexes = append(indexes, spec)
+	}
+	if len(indexes) > 0 {
+		cmd := bson.D{{Key: "createIndexes", Value: staging.Name()}, {Key: "indexes", Value: indexes}}
+		if err := config.DB.RunCommand(ctx, cmd).Err(); err != nil {
+			return nil, err
+		}
+	}
+	return staging, nil
+}
+
+// swapStagingCollection replaces a live collection with its restored staging copy
+func swapStagingCollection(ctx context.Context, name string) error {
+	db := config.DB.Name()
+	cmd := bson.D{
+		{Key: "renameCollection", Value: db + "." + name + backupStagingSuffix},
+		{Key: "to", Value: db + "." + name},
+		{Key: "dropTarget", Value: true},
+	}
+	return config.DB.Client().Database("admin").RunCommand(ctx, cmd).Err()
+}
+
+// dropStagingCollections removes the staging copies of a failed replace import
+func dropStagingCollections(targets map[string]*mongo.Collection) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	for name, collection := range targets {
+		if collection.Name() == name {
+			continue
+		}
+		if err := collection.Drop(ctx); err != nil {
+			fmt.Printf("⚠️ Failed to drop staging collection %s: %v\n", collection.Name(), err)
+		}
+	}
+}
+
+// importCollection writes the documents of one collection into collection, which is the staging copy in replace mode
+func importCollection(ctx context.Context, coll backupCollection, collection *mongo.Collection, docs []bson.M, opts models.BackupImportOptions,
+	collReport *models.BackupCollectionReport, report *models.BackupImportReport, remaps map[primitive.ObjectID]primitive.ObjectID,
+	previousVersions map[primitive.ObjectID]int64) error {
+
+	for _, doc := range docs {
+		oldID, ok := doc["_id"].(primitive.ObjectID)
+		if !ok {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("%s: skipped a document without an ObjectID _id", coll.name))
+			collReport.Skipped++
+			continue
+		}
+		for _, field := range backupReferenceFields {
+			if ref, ok := doc[field].(primitive.ObjectID); ok {
+				if mapped, found := remaps[ref]; found {
+					doc[field] = mapped
+				}
+			}
+		}
+
+		if opts.NewIDs && opts.Mode == models.BackupModeMerge && coll.checkUnique != nil {
+			// A copy must not duplicate a unique name; references go to the existing document instead
+			if err := coll.checkUnique(ctx, doc, primitive.NilObjectID); err != nil {
+				var existing struct {
+					ID primitive.ObjectID `bson:"_id"`
+				}
+				if findErr := collection.FindOne(ctx, coll.naturalKey(doc)).Decode(&existing); findErr != nil {
+					return err
+				}
+				remaps[oldID] = existing.ID
+				report.IDRemaps = append(report.IDRemaps, models.BackupIDRemap{Collection: coll.name, OldID: oldID.Hex(), NewID: existing.ID.Hex(), Reason: "matched existing document"})
+				report.Warnings = append(report.Warnings, fmt.Sprintf("%s: kept existing document instead of a copy: %v", coll.name, err))
+				collReport.Skipped++
+				continue
+			}
+		}
+		if opts.NewIDs {
+			newID := primitive.NewObjectID()
+			remaps[oldID] = newID
+			doc["_id"] = newID
+			report.IDRemaps = append(report.IDRemaps, models.BackupIDRemap{Collection: coll.name, OldID: oldID.Hex(), NewID: newID.Hex(), Reason: "new_ids"})
+		}
+
+		if opts.Mode == models.BackupModeMerge && !opts.NewIDs {
+			handled, err := mergeExistingDocument(ctx, coll, doc, oldID, opts.DryRun, collReport, report, remaps)
+			if err != nil {
+				return err
+			}
+			if handled {
+				continue
+			}
+		}
+
+		if coll.versioned {
+			if previous, found := previousVersions[oldID]; found {
+				version, _ := doc["version"].(int64)
+				if previous > version {
+					version = previous
+				}
+				doc["version"] = version + 1
+			}
+		}
+		if !opts.DryRun {
+			if _, err := collection.InsertOne(ctx, doc); err != nil {
+				return err
+			}
+		}
+		collReport.Created++
+	}
+	return nil
+}
+
+// mergeExistingDocument updates a document that already exists under the same _id or natural key.
+// It reports false when the document is new and should be inserted.
+func mergeExistingDocument(ctx context.Context, coll backupCollection, doc bson.M, oldID primitive.ObjectID, dryRun bool,
+	collReport *models.BackupCollectionReport, report *models.BackupImportReport, remaps map[primitive.ObjectID]primitive.ObjectID) (bool, error) {
+	collection := config.GetCollection(coll.name)
+
+	targetID := oldID
+	err := collection.FindOne(ctx, bson.M{"_id": oldID}, options.FindOne().SetProjection(bson.M{"_id": 1})).Err()
+	if err == mongo.ErrNoDocuments && coll.naturalKey != nil {
+		var existing struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		err = collection.FindOne(ctx, coll.naturalKey(doc)).Decode(&existing)
+		if err == nil {
+			targetID = existing.ID
+			remaps[oldID] = targetID
+			report.IDRemaps = append(report.IDRemaps, models.BackupIDRemap{Collection: coll.name, OldID: oldID.Hex(), NewID: targetID.Hex(), Reason: "matched existing document"})
+		}
+	}
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	if coll.immutable {
+		collReport.Skipped++
+		return true, nil
+	}
+	if coll.checkUnique != nil {
+		if err := coll.checkUnique(ctx, doc, targetID); err != nil {
+			report.Warnings = append(report.Warnings, fmt.Sprintf("%s: kept existing %s: %v", coll.name, targetID.Hex(), err))
+			collReport.Skipped++
+			return true, nil
+		}
+	}
+	doc["_id"] = targetID
+	if coll.versioned {
+		// Editors holding the current version must get a conflict rather than overwrite the restored document
+		var existing struct {
+			Version int64 `bson:"version"`
+		}
+		if err := collection.FindOne(ctx, bson.M{"_id": targetID}, options.FindOne().SetProjection(bson.M{"version": 1})).Decode(&existing); err != nil {
+			return false, err
+		}
+		doc["version"] = existing.Version + 1
+	}
+	if !dryRun {
+		if _, err := collection.ReplaceOne(ctx, bson.M{"_id": targetID}, doc); err != nil {
+			return false, err
+		}
+	}
+	collReport.Updated++
+	return true, nil
+}
+
+// importFiles restores verified uploads. Existing files are kept when merging and overwritten when replacing.
+func importFiles(ctx context.Context, uploads []backupUpload, opts models.BackupImportOptions, report *models.BackupImportReport) error {
+	for _, upload := range uploads {
+		f, rel := upload.entry, upload.name
+
+		if _, err := Files.Stat(ctx, rel); err == nil && opts.Mode == models.BackupModeMerge {
+			same, err := fileMatchesZipEntry(ctx, rel, f)
+			if err != nil {
+				return err
+			}
+			if !same {
+				report.Warnings = append(report.Warnings, fmt.Sprintf("kept existing %s which differs from the backup", rel))
+			}
+			report.Files.Skipped++
+			continue
+		} else if err != nil && !errors.Is(err, ErrStoredFileNotFound) {
+			return err
+		}
+
+		if !opts.DryRun {
+			if err := writeZipEntry(ctx, f, rel); err != nil {
+				return fmt.Errorf("restore %s: %v", rel, err)
+			}
+		}
+		report.Files.Written++
+	}
+	return nil
+}
+
+func fileMatchesZipEntry(ctx context.Context, name string, f *zip.File) (bool, error) {
+	info, err := Files.Stat(ctx, name)
+	if err != nil {
+		return false, err
+	}
+	if uint64(info.Size) != f.UncompressedSize64 {
+		return false, nil
+	}
+	existing, err := readStoredFile(ctx, Files, name)
+	if err != nil {
+		return false, err
+	}
+	archived, err := readZipEntry(f, maxBackupFileSize)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(existing, archived), nil
+}
+
+func writeZipEntry(ctx context.Context, f *zip.File, name string) error {
+	data, err := readZipEntry(f, maxBackupFileSize)
+	if err != nil {
+		return err
+	}
+	return Files.Put(ctx, name, data, "")
+}
+
+// readZipEntry reads an archive entry that must not expand beyond limit bytes
+func readZipEntry(f *zip.File, limit int64) ([]byte, error) {
+	if f.UncompressedSize64 > uint64(limit) {
+		return nil, fmt.Errorf("%s is larger than %d bytes", f.Name, limit)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	// The declared size can be forged, so the read itself is limited too
+	data, err := io.ReadAll(io.LimitReader(rc, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%s is larger than %d bytes", f.Name, limit)
+	}
+	return data, nil
+}
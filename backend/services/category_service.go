@@ -2,7 +2,10 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 	"web_AI/config"
 	"web_AI/models"
@@ -12,6 +15,15 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
+var (
+	// ErrCategoryNotFound is returned when a category ID or name does not match an existing category
+	ErrCategoryNotFound = errors.New("category not found")
+	// ErrInvalidCategory wraps validation errors such as duplicate names or parent cycles
+	ErrInvalidCategory = errors.New("invalid category")
+	// ErrCategoryInUse is returned when deleting a category that procedures still reference
+	ErrCategoryInUse = errors.New("category is still in use")
+)
+
 // GetCategories retrieves all categories from database
 func GetCategories() ([]models.Category, error) {
 	collection := config.GetCollection("categories")
@@ -32,39 +44,464 @@ func GetCategories() ([]models.Category, error) {
 	return categories, nil
 }
 
-// CreateCategory creates a new category
+// CreateCategory creates a new category, optionally under a parent category
 func CreateCategory(req models.CreateCategoryRequest) (*models.Category, error) {
 	collection := config.GetCollection("categories")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// Check if category name already exists
-	var existingCategory models.Category
-	err := collection.FindOne(ctx, bson.M{"name": req.Name}).Decode(&existingCategory)
-	if err == nil {
-		return nil, fmt.Errorf("category with name '%s' already exists", req.Name)
-	} else if err != mongo.ErrNoDocuments {
+	name := strings.TrimSpace(req.Name)
+	if err := checkCategoryNameAvailable(ctx, name, primitive.NilObjectID); err != nil {
 		return nil, err
 	}
 
 	category := models.Category{
 		ID:          primitive.NewObjectID(),
-		Name:        req.Name,
+		Name:        name,
 		Description: req.Description,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
+	if req.ParentID != "" {
+		parent, err := findCategoryByID(ctx, req.ParentID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: parent: %w", ErrInvalidCategory, err)
+		}
+		category.ParentID = &parent.ID
+	}
+
+	_, err := collection.InsertOne(ctx, category)
+	if err != nil {
+		return nil, err
+	}
+
+	InvalidateSuggestIndex()
+	return &category, nil
+}
+
+// UpdateCategory renames, re-describes or moves a category; renaming also updates
+// the category name stored on its procedures
+func UpdateCategory(id string, req models.UpdateCategoryRequest) (*models.Category, error) {
+	collection := config.GetCollection("categories")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	category, err := findCategoryByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	oldName := category.Name
+
+	set := bson.M{"updated_at": time.Now()}
+	unset := bson.M{}
+
+	if name := strings.TrimSpace(req.Name); name != "" && name != category.Name {
+		if err := checkCategoryNameAvailable(ctx, name, category.ID); err != nil {
+			return nil, err
+		}
+		set["name"] = name
+		category.Name = name
+	}
+	if req.Description != "" {
+		set["description"] = req.Description
+		category.Description = req.Description
+	}
+	if req.ParentID != nil {
+		if *req.ParentID == "" {
+			unset["parent_id"] = ""
+			category.ParentID = nil
+		} else {
+			parent, err := findCategoryByID(ctx, *req.ParentID)
+			if err != nil {
+				return nil, fmt.Errorf("%w: parent: %w", ErrInvalidCategory, err)
+			}
+			if err := checkCategoryParent(ctx, category.ID, parent); err != nil {
+				return nil, err
+			}
+			set["parent_id"] = parent.ID
+			category.ParentID = &parent.ID
+		}
+	}
+
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	if _, err := collection.UpdateOne(ctx, bson.M{"_id": category.ID}, update); err != nil {
+		return nil, err
+	}
+
+	if category.Name != oldName {
+		procedures := config.GetCollection("procedures")
+		filter := categoryReferenceFilter(category.ID, oldName)
+		_, err := procedures.UpdateMany(ctx, filter, bson.M{
+			"$set": bson.M{
+				"category":    category.Name,
+				"category_id": category.ID,
+			},
+			"$inc": bson.M{"version": 1},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	InvalidateSuggestIndex()
+	return category, nil
+}
+
+// DeleteCategory deletes a category. Procedures still using it are moved to reassignTo;
+// without reassignTo the delete is refused while procedures reference the category.
+// Sub-categories move up to the deleted category's parent.
+func DeleteCategory(id string, reassignTo string) (*models.DeleteCategoryResponse, error) {
+	collection := config.GetCollection("categories")
+	procedures := config.GetCollection("procedures")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	category, err := findCategoryByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.DeleteCategoryResponse{}
+	filter := categoryReferenceFilter(category.ID, category.Name)
+	count, err := procedures.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if count > 0 {
+		if reassignTo == "" {
+			result.BlockingProcedures = count
+			return result, fmt.Errorf("%w: %d procedures (including any in the trash) still use category '%s'", ErrCategoryInUse, count, category.Name)
+		}
+		target, err := findCategoryByID(ctx, reassignTo)
+		if err != nil {
+			return nil, fmt.Errorf("%w: reassign_to: %w", ErrInvalidCategory, err)
+		}
+		if target.ID == category.ID {
+			return nil, fmt.Errorf("%w: cannot reassign procedures to the category being deleted", ErrInvalidCategory)
+		}
+
+		updated, err := procedures.UpdateMany(ctx, filter, bson.M{
+			"$set": bson.M{
+				"category":    target.Name,
+				"category_id": target.ID,
+				"updated_at":  time.Now(),
+			},
+			"$inc": bson.M{"version": 1},
+		})
+		if err != nil {
+			return nil, err
+		}
+		result.ReassignedProcedures = updated.ModifiedCount
+		result.ReassignedTo = target.ID.Hex()
+	}
+
+	childUpdate := bson.M{"$unset": bson.M{"parent_id": ""}}
+	if category.ParentID != nil {
+		childUpdate = bson.M{"$set": bson.M{"parent_id": *category.ParentID}}
+	}
+	moved, err := collection.UpdateMany(ctx, bson.M{"parent_id": category.ID}, childUpdate)
+	if err != nil {
+		return nil, err
+	}
+	result.MovedChildren = moved.ModifiedCount
+
+	if _, err := collection.DeleteOne(ctx, bson.M{"_id": category.ID}); err != nil {
+		return nil, err
+	}
+
+	InvalidateSuggestIndex()
+	return result, nil
+}
+
+// GetCategoryTree returns categories nested under their parents, with the number of
+// published procedures the viewer may see directly in each category
+func GetCategoryTree(viewer models.Viewer) ([]*models.CategoryNode, error) {
+	categories, err := GetCategories()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pipeline := []bson.M{
+		{"$match": visibleTo(bson.M{"category_id": bson.M{"$exists": true}}, viewer)},
+		{"$group": bson.M{"_id": "$category_id", "count": bson.M{"$sum": 1}}},
+	}
+	cursor, err := config.GetCollection("procedures").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var counts []struct {
+		ID    primitive.ObjectID `bson:"_id"`
+		Count int64              `bson:"count"`
+	}
+	if err = cursor.All(ctx, &counts); err != nil {
+		return nil, err
+	}
+	countByID := make(map[primitive.ObjectID]int64, len(counts))
+	for _, c := range counts {
+		countByID[c.ID] = c.Count
+	}
+
+	nodes := make(map[primitive.ObjectID]*models.CategoryNode, len(categories))
+	for _, category := range categories {
+		nodes[category.ID] = &models.CategoryNode{
+			Category:       category,
+			ProcedureCount: countByID[category.ID],
+			Children:       []*models.CategoryNode{},
+		}
+	}
 
-	_, err = collection.InsertOne(ctx, category)
+	roots := []*models.CategoryNode{}
+	for _, category := range categories {
+		node := nodes[category.ID]
+		// Categories whose parent no longer exists are shown at the top level
+		if category.ParentID != nil {
+			if parent, ok := nodes[*category.ParentID]; ok {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+
+	sortCategoryNodes(roots)
+	return roots, nil
+}
+
+func sortCategoryNodes(nodes []*models.CategoryNode) {
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+	for _, node := range nodes {
+		sortCategoryNodes(node.Children)
+	}
+}
+
+// resolveProcedureCategory checks the procedure's category exists and fills in both its ID and name.
+// An ID that no longer exists falls back to the name, so restoring old revisions keeps working after a rename.
+func resolveProcedureCategory(ctx context.Context, procedure *models.Procedure) error {
+	if !procedure.CategoryID.IsZero() {
+		category, err := findCategoryByID(ctx, procedure.CategoryID.Hex())
+		if err == nil {
+			procedure.CategoryID = category.ID
+			procedure.Category = category.Name
+			return nil
+		} else if !errors.Is(err, ErrCategoryNotFound) || procedure.Category == "" {
+			return err
+		}
+	}
+
+	category, err := findCategoryByName(ctx, procedure.Category)
 	if err != nil {
+		return err
+	}
+	procedure.CategoryID = category.ID
+	procedure.Category = category.Name
+	return nil
+}
+
+func findCategoryByID(ctx context.Context, id string) (*models.Category, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid category ID", ErrInvalidCategory)
+	}
+
+	var category models.Category
+	err = config.GetCollection("categories").FindOne(ctx, bson.M{"_id": objID}).Decode(&category)
+	if err == mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("%w: %s", ErrCategoryNotFound, id)
+	} else if err != nil {
 		return nil, err
 	}
 
 	return &category, nil
 }
 
-// GetAdminStats returns statistics for admin dashboard
-func GetAdminStats() (*models.AdminStatsResponse, error) {
+func findCategoryByName(ctx context.Context, name string) (*models.Category, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: category is required", ErrInvalidCategory)
+	}
+
+	var category models.Category
+	err := config.GetCollection("categories").FindOne(ctx, bson.M{"name": name}).Decode(&category)
+	if err == mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("%w: '%s'", ErrCategoryNotFound, name)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &category, nil
+}
+
+// checkCategoryNameAvailable makes sure no other category already uses the name
+func checkCategoryNameAvailable(ctx context.Context, name string, selfID primitive.ObjectID) error {
+	if name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidCategory)
+	}
+
+	var existing models.Category
+	err := config.GetCollection("categories").FindOne(ctx, bson.M{"name": name}).Decode(&existing)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if existing.ID == selfID {
+		return nil
+	}
+	return fmt.Errorf("%w: category with name '%s' already exists", ErrInvalidCategory, name)
+}
+
+// checkCategoryParent rejects parents that would put the category inside its own subtree
+func checkCategoryParent(ctx context.Context, categoryID primitive.ObjectID, parent *models.Category) error {
+	categories, err := GetCategories()
+	if err != nil {
+		return err
+	}
+	parents := make(map[primitive.ObjectID]*primitive.ObjectID, len(categories))
+	for _, c := range categories {
+		parents[c.ID] = c.ParentID
+	}
+
+	current := &parent.ID
+	for depth := 0; current != nil && depth <= len(categories); depth++ {
+		if *current == categoryID {
+			return fmt.Errorf("%w: a category cannot be moved under itself or one of its sub-categories", ErrInvalidCategory)
+		}
+		current = parents[*current]
+	}
+	return nil
+}
+
+// categoryReferenceFilter matches procedures in a category, including legacy ones that only store its name
+func categoryReferenceFilter(id primitive.ObjectID, name string) bson.M {
+	return bson.M{"$or": []bson.M{
+		{"category_id": id},
+		{"category_id": bson.M{"$exists": false}, "category": name},
+	}}
+}
+
+// categoryFilter builds the procedure filter for a category given by ID or name,
+// including procedures in its sub-categories
+func categoryFilter(ctx context.Context, category string) (bson.M, error) {
+	var found *models.Category
+	var err error
+	if primitive.IsValidObjectID(category) {
+		found, err = findCategoryByID(ctx, category)
+	} else {
+		found, err = findCategoryByName(ctx, category)
+	}
+	if errors.Is(err, ErrCategoryNotFound) {
+		// Unknown categories match nothing except legacy procedures with that free-text name
+		return bson.M{"category": category}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	categories, err := GetCategories()
+	if err != nil {
+		return nil, err
+	}
+	children := make(map[primitive.ObjectID][]models.Category)
+	for _, c := range categories {
+		if c.ParentID != nil {
+			children[*c.ParentID] = append(children[*c.ParentID], c)
+		}
+	}
+
+	ids := bson.A{}
+	names := bson.A{}
+	seen := map[primitive.ObjectID]bool{}
+	queue := []models.Category{*found}
+	for len(queue) > 0 {
+		c := queue[0]
+		queue = queue[1:]
+		if seen[c.ID] {
+			continue
+		}
+		seen[c.ID] = true
+		ids = append(ids, c.ID)
+		names = append(names, c.Name)
+		queue = append(queue, children[c.ID]...)
+	}
+
+	return bson.M{"$or": []bson.M{
+		{"category_id": bson.M{"$in": ids}},
+		{"category_id": bson.M{"$exists": false}, "category": bson.M{"$in": names}},
+	}}, nil
+}
+
+// MigrateProcedureCategories links procedures that only store a category name to the
+// matching category document, creating categories that do not exist yet
+func MigrateProcedureCategories() (*models.CategoryMigrationReport, error) {
+	procedures := config.GetCollection("procedures")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	report := &models.CategoryMigrationReport{CreatedCategories: []string{}}
+	unlinked := bson.M{"category_id": bson.M{"$exists": false}}
+
+	names, err := procedures.Distinct(ctx, "category", unlinked)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, value := range names {
+		name, _ := value.(string)
+		filter := bson.M{"category_id": bson.M{"$exists": false}, "category": value}
+		if strings.TrimSpace(name) == "" {
+			count, err := procedures.CountDocuments(ctx, filter)
+			if err != nil {
+				return nil, err
+			}
+			report.Unresolved += count
+			continue
+		}
+
+		category, err := findCategoryByName(ctx, name)
+		if errors.Is(err, ErrCategoryNotFound) {
+			category = &models.Category{
+				ID:          primitive.NewObjectID(),
+				Name:        strings.TrimSpace(name),
+				Description: "Tạo tự động khi chuyển đổi dữ liệu",
+				CreatedAt:   time.Now(),
+				UpdatedAt:   time.Now(),
+			}
+			if _, err := config.GetCollection("categories").InsertOne(ctx, category); err != nil {
+				return nil, err
+			}
+			report.CreatedCategories = append(report.CreatedCategories, category.Name)
+		} else if err != nil {
+			return nil, err
+		}
+
+		result, err := procedures.UpdateMany(ctx, filter, bson.M{
+			"$set": bson.M{
+				"category":    category.Name,
+				"category_id": category.ID,
+			},
+			"$inc": bson.M{"version": 1},
+		})
+		if err != nil {
+			return nil, err
+		}
+		report.LinkedProcedures += result.ModifiedCount
+	}
+
+	if report.LinkedProcedures > 0 || len(report.CreatedCategories) > 0 {
+		InvalidateSuggestIndex()
+	}
+	return report, nil
+}
+
+// GetAdminStats returns statistics for admin dashboard: totals, daily and weekly activity over
+// the last days days, and the top procedures by views in that period
+func GetAdminStats(days int, top int) (*models.AdminStatsResponse, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
@@ -72,7 +509,7 @@ func GetAdminStats() (*models.AdminStatsResponse, error) {
 	categoriesCollection := config.GetCollection("categories")
 
 	// Count procedures
-	proceduresCount, err := proceduresCollection.CountDocuments(ctx, bson.M{})
+	proceduresCount, err := proceduresCollection.CountDocuments(ctx, notDeleted(bson.M{}))
 	if err != nil {
 		return nil, err
 	}
@@ -83,13 +520,31 @@ func GetAdminStats() (*models.AdminStatsResponse, error) {
 		return nil, err
 	}
 
-	// For visits, we'll use a simple counter (you could implement a more sophisticated tracking)
-	totalVisits := int64(0) // This could be stored in a separate collection
+	// Activity comes from the daily event rollups
+	totals, err := eventTotals(ctx)
+	if err != nil {
+		return nil, err
+	}
+	daily, weekly, err := eventSeries(ctx, days)
+	if err != nil {
+		return nil, err
+	}
+	topProcedures, err := topViewedProcedures(ctx, days, top)
+	if err != nil {
+		return nil, err
+	}
 
 	stats := &models.AdminStatsResponse{
 		TotalProcedures: proceduresCount,
 		TotalCategories: categoriesCount,
-		TotalVisits:     totalVisits,
+		TotalVisits:     totals[models.EventProcedureView],
+		TotalSearches:   totals[models.EventSearch],
+		TotalChats:      totals[models.EventChat],
+		TotalLogins:     totals[models.EventLogin],
+		Days:            days,
+		Daily:           daily,
+		Weekly:          weekly,
+		TopProcedures:   topProcedures,
 	}
 
 	return stats, nil
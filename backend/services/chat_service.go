@@ -10,6 +10,7 @@ import (
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
@@ -43,7 +44,7 @@ func SaveChatConversation(userIDStr, conversationID, userMessage, aiResponse str
 	if conversationID != "" {
 		convObjID, err := primitive.ObjectIDFromHex(conversationID)
 		if err == nil {
-			filter := bson.M{"_id": convObjID, "user_id": userObjID}
+			filter := bson.M{"_id": convObjID, "user_id": userObjID, "deleted_at": nil}
 			update := bson.M{
 				"$push": bson.M{"messages": bson.M{"$each": []models.ChatMessage{userMsg, aiMsg}}},
 				"$set":  bson.M{"updated_at": time.Now()},
@@ -88,7 +89,7 @@ func GetUserChatHistory(userIDStr string) ([]models.ChatConversation, error) {
 		return nil, fmt.Errorf("invalid user ID: %v", err)
 	}
 
-	filter := bson.M{"user_id": userObjID}
+	filter := bson.M{"user_id": userObjID, "deleted_at": nil}
 	opts := options.Find().SetSort(bson.D{bson.E{Key: "updated_at", Value: -1}}).SetLimit(50)
 
 	cursor, err := collection.Find(ctx, filter, opts)
@@ -121,7 +122,7 @@ func GetChatConversation(userIDStr, conversationID string) (*models.ChatConversa
 		return nil, fmt.Errorf("invalid conversation ID: %v", err)
 	}
 
-	filter := bson.M{"_id": convObjID, "user_id": userObjID}
+	filter := bson.M{"_id": convObjID, "user_id": userObjID, "deleted_at": nil}
 	var conversation models.ChatConversation
 	err = collection.FindOne(ctx, filter).Decode(&conversation)
 	if err != nil {
@@ -131,7 +132,7 @@ func GetChatConversation(userIDStr, conversationID string) (*models.ChatConversa
 	return &conversation, nil
 }
 
-// DeleteChatConversation deletes a conversation
+// DeleteChatConversation moves a conversation to the user's trash
 func DeleteChatConversation(userIDStr, conversationID string) error {
 	collection := config.GetCollection("chat_conversations")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
@@ -147,9 +148,17 @@ func DeleteChatConversation(userIDStr, conversationID string) error {
 		return fmt.Errorf("invalid conversation ID: %v", err)
 	}
 
-	filter := bson.M{"_id": convObjID, "user_id": userObjID}
-	_, err = collection.DeleteOne(ctx, filter)
-	return err
+	filter := bson.M{"_id": convObjID, "user_id": userObjID, "deleted_at": nil}
+	var conversation models.ChatConversation
+	err = collection.FindOneAndUpdate(ctx, filter, bson.M{"$set": bson.M{"deleted_at": time.Now()}}).Decode(&conversation)
+	if err == mongo.ErrNoDocuments {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	// The legacy history endpoint would still show the messages otherwise
+	return deleteLegacyConversationCopy(ctx, &conversation)
 }
 
 // generateConversationTitle creates a title from the first message
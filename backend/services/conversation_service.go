@@ -71,3 +71,31 @@ func GetConversations(userID primitive.ObjectID) ([]models.Conversation, error)
 
 	return conversations, nil
 }
+
+// deleteLegacyConversationCopy removes the messages of a chat conversation from the legacy
+// conversations collection, which every answer is also saved to. Legacy documents collect an hour
+// of messages and are not linked to a chat conversation, so they are matched by user, time and text.
+func deleteLegacyConversationCopy(ctx context.Context, conversation *models.ChatConversation) error {
+	if len(conversation.Messages) == 0 {
+		return nil
+	}
+	contents := make([]string, 0, len(conversation.Messages))
+	for _, message := range conversation.Messages {
+		contents = append(contents, message.Content)
+	}
+
+	collection := config.DB.Collection("conversations")
+	filter := bson.M{
+		"user_id": conversation.UserID,
+		"created_at": bson.M{
+			"$gte": conversation.CreatedAt.Add(-1 * time.Hour),
+			"$lte": conversation.UpdatedAt,
+		},
+	}
+	update := bson.M{"$pull": bson.M{"messages": bson.M{"content": bson.M{"$in": contents}}}}
+	if _, err := collection.UpdateMany(ctx, filter, update); err != nil {
+		return err
+	}
+	_, err := collection.DeleteMany(ctx, bson.M{"user_id": conversation.UserID, "messages": bson.M{"$size": 0}})
+	return err
+}
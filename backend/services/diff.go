@@ -0,0 +1,137 @@
+package services
+
+import (
+	"strings"
+	"web_AI/models"
+)
+
+// Diff operations
+const (
+	diffEqual  = "equal"
+	diffInsert = "insert"
+	diffDelete = "delete"
+)
+
+// splitLines splits text into lines, treating Windows line endings like Unix ones
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+}
+
+// maxDiffEdits bounds the search in diffLines, whose time and memory grow with the square of the
+// number of edits. Texts that differ more than this show the changed block as removed and re-added.
+const maxDiffEdits = 2000
+
+// diffLines computes a minimal line diff between a and b using Myers' algorithm
+func diffLines(a, b []string) []models.DiffLine {
+	// Unchanged lines at the start and end are common and need no search
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]models.DiffLine, 0, len(a)+len(b)-prefix-suffix)
+	for i := 0; i < prefix; i++ {
+		lines = append(lines, models.DiffLine{Op: diffEqual, Text: a[i], OldLine: i + 1, NewLine: i + 1})
+	}
+	lines = append(lines, diffMiddle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix], prefix)...)
+	for i := suffix; i > 0; i-- {
+		lines = append(lines, models.DiffLine{Op: diffEqual, Text: a[len(a)-i], OldLine: len(a) - i + 1, NewLine: len(b) - i + 1})
+	}
+	return lines
+}
+
+// diffMiddle runs the Myers search on the lines between the common prefix and suffix;
+// skip is the length of the prefix, added to line numbers
+func diffMiddle(a, b []string, skip int) []models.DiffLine {
+	n, m := len(a), len(b)
+	max := n + m
+	if max == 0 {
+		return nil
+	}
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	// trace[d] keeps the part of v that round d may read, diagonals -d-1..d+1
+	var trace [][]int
+
+search:
+	for d := 0; d <= max; d++ {
+		if d > maxDiffEdits {
+			return replaceLines(a, b, skip)
+		}
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// Walk the trace backwards to recover the edit script
+	var reversed []models.DiffLine
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		window := trace[d]
+		at := func(k int) int { return window[k+d+1] }
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			reversed = append(reversed, models.DiffLine{Op: diffEqual, Text: a[x-1], OldLine: skip + x, NewLine: skip + y})
+			x--
+			y--
+		}
+		if d > 0 {
+			if x == prevX {
+				reversed = append(reversed, models.DiffLine{Op: diffInsert, Text: b[y-1], NewLine: skip + y})
+			} else {
+				reversed = append(reversed, models.DiffLine{Op: diffDelete, Text: a[x-1], OldLine: skip + x})
+			}
+		}
+		x, y = prevX, prevY
+	}
+
+	lines := make([]models.DiffLine, 0, len(reversed))
+	for i := len(reversed) - 1; i >= 0; i-- {
+		lines = append(lines, reversed[i])
+	}
+	return lines
+}
+
+// replaceLines shows b as a replacement of all of a
+func replaceLines(a, b []string, skip int) []models.DiffLine {
+	lines := make([]models.DiffLine, 0, len(a)+len(b))
+	for i, text := range a {
+		lines = append(lines, models.DiffLine{Op: diffDelete, Text: text, OldLine: skip + i + 1})
+	}
+	for i, text := range b {
+		lines = append(lines, models.DiffLine{Op: diffInsert, Text: text, NewLine: skip + i + 1})
+	}
+	return lines
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// formatDiff writes each line as op:old:new:text for compact comparisons
+func formatDiff(a, b string) []string {
+	var out []string
+	for _, line := range diffLines(splitLines(a), splitLines(b)) {
+		out = append(out, fmt.Sprintf("%s:%d:%d:%s", line.Op, line.OldLine, line.NewLine, line.Text))
+	}
+	return out
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []string
+	}{
+		{name: "both empty", want: nil},
+		{name: "equal", a: "a\nb", b: "a\nb", want: []string{"equal:1:1:a", "equal:2:2:b"}},
+		{name: "added to empty", b: "a\nb", want: []string{"insert:0:1:a", "insert:0:2:b"}},
+		{name: "all removed", a: "a\nb", want: []string{"delete:1:0:a", "delete:2:0:b"}},
+		{name: "insert in the middle", a: "a\nc", b: "a\nb\nc", want: []string{"equal:1:1:a", "insert:0:2:b", "equal:2:3:c"}},
+		{name: "delete in the middle", a: "a\nb\nc", b: "a\nc", want: []string{"equal:1:1:a", "delete:2:0:b", "equal:3:2:c"}},
+		{name: "changed line", a: "a\nb\nc", b: "a\nx\nc", want: []string{"equal:1:1:a", "delete:2:0:b", "insert:0:2:x", "equal:3:3:c"}},
+		{
+			name: "minimal edit keeps the common lines",
+			a:    "a\nb\nc\na\nb\nb\na",
+			b:    "c\nb\na\nb\na\nc",
+			want: []string{
+				"delete:1:0:a", "delete:2:0:b", "equal:3:1:c", "insert:0:2:b", "equal:4:3:a",
+				"equal:5:4:b", "delete:6:0:b", "equal:7:5:a", "insert:0:6:c",
+			},
+		},
+		{name: "windows line endings", a: "a\r\nb", b: "a\nb", want: []string{"equal:1:1:a", "equal:2:2:b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := formatDiff(tt.a, tt.b)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("diff =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestDiffLinesRebuildsBothTexts(t *testing.T) {
+	many := func(prefix string, n int) []string {
+		lines := make([]string, n)
+		for i := range lines {
+			lines[i] = fmt.Sprintf("%s%d", prefix, i)
+		}
+		return lines
+	}
+	tests := []struct {
+		name string
+		a, b []string
+	}{
+		{name: "interleaved", a: []string{"1", "2", "3", "4", "5", "6"}, b: []string{"0", "2", "3", "x", "5", "7", "6"}},
+		{name: "repeated lines", a: []string{"x", "x", "y", "x"}, b: []string{"y", "x", "x", "x", "y"}},
+		// More edits than maxDiffEdits falls back to replacing the changed block
+		{name: "over the edit limit", a: append(append([]string{"top"}, many("a", maxDiffEdits)...), "end"), b: append(append([]string{"top"}, many("b", maxDiffEdits)...), "end")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var oldText, newText []string
+			for _, line := range diffLines(tt.a, tt.b) {
+				if line.Op != diffInsert {
+					if line.OldLine != len(oldText)+1 {
+						t.Fatalf("old line %d, want %d", line.OldLine, len(oldText)+1)
+					}
+					oldText = append(oldText, line.Text)
+				}
+				if line.Op != diffDelete {
+					if line.NewLine != len(newText)+1 {
+						t.Fatalf("new line %d, want %d", line.NewLine, len(newText)+1)
+					}
+					newText = append(newText, line.Text)
+				}
+			}
+			if strings.Join(oldText, "\n") != strings.Join(tt.a, "\n") || strings.Join(newText, "\n") != strings.Join(tt.b, "\n") {
+				t.Errorf("diff does not rebuild the texts")
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+	"web_AI/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrNoProcedureFile  = errors.New("procedure has no uploaded file")
+	ErrInvalidFileLink  = errors.New("download link is invalid or has expired")
+	fileLinkSecretOnce  sync.Once
+	fileLinkSecretValue []byte
+)
+
+// ProcedureFileURL is the path the original file of a procedure is downloaded from
+func ProcedureFileURL(id primitive.ObjectID) string {
+	return "/api/procedures/" + id.Hex() + "/file"
+}
+
+// ContentTypeForFile returns the document type of a stored file from its extension
+func ContentTypeForFile(name string) string {
+	ext := path.Ext(name)
+	for contentType, known := range fileExtensions {
+		if known == ext {
+			return contentType
+		}
+	}
+	return "application/octet-stream"
+}
+
+// OpenProcedureFile opens the original file of a procedure; the caller closes it
+func OpenProcedureFile(procedure *models.Procedure) (io.ReadSeekCloser, *FileInfo, error) {
+	if procedure.FileKey == "" {
+		return nil, nil, ErrNoProcedureFile
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	rc, info, err := Files.Open(ctx, procedure.FileKey)
+	if errors.Is(err, ErrStoredFileNotFound) {
+		return nil, nil, fmt.Errorf("%w: the stored file is missing", ErrNoProcedureFile)
+	}
+	return rc, info, err
+}
+
+// fileLinkTTL is how long signed download links work (FILE_LINK_TTL, default 5 minutes, at most a day)
+func fileLinkTTL() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("FILE_LINK_TTL")); err == nil && d > 0 && d <= 24*time.Hour {
+		return d
+	}
+	return 5 * time.Minute
+}
+
+// fileLinkSecret signs download links with FILE_LINK_SECRET, or with a key derived from JWT_SECRET so
+// the JWT key itself is never used for anything else. Without either a random key is used, so links
+// stop working when the server restarts.
+func fileLinkSecret() []byte {
+	fileLinkSecretOnce.Do(func() {
+		if secret := os.Getenv("FILE_LINK_SECRET"); secret != "" {
+			fileLinkSecretValue = []byte(secret)
+			return
+		}
+		if secret := os.Getenv("JWT_SECRET"); secret != "" {
+			mac := hmac.New(sha256.New, []byte(secret))
+			mac.Write([]byte("file-link"))
+			fileLinkSecretValue = mac.Sum(nil)
+			return
+		}
+		fileLinkSecretValue = make([]byte, 32)
+		if _, err := rand.Read(fileLinkSecretValue); err != nil {
+			panic(err)
+		}
+	})
+	return fileLinkSecretValue
+}
+
+// fileLinkSignature covers the procedure, the file, its status and who may see it, and the expiry,
+// so a link stops working when the file is replaced, the procedure is unpublished or access is narrowed
+func fileLinkSignature(procedure *models.Procedure, expires int64) string {
+	mac := hmac.New(sha256.New, fileLinkSecret())
+	access := procedure.ProcedureAccess
+	fmt.Fprintf(mac, "%s:%s:%s:%s:%q:%q:%d", procedure.ID.Hex(), procedure.FileKey, procedure.Status,
+		access.Visibility, access.VisibleDepartments, access.VisibleRoles, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// CreateFileLink returns a download link for the original file of a procedure that works without
+// authentication until it expires
+func CreateFileLink(procedure *models.Procedure) (*models.FileLinkResponse, error) {
+	if procedure.FileKey == "" {
+		return nil, ErrNoProcedureFile
+	}
+	expiresAt := time.Now().Add(fileLinkTTL()).Truncate(time.Second)
+	expires := expiresAt.Unix()
+	query := url.Values{
+		"expires":   {strconv.FormatInt(expires, 10)},
+		"signature": {fileLinkSignature(procedure, expires)},
+	}
+	return &models.FileLinkResponse{
+		URL:       ProcedureFileURL(procedure.ID) + "?" + query.Encode(),
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// VerifyFileLink checks the expires and signature parameters of a signed download link
+func VerifyFileLink(procedure *models.Procedure, expiresParam string, signature string) error {
+	expires, err := strconv.ParseInt(expiresParam, 10, 64)
+	if err != nil || time.Now().Unix() > expires || procedure.FileKey == "" {
+		return ErrInvalidFileLink
+	}
+	if !hmac.Equal([]byte(fileLinkSignature(procedure, expires)), []byte(signature)) {
+		return ErrInvalidFileLink
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"web_AI/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestVerifyFileLink(t *testing.T) {
+	published := func() *models.Procedure {
+		return &models.Procedure{
+			ID:              primitive.NewObjectID(),
+			FileKey:         "abc.pdf",
+			Status:          models.ProcedureStatusPublished,
+			ProcedureAccess: models.ProcedureAccess{Visibility: "department", VisibleDepartments: []string{"HR"}},
+		}
+	}
+	procedure := published()
+	link, err := CreateFileLink(procedure)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(link.URL, ProcedureFileURL(procedure.ID)+"?") {
+		t.Fatalf("url = %s", link.URL)
+	}
+	query, err := url.ParseQuery(link.URL[strings.Index(link.URL, "?")+1:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	expires, signature := query.Get("expires"), query.Get("signature")
+	past := strconv.FormatInt(time.Now().Add(-time.Minute).Unix(), 10)
+
+	tests := []struct {
+		name      string
+		change    func(p *models.Procedure)
+		expires   string
+		signature string
+		valid     bool
+	}{
+		{name: "valid", valid: true},
+		{name: "expired", expires: past, signature: fileLinkSignature(procedure, time.Now().Add(-time.Minute).Unix())},
+		{name: "later expiry", expires: strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10)},
+		{name: "bad expiry", expires: "soon"},
+		{name: "other signature", signature: strings.Repeat("0", 64)},
+		{name: "file replaced", change: func(p *models.Procedure) { p.FileKey = "def.pdf" }},
+		{name: "no file", change: func(p *models.Procedure) { p.FileKey = "" }},
+		{name: "unpublished", change: func(p *models.Procedure) { p.Status = models.ProcedureStatusDraft }},
+		{name: "access narrowed", change: func(p *models.Procedure) { p.VisibleDepartments = []string{"HR", "Legal"} }},
+		{name: "other procedure", change: func(p *models.Procedure) { p.ID = primitive.NewObjectID() }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := published()
+			p.ID = procedure.ID
+			if tt.change != nil {
+				tt.change(p)
+			}
+			e, s := expires, signature
+			if tt.expires != "" {
+				e = tt.expires
+			}
+			if tt.signature != "" {
+				s = tt.signature
+			}
+			err := VerifyFileLink(p, e, s)
+			if tt.valid && err != nil {
+				t.Errorf("VerifyFileLink = %v, want nil", err)
+			}
+			if !tt.valid && !errors.Is(err, ErrInvalidFileLink) {
+				t.Errorf("VerifyFileLink = %v, want ErrInvalidFileLink", err)
+			}
+		})
+	}
+
+	if _, err := CreateFileLink(&models.Procedure{ID: primitive.NewObjectID()}); !errors.Is(err, ErrNoProcedureFile) {
+		t.Errorf("CreateFileLink without a file = %v, want ErrNoProcedureFile", err)
+	}
+}
+
+func TestFileLinkSecret(t *testing.T) {
+	derived := hmac.New(sha256.New, []byte("jwt"))
+	derived.Write([]byte("file-link"))
+
+	tests := []struct {
+		name       string
+		fileSecret string
+		jwtSecret  string
+		want       string // empty for a random key
+	}{
+		{name: "own secret", fileSecret: "links", jwtSecret: "jwt", want: "links"},
+		{name: "derived from the JWT secret", jwtSecret: "jwt", want: string(derived.Sum(nil))},
+		{name: "random"},
+	}
+	t.Cleanup(func() { fileLinkSecretOnce, fileLinkSecretValue = sync.Once{}, nil })
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("FILE_LINK_SECRET", tt.fileSecret)
+			t.Setenv("JWT_SECRET", tt.jwtSecret)
+			fileLinkSecretOnce, fileLinkSecretValue = sync.Once{}, nil
+
+			got := string(fileLinkSecret())
+			if tt.want != "" && got != tt.want {
+				t.Errorf("fileLinkSecret = %x, want %x", got, tt.want)
+			}
+			if tt.want == "" && (len(got) != 32 || got == string(derived.Sum(nil))) {
+				t.Errorf("fileLinkSecret = %x, want 32 random bytes", got)
+			}
+		})
+	}
+}
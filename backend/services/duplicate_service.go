@@ -0,0 +1,297 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"web_AI/config"
+	"web_AI/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrDuplicateProcedure = errors.New("a procedure with nearly the same content already exists")
+
+const (
+	shingleSize       = 7   // characters per shingle
+	minhashSize       = 128 // hash functions per signature; the estimate is within about ±0.09
+	maxDuplicateMatch = 5   // matches returned when saving a procedure
+)
+
+// duplicateThreshold reads a similarity threshold between 0 and 1 from the environment
+func duplicateThreshold(name string, fallback float64) float64 {
+	if value, err := strconv.ParseFloat(os.Getenv(name), 64); err == nil && value > 0 && value <= 1 {
+		return value
+	}
+	return fallback
+}
+
+// DuplicateWarnThreshold is the similarity from which saving a procedure lists the existing one as a possible duplicate
+func DuplicateWarnThreshold() float64 {
+	return duplicateThreshold("DUPLICATE_WARN_THRESHOLD", 0.5)
+}
+
+// duplicateBlockThreshold is the similarity from which saving is refused unless forced
+func duplicateBlockThreshold() float64 {
+	return duplicateThreshold("DUPLICATE_BLOCK_THRESHOLD", 0.85)
+}
+
+// minhashSeeds turn one shingle hash into minhashSize independent ones
+var minhashSeeds = func() [minhashSize]uint64 {
+	var seeds [minhashSize]uint64
+	for i := range seeds {
+		seeds[i] = mix64(uint64(i) + 1)
+	}
+	return seeds
+}()
+
+// mix64 is the splitmix64 finalizer, a fast well-distributed 64-bit mix
+func mix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+type signature []uint64
+
+// duplicateText is the text compared between procedures: the content, or the rendered steps when there is none.
+// Only lowercased letters and digits are kept, because PDF extraction often drops the spaces between words;
+// this way a PDF and a DOCX of the same document compare equal.
+func duplicateText(procedure *models.Procedure) []rune {
+	text := procedure.Content
+	if strings.TrimSpace(text) == "" {
+		text = RenderStepsContent(procedure.Steps)
+	}
+	var runes []rune
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			runes = append(runes, r)
+		}
+	}
+	return runes
+}
+
+// minhashSignature returns the MinHash signature of the character shingles of a procedure, or nil when it has no text
+func minhashSignature(procedure *models.Procedure) signature {
+	return textSignature(duplicateText(procedure))
+}
+
+// textSignature returns the MinHash signature of the character shingles of a duplicateText
+func textSignature(text []rune) signature {
+	if len(text) == 0 {
+		return nil
+	}
+
+	sig := make(signature, minhashSize)
+	for i := range sig {
+		sig[i] = math.MaxUint64
+	}
+	add := func(shingle []rune) {
+		h := fnv.New64a()
+		h.Write([]byte(string(shingle)))
+		value := h.Sum64()
+		for i, seed := range minhashSeeds {
+			if v := mix64(value ^ seed); v < sig[i] {
+				sig[i] = v
+			}
+		}
+	}
+
+	if len(text) < shingleSize {
+		add(text)
+	}
+	for i := 0; i+shingleSize <= len(text); i++ {
+		add(text[i : i+shingleSize])
+	}
+	return sig
+}
+
+// similarity estimates the Jaccard similarity of the shingle sets two signatures were made from
+func (sig signature) similarity(other signature) float64 {
+	if len(sig) == 0 || len(sig) != len(other) {
+		return 0
+	}
+	same := 0
+	for i := range sig {
+		if sig[i] == other[i] {
+			same++
+		}
+	}
+	return math.Round(float64(same)/float64(len(sig))*100) / 100
+}
+
+type signedProcedure struct {
+	match     models.DuplicateMatch
+	signature signature
+}
+
+// storedSignature converts a signature for storage, since BSON has no unsigned integers
+func storedSignature(sig signature) []int64 {
+	if sig == nil {
+		return nil
+	}
+	stored := make([]int64, len(sig))
+	for i, v := range sig {
+		stored[i] = int64(v)
+	}
+	return stored
+}
+
+func loadedSignature(stored []int64) signature {
+	if len(stored) == 0 {
+		return nil
+	}
+	sig := make(signature, len(stored))
+	for i, v := range stored {
+		sig[i] = uint64(v)
+	}
+	return sig
+}
+
+// signedProcedures returns the stored signatures of all procedures that are not in the trash.
+// Procedures saved before signatures were stored get theirs computed and stored once.
+func signedProcedures() ([]signedProcedure, error) {
+	collection := config.GetCollection("procedures")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetProjection(bson.M{"title": 1, "status": 1, "duplicate_signature": 1})
+	cursor, err := collection.Find(ctx, notDeleted(bson.M{}), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var signed []signedProcedure
+	var unsigned []primitive.ObjectID
+	for cursor.Next(ctx) {
+		var p models.Procedure
+		if err := cursor.Decode(&p); err != nil {
+			return nil, err
+		}
+		if _, err := cursor.Current.LookupErr("duplicate_signature"); err != nil {
+			unsigned = append(unsigned, p.ID)
+			continue
+		}
+		if sig := loadedSignature(p.DuplicateSignature); sig != nil {
+			signed = append(signed, signedProcedure{
+				match:     models.DuplicateMatch{ProcedureID: p.ID, Title: p.Title, Status: p.Status},
+				signature: sig,
+			})
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(unsigned) > 0 {
+		backfilled, err := signLegacyProcedures(ctx, unsigned)
+		if err != nil {
+			return nil, err
+		}
+		signed = append(signed, backfilled...)
+	}
+	return signed, nil
+}
+
+// signLegacyProcedures computes and stores the signatures of procedures that have none yet
+func signLegacyProcedures(ctx context.Context, ids []primitive.ObjectID) ([]signedProcedure, error) {
+	collection := config.GetCollection("procedures")
+	opts := options.Find().SetProjection(bson.M{"title": 1, "content": 1, "steps": 1, "status": 1})
+	cursor, err := collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var signed []signedProcedure
+	for cursor.Next(ctx) {
+		var p models.Procedure
+		if err := cursor.Decode(&p); err != nil {
+			return nil, err
+		}
+		sig := minhashSignature(&p)
+		// Only filled in where still missing, so a concurrent save is not overwritten
+		filter := bson.M{"_id": p.ID, "duplicate_signature": bson.M{"$exists": false}}
+		if _, err := collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"duplicate_signature": storedSignature(sig)}}); err != nil {
+			return nil, err
+		}
+		if sig != nil {
+			signed = append(signed, signedProcedure{
+				match:     models.DuplicateMatch{ProcedureID: p.ID, Title: p.Title, Status: p.Status},
+				signature: sig,
+			})
+		}
+	}
+	return signed, cursor.Err()
+}
+
+// CheckDuplicates lists existing procedures similar to procedure, most similar first, leaving out excludeID
+// (the procedure itself when it is being updated). Unless force is set, a match at or above
+// DUPLICATE_BLOCK_THRESHOLD returns ErrDuplicateProcedure along with the matches.
+func CheckDuplicates(procedure *models.Procedure, excludeID primitive.ObjectID, force bool) ([]models.DuplicateMatch, error) {
+	sig := minhashSignature(procedure)
+	if sig == nil {
+		return nil, nil
+	}
+	signed, err := signedProcedures()
+	if err != nil {
+		return nil, err
+	}
+
+	threshold := DuplicateWarnThreshold()
+	var matches []models.DuplicateMatch
+	for _, candidate := range signed {
+		if candidate.match.ProcedureID == excludeID {
+			continue
+		}
+		if s := sig.similarity(candidate.signature); s >= threshold {
+			match := candidate.match
+			match.Similarity = s
+			matches = append(matches, match)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Similarity > matches[j].Similarity })
+	if len(matches) > maxDuplicateMatch {
+		matches = matches[:maxDuplicateMatch]
+	}
+
+	if !force && len(matches) > 0 && matches[0].Similarity >= duplicateBlockThreshold() {
+		return matches, fmt.Errorf("%w: \"%s\" (%.0f%% similar)", ErrDuplicateProcedure, matches[0].Title, matches[0].Similarity*100)
+	}
+	return matches, nil
+}
+
+// GetDuplicatePairs lists pairs of existing procedures at least threshold similar, most similar first
+func GetDuplicatePairs(threshold float64) ([]models.DuplicatePair, error) {
+	signed, err := signedProcedures()
+	if err != nil {
+		return nil, err
+	}
+
+	pairs := []models.DuplicatePair{}
+	for i := range signed {
+		for j := i + 1; j < len(signed); j++ {
+			s := signed[i].signature.similarity(signed[j].signature)
+			if s < threshold {
+				continue
+			}
+			first, second := signed[i].match, signed[j].match
+			first.Similarity, second.Similarity = s, s
+			pairs = append(pairs, models.DuplicatePair{First: first, Second: second, Similarity: s})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Similarity > pairs[j].Similarity })
+	return pairs, nil
+}
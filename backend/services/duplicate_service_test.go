@@ -0,0 +1,69 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"web_AI/models"
+)
+
+func TestDuplicateText(t *testing.T) {
+	tests := []struct {
+		name      string
+		procedure models.Procedure
+		want      string
+	}{
+		{name: "content", procedure: models.Procedure{Content: "Bước 1: Nộp Hồ sơ!"}, want: "bước1nộphồsơ"},
+		{name: "spaces dropped by PDF extraction", procedure: models.Procedure{Content: "Bước1:NộpHồsơ"}, want: "bước1nộphồsơ"},
+		{name: "steps when there is no content", procedure: models.Procedure{Content: "  ", Steps: []models.ProcedureStep{{Title: "Nộp hồ sơ"}}}, want: "nộphồsơ"},
+		{name: "no text"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(duplicateText(&tt.procedure))
+			if !strings.Contains(got, tt.want) || (tt.want == "" && got != "") {
+				t.Errorf("duplicateText = %q, want it to contain %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignatureSimilarity(t *testing.T) {
+	text := "Nhân viên gửi đơn xin nghỉ phép cho trưởng phòng ít nhất ba ngày trước khi nghỉ. Trưởng phòng duyệt đơn và chuyển cho phòng nhân sự để cập nhật ngày phép."
+	edited := strings.Replace(text, "ba ngày", "năm ngày", 1)
+	other := "Kế toán đối chiếu hóa đơn với đơn đặt hàng, lập phiếu chi và trình giám đốc ký trước khi thanh toán cho nhà cung cấp vào cuối tháng."
+	signatureOf := func(s string) signature {
+		return minhashSignature(&models.Procedure{Content: s})
+	}
+
+	tests := []struct {
+		name     string
+		a, b     signature
+		min, max float64
+	}{
+		{name: "identical", a: signatureOf(text), b: signatureOf(text), min: 1, max: 1},
+		{name: "same text formatted differently", a: signatureOf(text), b: signatureOf(strings.ToUpper(strings.ReplaceAll(text, " ", ""))), min: 1, max: 1},
+		{name: "small edit", a: signatureOf(text), b: signatureOf(edited), min: 0.8, max: 0.99},
+		{name: "different procedures", a: signatureOf(text), b: signatureOf(other), min: 0, max: 0.1},
+		{name: "no text", a: signatureOf(text), b: signatureOf(""), min: 0, max: 0},
+		{name: "short text", a: signatureOf("abc"), b: signatureOf("abc"), min: 1, max: 1},
+		{name: "round trip through storage", a: signatureOf(text), b: loadedSignature(storedSignature(signatureOf(text))), min: 1, max: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.a.similarity(tt.b)
+			if got < tt.min || got > tt.max {
+				t.Errorf("similarity = %v, want between %v and %v", got, tt.min, tt.max)
+			}
+			if reverse := tt.b.similarity(tt.a); reverse != got {
+				t.Errorf("similarity is not symmetric: %v and %v", got, reverse)
+			}
+		})
+	}
+
+	if sig := textSignature(nil); sig != nil {
+		t.Errorf("textSignature(nil) = %v, want nil", sig)
+	}
+	if stored := storedSignature(nil); stored != nil {
+		t.Errorf("storedSignature(nil) = %v, want nil", stored)
+	}
+}
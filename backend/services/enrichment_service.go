@@ -0,0 +1,342 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"web_AI/config"
+	"web_AI/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrEnrichmentNotFound   = errors.New("enrichment not found")
+	ErrEnrichmentInProgress = errors.New("enrichment is already being generated")
+	ErrInvalidEnrichment    = errors.New("invalid enrichment")
+)
+
+// UploadDescriptionPrefix starts the placeholder description of procedures created from an uploaded file
+const UploadDescriptionPrefix = "File upload: "
+
+const (
+	maxSummaryPoints = 7
+	maxFAQPairs      = 10
+	// enrichmentTimeout is how long a generation may run before another one may replace it
+	enrichmentTimeout = 10 * time.Minute
+)
+
+// AutoEnrichEnabled reports whether new and uploaded procedures are enriched by the AI in the background (AUTO_ENRICH)
+func AutoEnrichEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv("AUTO_ENRICH"))
+	return enabled
+}
+
+// placeholderDescription reports whether a description is empty or the upload placeholder
+func placeholderDescription(description string) bool {
+	description = strings.TrimSpace(description)
+	return description == "" || strings.HasPrefix(description, UploadDescriptionPrefix)
+}
+
+// setEnrichmentFields updates enrichment fields of a procedure that is not in the trash.
+// The version is left alone: the enrichment is not part of the content and must not break editors' If-Match checks.
+func setEnrichmentFields(ctx context.Context, filter bson.M, set bson.M) (bool, error) {
+	result, err := config.GetCollection("procedures").UpdateOne(ctx, notDeleted(filter), bson.M{"$set": set})
+	if err != nil {
+		return false, err
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// GetEnrichment returns a procedure's enrichment, flagging it when the procedure text changed since
+func GetEnrichment(id string) (*models.ProcedureEnrichment, error) {
+	procedure, err := GetProcedureByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if procedure.Enrichment == nil {
+		return nil, ErrEnrichmentNotFound
+	}
+
+	enrichment := procedure.Enrichment
+	enrichment.Outdated = enrichment.SourceHash != "" && enrichment.SourceHash != sourceHash(procedure)
+	return enrichment, nil
+}
+
+// StartEnrichment marks a procedure as being enriched and generates the enrichment in the background.
+// The result replaces the previous text as a draft, which has to be approved before readers see it.
+func StartEnrichment(id string, actorID string) (*models.ProcedureEnrichment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	procedure, err := GetProcedureByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	started, err := setEnrichmentFields(ctx,
+		bson.M{
+			"_id": procedure.ID,
+			"$or": []bson.M{
+				{"enrichment.generating": bson.M{"$ne": true}},
+				{"enrichment.generating_since": bson.M{"$lt": now.Add(-enrichmentTimeout)}},
+			},
+		},
+		bson.M{"enrichment.generating": true, "enrichment.generating_since": now, "enrichment.error": ""},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if !started {
+		return nil, ErrEnrichmentInProgress
+	}
+
+	go func() {
+		if err := generateEnrichment(procedure, actorID, now); err != nil {
+			fmt.Printf("⚠️ Failed to enrich procedure %s: %v\n", procedure.ID.Hex(), err)
+		}
+	}()
+
+	enrichment := procedure.Enrichment
+	if enrichment == nil {
+		enrichment = &models.ProcedureEnrichment{}
+	}
+	enrichment.Generating = true
+	enrichment.GeneratingSince = &now
+	enrichment.Error = ""
+	return enrichment, nil
+}
+
+// EnrichInBackground starts an enrichment of a freshly saved procedure, logging instead of failing
+func EnrichInBackground(id primitive.ObjectID, actorID string) {
+	if _, err := StartEnrichment(id.Hex(), actorID); err != nil {
+		fmt.Printf("⚠️ Failed to start enrichment of procedure %s: %v\n", id.Hex(), err)
+	}
+}
+
+// generateEnrichment asks the AI for the enrichment and saves it as a draft, or records the error.
+// Nothing is saved if another generation took over in the meantime.
+func generateEnrichment(procedure *models.Procedure, actorID string, startedAt time.Time) error {
+	enrichment, genErr := draftEnrichment(procedure)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"_id": procedure.ID, "enrichment.generating_since": startedAt}
+	if genErr != nil {
+		_, err := setEnrichmentFields(ctx, filter, bson.M{"enrichment.generating": false, "enrichment.error": genErr.Error()})
+		if err != nil {
+			return err
+		}
+		return genErr
+	}
+
+	now := time.Now()
+	enrichment.Generating = false
+	enrichment.GeneratingSince = nil
+	enrichment.Error = ""
+	enrichment.Status = models.EnrichmentStatusDraft
+	enrichment.MachineDraft = true
+	enrichment.SourceHash = sourceHash(procedure)
+	enrichment.GeneratedAt = &now
+	enrichment.UpdatedAt = &now
+	enrichment.UpdatedBy = actorID
+	_, err := setEnrichmentFields(ctx, filter, bson.M{"enrichment": enrichment})
+	return err
+}
+
+// draftEnrichment asks the AI for a description, summary and FAQ in the procedure's language
+func draftEnrichment(procedure *models.Procedure) (*models.ProcedureEnrichment, error) {
+	answer, err := CallMistralAPI(buildEnrichmentPrompt(procedure))
+	if err != nil {
+		return nil, err
+	}
+
+	var enrichment models.ProcedureEnrichment
+	if err := json.Unmarshal([]byte(extractJSONObject(answer)), &enrichment); err != nil {
+		return nil, fmt.Errorf("unreadable AI response: %v", err)
+	}
+	if err := cleanEnrichment(&enrichment); err != nil {
+		return nil, err
+	}
+	return &enrichment, nil
+}
+
+func buildEnrichmentPrompt(procedure *models.Procedure) string {
+	body := procedure.Content
+	if len(procedure.Steps) > 0 {
+		body = RenderStepsContent(procedure.Steps)
+	}
+	description := ""
+	if !placeholderDescription(procedure.Description) {
+		description = "\nMô tả hiện tại: " + procedure.Description
+	}
+
+	return fmt.Sprintf(`Đọc quy trình nội bộ sau và viết bằng %s:
+- "description": mô tả ngắn 1-2 câu để hiển thị trong danh sách quy trình
+- "summary": tối đa %d ý tóm tắt ngắn gọn
+- "faq": tối đa %d cặp câu hỏi mà nhân viên có thể hỏi và câu trả lời dựa trên quy trình
+
+Chỉ dùng thông tin có trong quy trình. Chỉ trả về JSON hợp lệ dạng
+{"description": "...", "summary": ["..."], "faq": [{"question": "...", "answer": "..."}]}
+
+Tiêu đề: %s%s
+Nội dung:
+%s`, languageNames[sourceLanguage(procedure)], maxSummaryPoints, maxFAQPairs, procedure.Title, description, truncateText(body, 8000))
+}
+
+// cleanEnrichment trims the text, drops empty entries and enforces the limits
+func cleanEnrichment(enrichment *models.ProcedureEnrichment) error {
+	enrichment.Description = strings.TrimSpace(enrichment.Description)
+
+	var summary []string
+	for _, point := range enrichment.Summary {
+		if point = strings.TrimSpace(strings.TrimLeft(point, "-•* ")); point != "" && len(summary) < maxSummaryPoints {
+			summary = append(summary, point)
+		}
+	}
+	enrichment.Summary = summary
+
+	var faq []models.FAQPair
+	for _, pair := range enrichment.FAQ {
+		pair.Question = strings.TrimSpace(pair.Question)
+		pair.Answer = strings.TrimSpace(pair.Answer)
+		if pair.Question != "" && pair.Answer != "" && len(faq) < maxFAQPairs {
+			faq = append(faq, pair)
+		}
+	}
+	enrichment.FAQ = faq
+
+	if enrichment.Description == "" && len(enrichment.Summary) == 0 && len(enrichment.FAQ) == 0 {
+		return fmt.Errorf("%w: description, summary and FAQ are all empty", ErrInvalidEnrichment)
+	}
+	return nil
+}
+
+// UpdateEnrichment saves an admin's edits as a draft that has to be approved again
+func UpdateEnrichment(id string, req models.UpdateEnrichmentRequest, actorID string) (*models.ProcedureEnrichment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	procedure, err := GetProcedureByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	enrichment := models.ProcedureEnrichment{}
+	if procedure.Enrichment != nil {
+		enrichment = *procedure.Enrichment
+	}
+	enrichment.Description = req.Description
+	enrichment.Summary = req.Summary
+	enrichment.FAQ = req.FAQ
+	if err := cleanEnrichment(&enrichment); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	enrichment.Status = models.EnrichmentStatusDraft
+	enrichment.MachineDraft = false
+	enrichment.SourceHash = sourceHash(procedure)
+	enrichment.UpdatedAt = &now
+	enrichment.UpdatedBy = actorID
+	enrichment.ApprovedAt = nil
+	enrichment.ApprovedBy = ""
+
+	set := bson.M{
+		"enrichment.description":   enrichment.Description,
+		"enrichment.summary":       enrichment.Summary,
+		"enrichment.faq":           enrichment.FAQ,
+		"enrichment.status":        enrichment.Status,
+		"enrichment.machine_draft": false,
+		"enrichment.source_hash":   enrichment.SourceHash,
+		"enrichment.updated_at":    now,
+		"enrichment.updated_by":    actorID,
+		"enrichment.approved_at":   nil,
+		"enrichment.approved_by":   "",
+	}
+	if found, err := setEnrichmentFields(ctx, bson.M{"_id": procedure.ID}, set); err != nil {
+		return nil, err
+	} else if !found {
+		return nil, ErrProcedureNotFound
+	}
+	return &enrichment, nil
+}
+
+// ApproveEnrichment makes the enrichment visible to readers, search and chat. The generated description
+// replaces an empty or placeholder procedure description, or any description when replaceDescription is set.
+func ApproveEnrichment(id string, replaceDescription bool, actorID string) (*models.ProcedureEnrichment, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	procedure, err := GetProcedureByID(id)
+	if err != nil {
+		return nil, err
+	}
+	enrichment := procedure.Enrichment
+	if enrichment == nil || enrichment.Status == "" {
+		return nil, ErrEnrichmentNotFound
+	}
+
+	now := time.Now()
+	enrichment.Status = models.EnrichmentStatusApproved
+	enrichment.ApprovedAt = &now
+	enrichment.ApprovedBy = actorID
+	set := bson.M{
+		"enrichment.status":      enrichment.Status,
+		"enrichment.approved_at": now,
+		"enrichment.approved_by": actorID,
+	}
+
+	if enrichment.Description != "" && (replaceDescription || placeholderDescription(procedure.Description)) {
+		// The description is part of the content, so this goes through the versioned update
+		set["description"] = enrichment.Description
+		set["updated_at"] = now
+		set["updated_by"] = actorID
+		if err := updateProcedureFields(procedure.ID, bson.M{"$set": set}); err != nil {
+			return nil, err
+		}
+		procedure.Description = enrichment.Description
+		InvalidateSuggestIndex()
+	} else if found, err := setEnrichmentFields(ctx, bson.M{"_id": procedure.ID}, set); err != nil {
+		return nil, err
+	} else if !found {
+		return nil, ErrProcedureNotFound
+	}
+
+	enrichment.Outdated = enrichment.SourceHash != "" && enrichment.SourceHash != sourceHash(procedure)
+	return enrichment, nil
+}
+
+// DeleteEnrichment removes a procedure's enrichment
+func DeleteEnrichment(id string) error {
+	collection := config.GetCollection("procedures")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	procedure, err := GetProcedureByID(id)
+	if err != nil {
+		return err
+	}
+	if procedure.Enrichment == nil {
+		return ErrEnrichmentNotFound
+	}
+
+	_, err = collection.UpdateOne(ctx, notDeleted(bson.M{"_id": procedure.ID}), bson.M{"$unset": bson.M{"enrichment": ""}})
+	return err
+}
+
+// approvedEnrichment returns the enrichment of a procedure if readers may see it
+func approvedEnrichment(procedure *models.Procedure) *models.ProcedureEnrichment {
+	if procedure.Enrichment == nil || procedure.Enrichment.Status != models.EnrichmentStatusApproved {
+		return nil
+	}
+	return procedure.Enrichment
+}
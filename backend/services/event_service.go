@@ -0,0 +1,278 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+	"web_AI/config"
+	"web_AI/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// indexOptionsConflict is the server error for an index that exists with different options
+const indexOptionsConflict = 85
+
+// eventRetentionDays returns how long raw events are kept (EVENT_RETENTION_DAYS, default 90).
+// Daily rollups are kept forever, so totals and time series do not depend on it.
+func eventRetentionDays() int {
+	if days, err := strconv.Atoi(os.Getenv("EVENT_RETENTION_DAYS")); err == nil && days > 0 {
+		return days
+	}
+	return 90
+}
+
+// EnsureEventIndexes creates the TTL index that expires raw events and the rollup lookup index
+func EnsureEventIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var errs []error
+	if err := ensureTTLIndex(ctx, config.GetCollection("events"), "created_at", eventRetentionDays()); err != nil {
+		errs = append(errs, fmt.Errorf("events TTL index: %v", err))
+	}
+
+	_, err := config.GetCollection("event_rollups").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "day", Value: 1}, {Key: "type", Value: 1}, {Key: "procedure_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		errs = append(errs, fmt.Errorf("event rollups index: %v", err))
+	}
+	return errors.Join(errs...)
+}
+
+// ensureTTLIndex creates an index that expires documents days after field. Creating it again with
+// another expiry fails, so when the retention changed the existing index is updated instead.
+func ensureTTLIndex(ctx context.Context, collection *mongo.Collection, field string, days int) error {
+	seconds := int32(days * 24 * 60 * 60)
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: field, Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(seconds),
+	})
+	var cmdErr mongo.CommandError
+	if !errors.As(err, &cmdErr) || !cmdErr.HasErrorCode(indexOptionsConflict) {
+		return err
+	}
+	return collection.Database().RunCommand(ctx, bson.D{
+		{Key: "collMod", Value: collection.Name()},
+		{Key: "index", Value: bson.D{
+			{Key: "keyPattern", Value: bson.D{{Key: field, Value: 1}}},
+			{Key: "expireAfterSeconds", Value: seconds},
+		}},
+	}).Err()
+}
+
+// TrackEvent records an event in the background so tracking never slows down or fails a request
+func TrackEvent(event models.Event) {
+	event.ID = primitive.NewObjectID()
+	event.CreatedAt = time.Now()
+	event.Query = truncateText(event.Query, 200)
+
+	go func() {
+		if err := recordEvent(&event); err != nil {
+			fmt.Printf("⚠️ Failed to track %s event: %v\n", event.Type, err)
+		}
+	}()
+}
+
+// recordEvent stores the raw event and adds it to the daily rollups
+func recordEvent(event *models.Event) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := config.GetCollection("events").InsertOne(ctx, event); err != nil {
+		return err
+	}
+
+	day := startOfDay(event.CreatedAt)
+	if err := incrementRollup(ctx, day, event.Type, nil); err != nil {
+		return err
+	}
+	if event.ProcedureID != nil {
+		return incrementRollup(ctx, day, event.Type, event.ProcedureID)
+	}
+	return nil
+}
+
+func incrementRollup(ctx context.Context, day time.Time, eventType string, procedureID *primitive.ObjectID) error {
+	collection := config.GetCollection("event_rollups")
+	_, err := collection.UpdateOne(ctx,
+		bson.M{"day": day, "type": eventType, "procedure_id": procedureID},
+		bson.M{"$inc": bson.M{"count": 1}},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+// startOfDay returns midnight UTC of the day t falls on
+func startOfDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// eventTotals returns the all-time count of each event type
+func eventTotals(ctx context.Context) (map[string]int64, error) {
+	collection := config.GetCollection("event_rollups")
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"procedure_id": nil}}},
+		{{Key: "$group", Value: bson.M{"_id": "$type", "count": bson.M{"$sum": "$count"}}}},
+	}
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		Type  string `bson:"_id"`
+		Count int64  `bson:"count"`
+	}
+	if err = cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	totals := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		totals[row.Type] = row.Count
+	}
+	return totals, nil
+}
+
+// eventSeries returns the daily counts of the last days days and the weekly counts of the ISO weeks
+// they fall in; periods without events are included with zero counts
+func eventSeries(ctx context.Context, days int) ([]models.StatsPoint, []models.StatsPoint, error) {
+	collection := config.GetCollection("event_rollups")
+
+	today := startOfDay(time.Now())
+	since := today.AddDate(0, 0, -(days - 1))
+	weekStart := since.AddDate(0, 0, -((int(since.Weekday()) + 6) % 7)) // back to Monday
+
+	cursor, err := collection.Find(ctx, bson.M{"procedure_id": nil, "day": bson.M{"$gte": weekStart}})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rollups []models.EventRollup
+	if err = cursor.All(ctx, &rollups); err != nil {
+		return nil, nil, err
+	}
+	byDay := make(map[string]map[string]int64)
+	for _, r := range rollups {
+		key := r.Day.UTC().Format("2006-01-02")
+		if byDay[key] == nil {
+			byDay[key] = make(map[string]int64)
+		}
+		byDay[key][r.Type] += r.Count
+	}
+
+	var daily, weekly []models.StatsPoint
+	for day := weekStart; !day.After(today); day = day.AddDate(0, 0, 1) {
+		counts := byDay[day.Format("2006-01-02")]
+
+		year, week := day.ISOWeek()
+		period := fmt.Sprintf("%d-W%02d", year, week)
+		if len(weekly) == 0 || weekly[len(weekly)-1].Period != period {
+			weekly = append(weekly, models.StatsPoint{Period: period})
+		}
+		addEventCounts(&weekly[len(weekly)-1], counts)
+
+		if !day.Before(since) {
+			point := models.StatsPoint{Period: day.Format("2006-01-02")}
+			addEventCounts(&point, counts)
+			daily = append(daily, point)
+		}
+	}
+	return daily, weekly, nil
+}
+
+func addEventCounts(point *models.StatsPoint, counts map[string]int64) {
+	point.Views += counts[models.EventProcedureView]
+	point.Searches += counts[models.EventSearch]
+	point.Chats += counts[models.EventChat]
+	point.Logins += counts[models.EventLogin]
+}
+
+// topViewedProcedures returns the most viewed procedures of the last days days; procedures in the trash are left out
+func topViewedProcedures(ctx context.Context, days int, limit int) ([]models.TopProcedure, error) {
+	collection := config.GetCollection("event_rollups")
+
+	since := startOfDay(time.Now()).AddDate(0, 0, -(days - 1))
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"type":         models.EventProcedureView,
+			"procedure_id": bson.M{"$ne": nil},
+			"day":          bson.M{"$gte": since},
+		}}},
+		{{Key: "$group", Value: bson.M{"_id": "$procedure_id", "views": bson.M{"$sum": "$count"}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "views", Value: -1}}}},
+		// Fetch a few extra to make up for deleted procedures
+		{{Key: "$limit", Value: limit * 2}},
+	}
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		ProcedureID primitive.ObjectID `bson:"_id"`
+		Views       int64              `bson:"views"`
+	}
+	if err = cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return []models.TopProcedure{}, nil
+	}
+
+	ids := make([]primitive.ObjectID, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ProcedureID
+	}
+	titles, err := procedureTitles(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	top := []models.TopProcedure{}
+	for _, row := range rows {
+		title, ok := titles[row.ProcedureID]
+		if !ok {
+			continue
+		}
+		top = append(top, models.TopProcedure{ProcedureID: row.ProcedureID, Title: title, Views: row.Views})
+		if len(top) == limit {
+			break
+		}
+	}
+	return top, nil
+}
+
+// procedureTitles maps the IDs of procedures that are not in the trash to their titles
+func procedureTitles(ctx context.Context, ids []primitive.ObjectID) (map[primitive.ObjectID]string, error) {
+	collection := config.GetCollection("procedures")
+	opts := options.Find().SetProjection(bson.M{"title": 1})
+	cursor, err := collection.Find(ctx, notDeleted(bson.M{"_id": bson.M{"$in": ids}}), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var procedures []models.Procedure
+	if err = cursor.All(ctx, &procedures); err != nil {
+		return nil, err
+	}
+
+	titles := make(map[primitive.ObjectID]string, len(procedures))
+	for _, p := range procedures {
+		titles[p.ID] = p.Title
+	}
+	return titles, nil
+}
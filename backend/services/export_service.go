@@ -0,0 +1,445 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"web_AI/models"
+	"web_AI/utils"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// Export formats
+const (
+	ExportFormatMarkdown = "md"
+	ExportFormatDOCX     = "docx"
+	ExportFormatPDF      = "pdf"
+)
+
+var exportContentTypes = map[string]string{
+	ExportFormatMarkdown: "text/markdown; charset=utf-8",
+	ExportFormatDOCX:     "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	ExportFormatPDF:      "application/pdf",
+}
+
+// Block kinds of the format-independent document every exporter renders
+const (
+	blockTitle     = "title"
+	blockHeading   = "heading"
+	blockParagraph = "paragraph"
+	blockBullet    = "bullet"
+	blockNumbered  = "numbered"
+)
+
+type exportBlock struct {
+	kind   string
+	level  int    // list nesting, 0 = top level
+	marker string // numbered lists: "2." or "2.1."
+	number int    // numbered lists: position within its own level
+	text   string
+}
+
+var (
+	// bullets typed by hand or left over from PDF extraction (U+F0B7 is the Symbol-font bullet)
+	bulletLine   = regexp.MustCompile(`^(?:[-*•\x{f0b7}])\s*(.*)$`)
+	numberedLine = regexp.MustCompile(`^(\d+(?:\.\d+)*[.)])\s+(.*)$`)
+	headingLine  = regexp.MustCompile(`^#{1,6}\s+(.*)$`)
+)
+
+// ExportProcedure renders a procedure as a Markdown, DOCX or PDF document
+func ExportProcedure(procedure *models.Procedure, format string) (data []byte, contentType string, filename string, err error) {
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		return nil, "", "", fmt.Errorf("unsupported export format '%s' (use docx, pdf or md)", format)
+	}
+
+	blocks := buildExportBlocks(procedure)
+	switch format {
+	case ExportFormatMarkdown:
+		data = renderMarkdown(blocks)
+	case ExportFormatDOCX:
+		data, err = renderDOCX(blocks, procedure.Title)
+	case ExportFormatPDF:
+		data, err = renderPDF(blocks, procedure.Title)
+	}
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	return data, contentType, exportFileName(procedure.Title) + "." + format, nil
+}
+
+// exportFileName turns a title into an ASCII file name, e.g. "Quy trình nghỉ phép" -> "Quy_trinh_nghi_phep"
+func exportFileName(title string) string {
+	name := strings.Join(strings.Fields(utils.RemoveDiacritics(title)), "_")
+	name = regexp.MustCompile(`[^A-Za-z0-9_-]`).ReplaceAllString(name, "")
+	if name == "" {
+		name = "quy_trinh"
+	}
+	if len(name) > 80 {
+		name = name[:80]
+	}
+	return name
+}
+
+func buildExportBlocks(procedure *models.Procedure) []exportBlock {
+	blocks := []exportBlock{{kind: blockTitle, text: procedure.Title}}
+
+	meta := []string{}
+	if procedure.Category != "" {
+		meta = append(meta, "Danh mục: "+procedure.Category)
+	}
+	if !procedure.UpdatedAt.IsZero() {
+		meta = append(meta, "Cập nhật lần cuối: "+procedure.UpdatedAt.Format("02/01/2006"))
+	}
+	if !procedure.ID.IsZero() {
+		meta = append(meta, "Mã quy trình: "+procedure.ID.Hex())
+	}
+	meta = append(meta, "Ngày xuất: "+time.Now().Format("02/01/2006 15:04"))
+	for _, m := range meta {
+		blocks = append(blocks, exportBlock{kind: blockParagraph, text: m})
+	}
+
+	if strings.TrimSpace(procedure.Description) != "" {
+		blocks = append(blocks, exportBlock{kind: blockHeading, text: "Mô tả"})
+		blocks = append(blocks, parseContentBlocks(procedure.Description)...)
+	}
+
+	if len(procedure.Steps) > 0 {
+		blocks = append(blocks, exportBlock{kind: blockHeading, text: "Các bước thực hiện"})
+		blocks = append(blocks, stepBlocks(procedure.Steps, "", 0)...)
+	} else if strings.TrimSpace(procedure.Content) != "" {
+		blocks = append(blocks, exportBlock{kind: blockHeading, text: "Nội dung"})
+		blocks = append(blocks, parseContentBlocks(procedure.Content)...)
+	}
+
+	return blocks
+}
+
+func stepBlocks(steps []models.ProcedureStep, prefix string, level int) []exportBlock {
+	var blocks []exportBlock
+	for i, step := range steps {
+		marker := prefix + strconv.Itoa(i+1) + "."
+		blocks = append(blocks, exportBlock{kind: blockNumbered, level: level, marker: marker, number: i + 1, text: step.Title})
+		for _, line := range splitLines(step.Instructions) {
+			if strings.TrimSpace(line) != "" {
+				blocks = append(blocks, exportBlock{kind: blockParagraph, level: level + 1, text: strings.TrimSpace(line)})
+			}
+		}
+		if step.ResponsibleRole != "" {
+			blocks = append(blocks, exportBlock{kind: blockBullet, level: level + 1, text: "Người phụ trách: " + step.ResponsibleRole})
+		}
+		if step.ExpectedDuration != "" {
+			blocks = append(blocks, exportBlock{kind: blockBullet, level: level + 1, text: "Thời gian dự kiến: " + step.ExpectedDuration})
+		}
+		if len(step.RequiredDocuments) > 0 {
+			blocks = append(blocks, exportBlock{kind: blockBullet, level: level + 1, text: "Giấy tờ cần có: " + strings.Join(step.RequiredDocuments, ", ")})
+		}
+		blocks = append(blocks, stepBlocks(step.SubSteps, strings.TrimSuffix(marker, "."), level+1)...)
+	}
+	return blocks
+}
+
+// parseContentBlocks recognises headings, bullets and numbered lines in free-text content
+func parseContentBlocks(content string) []exportBlock {
+	var blocks []exportBlock
+	for _, raw := range splitLines(content) {
+		line := strings.TrimRight(raw, " \t")
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed == "" {
+			continue
+		}
+		indent := len(strings.ReplaceAll(line[:len(line)-len(trimmed)], "\t", "    "))
+		level := min(indent/3, 3)
+
+		if m := headingLine.FindStringSubmatch(trimmed); m != nil {
+			blocks = append(blocks, exportBlock{kind: blockHeading, text: m[1]})
+			continue
+		}
+		if m := numberedLine.FindStringSubmatch(trimmed); m != nil {
+			parts := strings.Split(strings.TrimRight(m[1], ".)"), ".")
+			n, _ := strconv.Atoi(parts[len(parts)-1])
+			if depth := strings.Count(strings.TrimRight(m[1], ".)"), "."); depth > level {
+				level = min(depth, 3)
+			}
+			blocks = append(blocks, exportBlock{kind: blockNumbered, level: level, marker: m[1], number: n, text: m[2]})
+			continue
+		}
+		if m := bulletLine.FindStringSubmatch(trimmed); m != nil {
+			if text := strings.TrimSpace(m[1]); text != "" {
+				blocks = append(blocks, exportBlock{kind: blockBullet, level: level, text: text})
+			}
+			continue
+		}
+		blocks = append(blocks, exportBlock{kind: blockParagraph, level: level, text: trimmed})
+	}
+	return blocks
+}
+
+func renderMarkdown(blocks []exportBlock) []byte {
+	var sb strings.Builder
+	prevList := false
+	for _, b := range blocks {
+		indent := strings.Repeat("   ", b.level)
+		isList := b.kind == blockBullet || b.kind == blockNumbered
+		if prevList && !isList && b.level == 0 {
+			sb.WriteString("\n")
+		}
+		switch b.kind {
+		case blockTitle:
+			sb.WriteString("# " + b.text + "\n\n")
+		case blockHeading:
+			sb.WriteString("## " + b.text + "\n\n")
+		case blockBullet:
+			sb.WriteString(indent + "- " + b.text + "\n")
+		case blockNumbered:
+			sb.WriteString(fmt.Sprintf("%s%d. %s\n", indent, b.number, b.text))
+		default:
+			if b.level > 0 {
+				sb.WriteString(indent + b.text + "\n")
+			} else {
+				sb.WriteString(b.text + "\n\n")
+			}
+		}
+		prevList = isList || b.level > 0
+	}
+	return []byte(strings.TrimRight(sb.String(), "\n") + "\n")
+}
+
+// renderDOCX writes a minimal WordprocessingML package directly: unioffice needs a paid license to save documents
+func renderDOCX(blocks []exportBlock, title string) ([]byte, error) {
+	var body strings.Builder
+	for _, b := range blocks {
+		style := ""
+		text := b.text
+		indent := 0
+		switch b.kind {
+		case blockTitle:
+			style = "Title"
+		case blockHeading:
+			style = "Heading1"
+		case blockBullet:
+			style = "ListParagraph"
+			text = "•\t" + text
+			indent = 360 * (b.level + 1)
+		case blockNumbered:
+			style = "ListParagraph"
+			text = b.marker + "\t" + text
+			indent = 360 * (b.level + 1)
+		default:
+			indent = 360 * b.level
+		}
+
+		body.WriteString("<w:p><w:pPr>")
+		if style != "" {
+			body.WriteString(`<w:pStyle w:val="` + style + `"/>`)
+		}
+		if indent > 0 {
+			hanging := ""
+			if b.kind == blockBullet || b.kind == blockNumbered {
+				hanging = ` w:hanging="360"`
+			}
+			body.WriteString(fmt.Sprintf(`<w:ind w:left="%d"%s/>`, indent, hanging))
+		}
+		body.WriteString("</w:pPr>")
+		for i, part := range strings.Split(text, "\t") {
+			if i > 0 {
+				body.WriteString("<w:r><w:tab/></w:r>")
+			}
+			body.WriteString(`<w:r><w:t xml:space="preserve">` + xmlEscape(part) + "</w:t></w:r>")
+		}
+		body.WriteString("</w:p>")
+	}
+
+	files := []struct{ name, content string }{
+		{"[Content_Types].xml", docxContentTypes},
+		{"_rels/.rels", docxRootRels},
+		{"docProps/core.xml", fmt.Sprintf(docxCoreProps, xmlEscape(title), time.Now().UTC().Format(time.RFC3339))},
+		{"word/_rels/document.xml.rels", docxDocumentRels},
+		{"word/styles.xml", docxStyles},
+		{"word/document.xml", docxDocumentHeader + body.String() + docxDocumentFooter},
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write([]byte(f.content)); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func xmlEscape(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+// pdfFontCandidates are Unicode fonts tried when PDF_FONT_PATH is not set
+var pdfFontCandidates = []string{
+	"/usr/share/fonts/truetype/dejavu/DejaVuSans.ttf",
+	"/usr/share/fonts/dejavu/DejaVuSans.ttf",
+	"/Library/Fonts/Arial Unicode.ttf",
+	`C:\Windows\Fonts\arial.ttf`,
+}
+
+// findPDFFont returns a TrueType font that can render Vietnamese, or "" if none is available
+func findPDFFont() (regular string, bold string) {
+	candidates := pdfFontCandidates
+	if path := os.Getenv("PDF_FONT_PATH"); path != "" {
+		candidates = append([]string{path}, candidates...)
+	}
+	for _, path := range candidates {
+		if _, err := os.Stat(path); err == nil {
+			regular = path
+			break
+		}
+	}
+	if regular == "" {
+		return "", ""
+	}
+
+	bold = os.Getenv("PDF_FONT_BOLD_PATH")
+	if bold == "" {
+		// DejaVuSans.ttf -> DejaVuSans-Bold.ttf, arial.ttf -> arialbd.ttf
+		for _, guess := range []string{
+			strings.TrimSuffix(regular, ".ttf") + "-Bold.ttf",
+			strings.TrimSuffix(regular, ".ttf") + "bd.ttf",
+		} {
+			if _, err := os.Stat(guess); err == nil {
+				bold = guess
+				break
+			}
+		}
+	}
+	if bold == "" {
+		bold = regular
+	}
+	return regular, bold
+}
+
+func renderPDF(blocks []exportBlock, title string) ([]byte, error) {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(20, 20, 20)
+	pdf.SetAutoPageBreak(true, 20)
+
+	family := "Helvetica"
+	encode := func(s string) string { return s }
+	regular, bold := findPDFFont()
+	regularFont, errRegular := os.ReadFile(regular)
+	boldFont, errBold := os.ReadFile(bold)
+	if regular != "" && errRegular == nil && errBold == nil {
+		family = "UnicodeSans"
+		pdf.AddUTF8FontFromBytes(family, "", regularFont)
+		pdf.AddUTF8FontFromBytes(family, "B", boldFont)
+		pdf.SetTitle(title, true)
+	} else {
+		// Core PDF fonts only cover Latin-1: keep the text readable without accents
+		fmt.Println("⚠️ PDF export: no Unicode font found (set PDF_FONT_PATH), Vietnamese accents will be removed")
+		translate := pdf.UnicodeTranslatorFromDescriptor("")
+		encode = func(s string) string { return translate(utils.RemoveDiacritics(s)) }
+		pdf.SetTitle(utils.RemoveDiacritics(title), false)
+	}
+	pdf.AddPage()
+
+	const indentStep = 7.0
+	for _, b := range blocks {
+		left := 20 + indentStep*float64(b.level)
+		pdf.SetX(left)
+		switch b.kind {
+		case blockTitle:
+			pdf.SetFont(family, "B", 18)
+			pdf.MultiCell(0, 9, encode(b.text), "", "L", false)
+			pdf.Ln(3)
+		case blockHeading:
+			pdf.Ln(3)
+			pdf.SetFont(family, "B", 14)
+			pdf.MultiCell(0, 7, encode(b.text), "", "L", false)
+			pdf.Ln(1)
+		case blockBullet, blockNumbered:
+			marker := "•"
+			if b.kind == blockNumbered {
+				marker = b.marker
+			}
+			if family == "Helvetica" && b.kind == blockBullet {
+				marker = "-"
+			}
+			pdf.SetFont(family, "", 11)
+			markerWidth := pdf.GetStringWidth(encode(marker)) + 2
+			pdf.CellFormat(markerWidth, 6, encode(marker), "", 0, "L", false, 0, "")
+			pdf.SetLeftMargin(left + markerWidth)
+			pdf.MultiCell(0, 6, encode(b.text), "", "L", false)
+			pdf.SetLeftMargin(20)
+		default:
+			pdf.SetFont(family, "", 11)
+			pdf.SetLeftMargin(left)
+			pdf.MultiCell(0, 6, encode(b.text), "", "L", false)
+			pdf.SetLeftMargin(20)
+			if b.level == 0 {
+				pdf.Ln(1)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+const docxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>
+<Override PartName="/word/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.styles+xml"/>
+<Override PartName="/docProps/core.xml" ContentType="application/vnd.openxmlformats-package.core-properties+xml"/>
+</Types>`
+
+const docxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/package/2006/relationships/metadata/core-properties" Target="docProps/core.xml"/>
+</Relationships>`
+
+const docxCoreProps = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:dcterms="http://purl.org/dc/terms/" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+<dc:title>%s</dc:title>
+<dcterms:created xsi:type="dcterms:W3CDTF">%s</dcterms:created>
+</cp:coreProperties>`
+
+const docxDocumentRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+</Relationships>`
+
+const docxStyles = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">
+<w:docDefaults><w:rPrDefault><w:rPr><w:rFonts w:ascii="Arial" w:hAnsi="Arial" w:cs="Arial"/><w:sz w:val="22"/></w:rPr></w:rPrDefault>
+<w:pPrDefault><w:pPr><w:spacing w:after="120"/></w:pPr></w:pPrDefault></w:docDefaults>
+<w:style w:type="paragraph" w:default="1" w:styleId="Normal"><w:name w:val="Normal"/></w:style>
+<w:style w:type="paragraph" w:styleId="Title"><w:name w:val="Title"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:pPr><w:spacing w:after="240"/></w:pPr><w:rPr><w:b/><w:sz w:val="40"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="Heading1"><w:name w:val="heading 1"/><w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:pPr><w:keepNext/><w:spacing w:before="240" w:after="120"/><w:outlineLvl w:val="0"/></w:pPr><w:rPr><w:b/><w:sz w:val="30"/></w:rPr></w:style>
+<w:style w:type="paragraph" w:styleId="ListParagraph"><w:name w:val="List Paragraph"/><w:basedOn w:val="Normal"/><w:pPr><w:spacing w:after="60"/></w:pPr></w:style>
+</w:styles>`
+
+const docxDocumentHeader = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>`
+
+const docxDocumentFooter = `<w:sectPr><w:pgSz w:w="11906" w:h="16838"/><w:pgMar w:top="1134" w:right="1134" w:bottom="1134" w:left="1134" w:header="708" w:footer="708" w:gutter="0"/></w:sectPr></w:body></w:document>`
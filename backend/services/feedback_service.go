@@ -0,0 +1,332 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"web_AI/config"
+	"web_AI/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrInvalidFeedback = errors.New("invalid feedback")
+	ErrCommentNotFound = errors.New("comment not found")
+)
+
+// maxCommentLength limits comments to a few paragraphs
+const maxCommentLength = 2000
+
+// RateProcedure records whether the viewer found a procedure helpful, replacing their earlier vote
+func RateProcedure(procedureID string, viewer models.Viewer, helpful bool) (*models.RatingResponse, error) {
+	collection := config.GetCollection("procedure_ratings")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	procedure, err := GetPublishedProcedureByID(procedureID, viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	filter := bson.M{"procedure_id": procedure.ID, "user_id": viewer.UserID}
+	update := bson.M{
+		"$set":         bson.M{"helpful": helpful, "updated_at": now},
+		"$setOnInsert": bson.M{"created_at": now},
+	}
+	_, err = collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		// Another vote of the same reader was inserted in between; update that one instead
+		_, err = collection.UpdateOne(ctx, filter, update)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	summary, err := refreshFeedbackSummary(ctx, procedure.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.RatingResponse{Helpful: &helpful, Feedback: *summary}, nil
+}
+
+// RemoveRating withdraws the viewer's vote on a procedure
+func RemoveRating(procedureID string, viewer models.Viewer) (*models.RatingResponse, error) {
+	collection := config.GetCollection("procedure_ratings")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	procedure, err := GetPublishedProcedureByID(procedureID, viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := collection.DeleteOne(ctx, bson.M{"procedure_id": procedure.ID, "user_id": viewer.UserID}); err != nil {
+		return nil, err
+	}
+
+	summary, err := refreshFeedbackSummary(ctx, procedure.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &models.RatingResponse{Feedback: *summary}, nil
+}
+
+// AddComment stores a comment or clarification request; clarification requests notify the procedure owner
+func AddComment(procedureID string, viewer models.Viewer, req models.CreateCommentRequest) (*models.ProcedureComment, error) {
+	collection := config.GetCollection("procedure_comments")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	procedure, err := GetPublishedProcedureByID(procedureID, viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	comment := models.ProcedureComment{
+		ID:          primitive.NewObjectID(),
+		ProcedureID: procedure.ID,
+		UserID:      viewer.UserID,
+		Type:        req.Type,
+		Comment:     strings.TrimSpace(req.Comment),
+		StepNumber:  strings.TrimSpace(req.StepNumber),
+		CreatedAt:   time.Now(),
+	}
+	if comment.Type == "" {
+		comment.Type = models.FeedbackTypeComment
+	}
+	if comment.Type != models.FeedbackTypeComment && comment.Type != models.FeedbackTypeClarification {
+		return nil, fmt.Errorf("%w: type must be 'comment' or 'clarification'", ErrInvalidFeedback)
+	}
+	if comment.Comment == "" {
+		return nil, fmt.Errorf("%w: comment is empty", ErrInvalidFeedback)
+	}
+	if utf8.RuneCountInString(comment.Comment) > maxCommentLength {
+		return nil, fmt.Errorf("%w: comment is longer than %d characters", ErrInvalidFeedback, maxCommentLength)
+	}
+	if comment.StepNumber != "" {
+		if _, err := FindStep(procedure.Steps, comment.StepNumber); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFeedback, err)
+		}
+	}
+
+	if _, err := collection.InsertOne(ctx, comment); err != nil {
+		return nil, err
+	}
+	if _, err := refreshFeedbackSummary(ctx, procedure.ID); err != nil {
+		fmt.Printf("⚠️ Failed to refresh feedback for procedure %s: %v\n", procedure.ID.Hex(), err)
+	}
+
+	if comment.Type == models.FeedbackTypeClarification {
+		if owner := procedureOwner(procedure); owner != "" && owner != viewer.UserID {
+			notification := &models.Notification{
+				UserID:      owner,
+				Type:        models.NotificationTypeClarificationRequested,
+				Title:       "Yêu cầu làm rõ quy trình",
+				Message:     clarificationMessage(procedure, &comment),
+				ProcedureID: procedure.ID,
+			}
+			if err := CreateNotification(notification); err != nil {
+				fmt.Printf("⚠️ Failed to notify owner of procedure %s: %v\n", procedure.ID.Hex(), err)
+			}
+		}
+	}
+
+	return &comment, nil
+}
+
+func clarificationMessage(procedure *models.Procedure, comment *models.ProcedureComment) string {
+	where := ""
+	if comment.StepNumber != "" {
+		where = fmt.Sprintf(" (bước %s)", comment.StepNumber)
+	}
+	return fmt.Sprintf("Người đọc cần làm rõ quy trình \"%s\"%s: %s", procedure.Title, where, truncateText(comment.Comment, 200))
+}
+
+// GetComments lists the comments on a procedure the viewer may see, newest first
+func GetComments(procedureID string, viewer models.Viewer, commentType string) ([]models.ProcedureComment, error) {
+	collection := config.GetCollection("procedure_comments")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	procedure, err := GetPublishedProcedureByID(procedureID, viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"procedure_id": procedure.ID}
+	if commentType != "" {
+		filter["type"] = commentType
+	}
+
+	opts := options.Find().SetSort(bson.D{bson.E{Key: "created_at", Value: -1}})
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	comments := []models.ProcedureComment{}
+	if err = cursor.All(ctx, &comments); err != nil {
+		return nil, err
+	}
+
+	// Readers see who commented by name; account IDs stay with admins
+	var userIDs []string
+	for _, comment := range comments {
+		if !containsString(userIDs, comment.UserID) {
+			userIDs = append(userIDs, comment.UserID)
+		}
+	}
+	names, err := GetUserNames(userIDs)
+	if err != nil {
+		return nil, err
+	}
+	for i := range comments {
+		comments[i].AuthorName = names[comments[i].UserID]
+		if !viewer.IsAdmin {
+			comments[i].UserID = ""
+		}
+	}
+
+	return comments, nil
+}
+
+// EnsureFeedbackIndexes allows one vote per reader and procedure, so concurrent votes cannot both be inserted
+func EnsureFeedbackIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := config.GetCollection("procedure_ratings").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "procedure_id", Value: 1}, {Key: "user_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// ResolveComment marks a clarification request (or comment) as handled
+func ResolveComment(id string, actorID string) (*models.ProcedureComment, error) {
+	collection := config.GetCollection("procedure_comments")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid comment ID", ErrInvalidFeedback)
+	}
+
+	now := time.Now()
+	var comment models.ProcedureComment
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": objID},
+		bson.M{"$set": bson.M{"resolved": true, "resolved_by": actorID, "resolved_at": now}},
+		opts,
+	).Decode(&comment)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrCommentNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	if _, err := refreshFeedbackSummary(ctx, comment.ProcedureID); err != nil {
+		fmt.Printf("⚠️ Failed to refresh feedback for procedure %s: %v\n", comment.ProcedureID.Hex(), err)
+	}
+	return &comment, nil
+}
+
+// refreshFeedbackSummary recounts a procedure's ratings and comments and stores the totals on it.
+// The version is left alone: feedback is not an edit and must not break editors' If-Match checks.
+func refreshFeedbackSummary(ctx context.Context, procedureID primitive.ObjectID) (*models.FeedbackSummary, error) {
+	ratings := config.GetCollection("procedure_ratings")
+	comments := config.GetCollection("procedure_comments")
+
+	var summary models.FeedbackSummary
+	var err error
+	if summary.HelpfulCount, err = ratings.CountDocuments(ctx, bson.M{"procedure_id": procedureID, "helpful": true}); err != nil {
+		return nil, err
+	}
+	if summary.NotHelpfulCount, err = ratings.CountDocuments(ctx, bson.M{"procedure_id": procedureID, "helpful": false}); err != nil {
+		return nil, err
+	}
+	if summary.CommentCount, err = comments.CountDocuments(ctx, bson.M{"procedure_id": procedureID, "type": models.FeedbackTypeComment}); err != nil {
+		return nil, err
+	}
+	if summary.ClarificationCount, err = comments.CountDocuments(ctx, bson.M{"procedure_id": procedureID, "type": models.FeedbackTypeClarification, "resolved": false}); err != nil {
+		return nil, err
+	}
+	summary.RatingCount = summary.HelpfulCount + summary.NotHelpfulCount
+	if summary.RatingCount > 0 {
+		summary.Score = float64(summary.HelpfulCount) / float64(summary.RatingCount)
+	}
+	now := time.Now()
+	summary.UpdatedAt = &now
+
+	_, err = config.GetCollection("procedures").UpdateOne(ctx, bson.M{"_id": procedureID}, bson.M{"$set": bson.M{"feedback": summary}})
+	if err != nil {
+		return nil, err
+	}
+	return &summary, nil
+}
+
+// GetFeedbackReport lists procedures with feedback, either the lowest rated first (ties broken by
+// how many people rated them) or the most commented first (comments plus open clarification requests)
+func GetFeedbackReport(sortBy string, limit int64) ([]models.ProcedureFeedback, error) {
+	collection := config.GetCollection("procedures")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var pipeline mongo.Pipeline
+	switch sortBy {
+	case models.FeedbackSortLowestRated:
+		pipeline = mongo.Pipeline{
+			{{Key: "$match", Value: notDeleted(bson.M{"feedback.rating_count": bson.M{"$gt": 0}})}},
+			{{Key: "$sort", Value: bson.D{{Key: "feedback.score", Value: 1}, {Key: "feedback.rating_count", Value: -1}}}},
+		}
+	case models.FeedbackSortMostCommented:
+		pipeline = mongo.Pipeline{
+			{{Key: "$match", Value: notDeleted(bson.M{"feedback": bson.M{"$exists": true}})}},
+			{{Key: "$addFields", Value: bson.M{"feedback_total": bson.M{"$add": bson.A{"$feedback.comment_count", "$feedback.clarification_count"}}}}},
+			{{Key: "$match", Value: bson.M{"feedback_total": bson.M{"$gt": 0}}}},
+			{{Key: "$sort", Value: bson.D{{Key: "feedback_total", Value: -1}, {Key: "feedback.clarification_count", Value: -1}}}},
+		}
+	default:
+		return nil, fmt.Errorf("%w: sort must be '%s' or '%s'", ErrInvalidFeedback, models.FeedbackSortLowestRated, models.FeedbackSortMostCommented)
+	}
+	if limit > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: limit}})
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var procedures []models.Procedure
+	if err = cursor.All(ctx, &procedures); err != nil {
+		return nil, err
+	}
+
+	report := make([]models.ProcedureFeedback, 0, len(procedures))
+	for _, p := range procedures {
+		row := models.ProcedureFeedback{
+			ProcedureID: p.ID,
+			Title:       p.Title,
+			Category:    p.Category,
+			OwnerID:     procedureOwner(&p),
+		}
+		if p.Feedback != nil {
+			row.Feedback = *p.Feedback
+		}
+		report = append(report, row)
+	}
+	return report, nil
+}
@@ -0,0 +1,269 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	"web_AI/config"
+	"web_AI/models"
+	"web_AI/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrGlossaryEntryNotFound is returned when no glossary entry has the given ID
+var ErrGlossaryEntryNotFound = errors.New("glossary entry not found")
+
+// glossaryCache keeps the (small) glossary in memory because every search and chat request reads it.
+// generation changes on every invalidation, so a load that overlaps an edit is not kept.
+var glossaryCache struct {
+	mu         sync.RWMutex
+	entries    []models.GlossaryEntry
+	loaded     bool
+	generation uint64
+}
+
+func invalidateGlossaryCache() {
+	glossaryCache.mu.Lock()
+	glossaryCache.loaded = false
+	glossaryCache.generation++
+	glossaryCache.mu.Unlock()
+}
+
+func cachedGlossary() ([]models.GlossaryEntry, error) {
+	glossaryCache.mu.RLock()
+	if glossaryCache.loaded {
+		entries := glossaryCache.entries
+		glossaryCache.mu.RUnlock()
+		return entries, nil
+	}
+	generation := glossaryCache.generation
+	glossaryCache.mu.RUnlock()
+
+	entries, err := GetGlossaryEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	glossaryCache.mu.Lock()
+	if glossaryCache.generation == generation {
+		glossaryCache.entries = entries
+		glossaryCache.loaded = true
+	}
+	glossaryCache.mu.Unlock()
+	return entries, nil
+}
+
+// GetGlossaryEntries retrieves all glossary entries sorted by term
+func GetGlossaryEntries() ([]models.GlossaryEntry, error) {
+	collection := config.GetCollection("glossary")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{bson.E{Key: "term", Value: 1}})
+	cursor, err := collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	entries := []models.GlossaryEntry{}
+	if err = cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// GetGlossaryEntryByID retrieves a single glossary entry
+func GetGlossaryEntryByID(id string) (*models.GlossaryEntry, error) {
+	collection := config.GetCollection("glossary")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid glossary entry ID")
+	}
+
+	var entry models.GlossaryEntry
+	err = collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&entry)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrGlossaryEntryNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+// CreateGlossaryEntry creates a new glossary entry
+func CreateGlossaryEntry(req models.CreateGlossaryEntryRequest) (*models.GlossaryEntry, error) {
+	collection := config.GetCollection("glossary")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	term := strings.TrimSpace(req.Term)
+	if err := checkGlossaryTermAvailable(ctx, term, primitive.NilObjectID); err != nil {
+		return nil, err
+	}
+
+	entry := models.GlossaryEntry{
+		ID:         primitive.NewObjectID(),
+		Term:       term,
+		Synonyms:   cleanSynonyms(term, req.Synonyms),
+		Definition: strings.TrimSpace(req.Definition),
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+
+	if _, err := collection.InsertOne(ctx, entry); err != nil {
+		return nil, err
+	}
+
+	invalidateGlossaryCache()
+	return &entry, nil
+}
+
+// UpdateGlossaryEntry replaces the term, synonyms and definition of an entry
+func UpdateGlossaryEntry(id string, req models.UpdateGlossaryEntryRequest) (*models.GlossaryEntry, error) {
+	collection := config.GetCollection("glossary")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid glossary entry ID")
+	}
+
+	term := strings.TrimSpace(req.Term)
+	if err := checkGlossaryTermAvailable(ctx, term, objID); err != nil {
+		return nil, err
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"term":       term,
+			"synonyms":   cleanSynonyms(term, req.Synonyms),
+			"definition": strings.TrimSpace(req.Definition),
+			"updated_at": time.Now(),
+		},
+	}
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": objID}, update)
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		return nil, ErrGlossaryEntryNotFound
+	}
+
+	invalidateGlossaryCache()
+	return GetGlossaryEntryByID(id)
+}
+
+// DeleteGlossaryEntry deletes a glossary entry by ID
+func DeleteGlossaryEntry(id string) error {
+	collection := config.GetCollection("glossary")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("invalid glossary entry ID")
+	}
+
+	result, err := collection.DeleteOne(ctx, bson.M{"_id": objID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrGlossaryEntryNotFound
+	}
+
+	invalidateGlossaryCache()
+	return nil
+}
+
+// checkGlossaryTermAvailable rejects a term that another entry already uses
+func checkGlossaryTermAvailable(ctx context.Context, term string, selfID primitive.ObjectID) error {
+	if term == "" {
+		return fmt.Errorf("term is required")
+	}
+	collection := config.GetCollection("glossary")
+	var existing models.GlossaryEntry
+	err := collection.FindOne(ctx, bson.M{"term": term, "_id": bson.M{"$ne": selfID}}).Decode(&existing)
+	if err == nil {
+		return fmt.Errorf("glossary term '%s' already exists", term)
+	} else if err != mongo.ErrNoDocuments {
+		return err
+	}
+	return nil
+}
+
+// cleanSynonyms trims synonyms and drops empty ones and duplicates of the term
+func cleanSynonyms(term string, synonyms []string) []string {
+	seen := map[string]bool{utils.NormalizeText(term): true}
+	cleaned := []string{}
+	for _, s := range synonyms {
+		s = strings.TrimSpace(s)
+		key := utils.NormalizeText(s)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		cleaned = append(cleaned, s)
+	}
+	return cleaned
+}
+
+// containsPhrase reports whether the normalized phrase appears as whole words in normalized text
+func containsPhrase(normalizedText, normalizedPhrase string) bool {
+	if normalizedPhrase == "" {
+		return false
+	}
+	return strings.Contains(" "+normalizedText+" ", " "+normalizedPhrase+" ")
+}
+
+// FindGlossaryMatches returns the glossary entries whose term or a synonym appears in text
+func FindGlossaryMatches(text string) []models.GlossaryEntry {
+	entries, err := cachedGlossary()
+	if err != nil {
+		fmt.Printf("⚠️ Glossary: could not load entries: %v\n", err)
+		return nil
+	}
+
+	normalized := utils.NormalizeText(text)
+	var matches []models.GlossaryEntry
+	for _, entry := range entries {
+		for _, variant := range entry.Variants() {
+			if containsPhrase(normalized, utils.NormalizeText(variant)) {
+				matches = append(matches, entry)
+				break
+			}
+		}
+	}
+	return matches
+}
+
+// ExpandQuery returns the query followed by every synonym of the glossary terms it mentions
+func ExpandQuery(query string) []string {
+	terms := []string{query}
+	seen := map[string]bool{utils.NormalizeText(query): true}
+	for _, entry := range FindGlossaryMatches(query) {
+		for _, variant := range entry.Variants() {
+			key := utils.NormalizeText(variant)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			terms = append(terms, variant)
+		}
+	}
+	return terms
+}
@@ -0,0 +1,395 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"web_AI/config"
+	"web_AI/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrImportNotFound    = errors.New("import not found")
+	ErrInvalidImport     = errors.New("invalid import")
+	ErrStructuringFailed = errors.New("could not convert the document into a procedure")
+)
+
+const (
+	// maxImportText bounds the document text sent to the AI
+	maxImportText  = 12000
+	maxImportSteps = 200 // sub-steps included
+)
+
+// structuredProcedureSchema is the JSON Schema the AI must follow; parseStructuredProcedure enforces it
+const structuredProcedureSchema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["title", "steps"],
+  "properties": {
+    "title": {"type": "string", "minLength": 1, "maxLength": 200},
+    "description": {"type": "string", "maxLength": 1000},
+    "steps": {"type": "array", "minItems": 1, "items": {"$ref": "#/definitions/step"}}
+  },
+  "definitions": {
+    "step": {
+      "type": "object",
+      "additionalProperties": false,
+      "required": ["title"],
+      "properties": {
+        "title": {"type": "string", "minLength": 1},
+        "instructions": {"type": "string"},
+        "responsible_role": {"type": "string"},
+        "expected_duration": {"type": "string"},
+        "required_documents": {"type": "array", "items": {"type": "string"}},
+        "sub_steps": {"type": "array", "items": {"$ref": "#/definitions/step"}}
+      }
+    }
+  }
+}`
+
+// StructureDocument asks the AI to turn the text of a document into a structured procedure.
+// An answer that does not match the schema is sent back once with the validation error.
+func StructureDocument(text string) (*models.StructuredProcedure, error) {
+	prompt := buildStructurePrompt(truncateText(text, maxImportText))
+
+	answer, err := CallMistralAPI(prompt)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStructuringFailed, err)
+	}
+	result, err := parseStructuredProcedure(answer)
+	if err == nil {
+		return result, nil
+	}
+
+	retry := fmt.Sprintf("%s\n\nCâu trả lời trước không hợp lệ (%v). Hãy trả lại JSON đúng schema.", prompt, err)
+	answer, err = CallMistralAPI(retry)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStructuringFailed, err)
+	}
+	result, err = parseStructuredProcedure(answer)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrStructuringFailed, err)
+	}
+	return result, nil
+}
+
+func buildStructurePrompt(text string) string {
+	return fmt.Sprintf(`Chuyển văn bản quy trình sau (trích xuất từ file PDF/Word, có thể lộn xộn) thành JSON có cấu trúc.
+
+YÊU CẦU:
+1. Giữ nguyên ngôn ngữ của văn bản, không thêm thông tin không có trong văn bản
+2. Các bước theo đúng thứ tự thực hiện; bước con đặt trong "sub_steps"
+3. "responsible_role" là vai trò/bộ phận thực hiện bước, "required_documents" là giấy tờ cần có
+4. Chỉ trả về một JSON hợp lệ theo JSON Schema dưới đây, không giải thích thêm
+
+JSON SCHEMA:
+%s
+
+VĂN BẢN:
+%s`, structuredProcedureSchema, text)
+}
+
+// parseStructuredProcedure reads an AI answer and checks it against structuredProcedureSchema
+func parseStructuredProcedure(answer string) (*models.StructuredProcedure, error) {
+	decoder := json.NewDecoder(bytes.NewReader([]byte(extractJSONObject(answer))))
+	decoder.DisallowUnknownFields()
+
+	var result models.StructuredProcedure
+	if err := decoder.Decode(&result); err != nil {
+		return nil, fmt.Errorf("does not match the schema: %v", err)
+	}
+	if err := validateStructuredProcedure(&result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// validateStructuredProcedure enforces the limits of the schema and tidies the text
+func validateStructuredProcedure(result *models.StructuredProcedure) error {
+	result.Title = strings.TrimSpace(result.Title)
+	result.Description = strings.TrimSpace(result.Description)
+	if result.Title == "" {
+		return fmt.Errorf("%w: title is required", ErrInvalidImport)
+	}
+	if utf8.RuneCountInString(result.Title) > 200 {
+		return fmt.Errorf("%w: title is longer than 200 characters", ErrInvalidImport)
+	}
+	if utf8.RuneCountInString(result.Description) > 1000 {
+		return fmt.Errorf("%w: description is longer than 1000 characters", ErrInvalidImport)
+	}
+	if len(result.Steps) == 0 {
+		return fmt.Errorf("%w: at least one step is required", ErrInvalidImport)
+	}
+	if countSteps(result.Steps) > maxImportSteps {
+		return fmt.Errorf("%w: more than %d steps", ErrInvalidImport, maxImportSteps)
+	}
+	if err := validateSteps(result.Steps); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+	tidySteps(result.Steps)
+	return nil
+}
+
+func countSteps(steps []models.ProcedureStep) int {
+	count := len(steps)
+	for _, step := range steps {
+		count += countSteps(step.SubSteps)
+	}
+	return count
+}
+
+// tidySteps trims the optional step fields and drops empty documents
+func tidySteps(steps []models.ProcedureStep) {
+	for i := range steps {
+		steps[i].Instructions = strings.TrimSpace(steps[i].Instructions)
+		steps[i].ResponsibleRole = strings.TrimSpace(steps[i].ResponsibleRole)
+		steps[i].ExpectedDuration = strings.TrimSpace(steps[i].ExpectedDuration)
+		var documents []string
+		for _, doc := range steps[i].RequiredDocuments {
+			if doc = strings.TrimSpace(doc); doc != "" {
+				documents = append(documents, doc)
+			}
+		}
+		steps[i].RequiredDocuments = documents
+		tidySteps(steps[i].SubSteps)
+	}
+}
+
+// collectRolesAndDocuments lists the distinct roles and documents of the steps in order of appearance
+func collectRolesAndDocuments(steps []models.ProcedureStep, roles []string, documents []string) ([]string, []string) {
+	for _, step := range steps {
+		if step.ResponsibleRole != "" && !containsString(roles, step.ResponsibleRole) {
+			roles = append(roles, step.ResponsibleRole)
+		}
+		for _, doc := range step.RequiredDocuments {
+			if !containsString(documents, doc) {
+				documents = append(documents, doc)
+			}
+		}
+		roles, documents = collectRolesAndDocuments(step.SubSteps, roles, documents)
+	}
+	return roles, documents
+}
+
+// CreateProcedureImport converts the extracted text of an upload and stores the result for review
+func CreateProcedureImport(imp *models.ProcedureImport) error {
+	collection := config.GetCollection("procedure_imports")
+
+	result, err := StructureDocument(imp.ExtractedText)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	imp.ID = primitive.NewObjectID()
+	imp.Status = models.ImportStatusPending
+	imp.Truncated = len(imp.ExtractedText) > maxImportText
+	imp.Result = *result
+	imp.ResponsibleRoles, imp.RequiredDocuments = collectRolesAndDocuments(result.Steps, nil, nil)
+	imp.CreatedAt = time.Now()
+
+	_, err = collection.InsertOne(ctx, imp)
+	return err
+}
+
+// GetProcedureImports lists imports, newest first, without their extracted text
+func GetProcedureImports(status string) ([]models.ProcedureImport, error) {
+	collection := config.GetCollection("procedure_imports")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{}
+	if status != "" {
+		filter["status"] = status
+	}
+	opts := options.Find().
+		SetSort(bson.D{bson.E{Key: "created_at", Value: -1}}).
+		SetProjection(bson.M{"extracted_text": 0}).
+		SetLimit(200)
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	imports := []models.ProcedureImport{}
+	if err = cursor.All(ctx, &imports); err != nil {
+		return nil, err
+	}
+	return imports, nil
+}
+
+// GetProcedureImport returns an import with its extracted text, to compare with the result
+func GetProcedureImport(id string) (*models.ProcedureImport, error) {
+	collection := config.GetCollection("procedure_imports")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrImportNotFound
+	}
+
+	var imp models.ProcedureImport
+	err = collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&imp)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrImportNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &imp, nil
+}
+
+// PrepareImportedProcedure applies the admin's corrections to a pending import and returns the
+// procedure it would create or update, without saving anything
+func PrepareImportedProcedure(id string, req models.AcceptImportRequest, actorID string) (*models.ProcedureImport, *models.Procedure, error) {
+	imp, err := GetProcedureImport(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if imp.Status != models.ImportStatusPending {
+		return nil, nil, fmt.Errorf("%w: the import is already %s", ErrInvalidImport, imp.Status)
+	}
+
+	result := imp.Result
+	if req.Title != "" {
+		result.Title = req.Title
+	} else if imp.Title != "" {
+		result.Title = imp.Title
+	}
+	if req.Description != nil {
+		result.Description = *req.Description
+	}
+	if req.Steps != nil {
+		result.Steps = req.Steps
+	}
+	if err := validateStructuredProcedure(&result); err != nil {
+		return nil, nil, err
+	}
+
+	procedure := &models.Procedure{
+		Title:       result.Title,
+		Description: result.Description,
+		Steps:       result.Steps,
+		Category:    imp.Category,
+		CategoryID:  imp.CategoryID,
+		FileKey:     imp.StorageName,
+		FileName:    imp.FileName,
+		UpdatedBy:   actorID,
+	}
+	if req.Category != "" || req.CategoryID != "" {
+		procedure.Category = req.Category
+		procedure.CategoryID = primitive.NilObjectID
+		if req.CategoryID != "" {
+			if procedure.CategoryID, err = primitive.ObjectIDFromHex(req.CategoryID); err != nil {
+				return nil, nil, fmt.Errorf("%w: invalid category ID", ErrInvalidImport)
+			}
+		}
+	}
+	if imp.ProcedureID != nil {
+		procedure.ID = *imp.ProcedureID
+	}
+	return imp, procedure, nil
+}
+
+// AcceptProcedureImport creates the procedure prepared by PrepareImportedProcedure, or updates the
+// procedure the file was uploaded for, and marks the import accepted
+func AcceptProcedureImport(imp *models.ProcedureImport, procedure *models.Procedure, actorID string) (*models.Procedure, error) {
+	collection := config.GetCollection("procedure_imports")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Claim the import first so a double submit cannot create the procedure twice
+	now := time.Now()
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": imp.ID, "status": models.ImportStatusPending},
+		bson.M{"$set": bson.M{"status": models.ImportStatusAccepted, "reviewed_by": actorID, "reviewed_at": now}},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		return nil, fmt.Errorf("%w: the import is no longer pending", ErrInvalidImport)
+	}
+
+	if imp.ProcedureID != nil {
+		procedure.Description = keepDescription(procedure, imp.ProcedureID.Hex())
+		err = UpdateProcedureFromUpload(imp.ProcedureID.Hex(), procedure)
+		if err == nil {
+			procedure, err = GetProcedureByID(imp.ProcedureID.Hex())
+		}
+	} else {
+		procedure.CreatedBy, _ = primitive.ObjectIDFromHex(actorID)
+		procedure.OwnerID = actorID
+		err = CreateProcedure(procedure)
+	}
+	if err != nil {
+		// Put the import back so it can be corrected and accepted again
+		_, revertErr := collection.UpdateOne(ctx, bson.M{"_id": imp.ID}, bson.M{
+			"$set":   bson.M{"status": models.ImportStatusPending},
+			"$unset": bson.M{"reviewed_by": "", "reviewed_at": ""},
+		})
+		if revertErr != nil {
+			fmt.Printf("⚠️ Failed to reopen import %s: %v\n", imp.ID.Hex(), revertErr)
+		}
+		return nil, err
+	}
+
+	if _, err := collection.UpdateOne(ctx, bson.M{"_id": imp.ID}, bson.M{"$set": bson.M{"procedure_id": procedure.ID}}); err != nil {
+		fmt.Printf("⚠️ Failed to link import %s to procedure %s: %v\n", imp.ID.Hex(), procedure.ID.Hex(), err)
+	}
+	return procedure, nil
+}
+
+// keepDescription keeps a hand-written description of an existing procedure when the import has none
+func keepDescription(procedure *models.Procedure, existingID string) string {
+	if procedure.Description != "" {
+		return procedure.Description
+	}
+	existing, err := GetProcedureByID(existingID)
+	if err != nil {
+		return ""
+	}
+	return existing.Description
+}
+
+// RejectProcedureImport discards a pending import
+func RejectProcedureImport(id string, actorID string) (*models.ProcedureImport, error) {
+	collection := config.GetCollection("procedure_imports")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	imp, err := GetProcedureImport(id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": imp.ID, "status": models.ImportStatusPending},
+		bson.M{"$set": bson.M{"status": models.ImportStatusRejected, "reviewed_by": actorID, "reviewed_at": now}},
+	)
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		return nil, fmt.Errorf("%w: the import is already %s", ErrInvalidImport, imp.Status)
+	}
+
+	imp.Status = models.ImportStatusRejected
+	imp.ReviewedBy = actorID
+	imp.ReviewedAt = &now
+	return imp, nil
+}
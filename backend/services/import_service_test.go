@@ -0,0 +1,61 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseStructuredProcedure(t *testing.T) {
+	tests := []struct {
+		name    string
+		answer  string
+		title   string
+		steps   int
+		invalid bool // fails validation rather than decoding
+		wantErr bool
+	}{
+		{name: "valid", answer: `{"title":"Nghỉ phép","steps":[{"title":"Gửi đơn"},{"title":"Duyệt đơn"}]}`, title: "Nghỉ phép", steps: 2},
+		{name: "text around the JSON", answer: "Đây là kết quả:\n```json\n{\"title\": \" Nghỉ phép \", \"steps\": [{\"title\": \"Gửi đơn\"}]}\n```", title: "Nghỉ phép", steps: 1},
+		{name: "sub-steps", answer: `{"title":"Mua hàng","steps":[{"title":"Đề xuất","sub_steps":[{"title":"Báo giá"}]}]}`, title: "Mua hàng", steps: 1},
+		{name: "not JSON", answer: "Xin lỗi, tôi không thể xử lý văn bản này.", wantErr: true},
+		{name: "unknown field", answer: `{"title":"Nghỉ phép","steps":[{"title":"Gửi đơn"}],"notes":"x"}`, wantErr: true},
+		{name: "unknown step field", answer: `{"title":"Nghỉ phép","steps":[{"title":"Gửi đơn","owner":"HR"}]}`, wantErr: true},
+		{name: "missing title", answer: `{"title":"  ","steps":[{"title":"Gửi đơn"}]}`, invalid: true, wantErr: true},
+		{name: "long title", answer: `{"title":"` + strings.Repeat("đ", 201) + `","steps":[{"title":"Gửi đơn"}]}`, invalid: true, wantErr: true},
+		{name: "no steps", answer: `{"title":"Nghỉ phép","steps":[]}`, invalid: true, wantErr: true},
+		{name: "step without title", answer: `{"title":"Nghỉ phép","steps":[{"instructions":"Gửi đơn"}]}`, invalid: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseStructuredProcedure(tt.answer)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("parseStructuredProcedure succeeded, want an error")
+				}
+				if errors.Is(err, ErrInvalidImport) != tt.invalid {
+					t.Errorf("error = %v, ErrInvalidImport %v", err, tt.invalid)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Title != tt.title || len(result.Steps) != tt.steps {
+				t.Errorf("result = %q with %d steps, want %q with %d", result.Title, len(result.Steps), tt.title, tt.steps)
+			}
+		})
+	}
+}
+
+func TestParseStructuredProcedureTidiesSteps(t *testing.T) {
+	answer := `{"title":"Nghỉ phép","steps":[{"title":" Gửi đơn ","responsible_role":" Nhân viên ","required_documents":["Đơn xin nghỉ", " ", " CCCD "]}]}`
+	result, err := parseStructuredProcedure(answer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	step := result.Steps[0]
+	if step.Title != "Gửi đơn" || step.ResponsibleRole != "Nhân viên" || strings.Join(step.RequiredDocuments, "|") != "Đơn xin nghỉ|CCCD" {
+		t.Errorf("step = %+v", step)
+	}
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"web_AI/config"
+	"web_AI/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CreateNotification stores an in-app notification for a user
+func CreateNotification(notification *models.Notification) error {
+	collection := config.GetCollection("notifications")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	notification.ID = primitive.NewObjectID()
+	notification.Read = false
+	notification.CreatedAt = time.Now()
+
+	_, err := collection.InsertOne(ctx, notification)
+	return err
+}
+
+// GetNotifications lists a user's notifications, newest first, with the unread count
+func GetNotifications(userID string, unreadOnly bool) ([]models.Notification, int64, error) {
+	collection := config.GetCollection("notifications")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{"user_id": userID}
+	if unreadOnly {
+		filter["read"] = false
+	}
+
+	opts := options.Find().SetSort(bson.D{bson.E{Key: "created_at", Value: -1}}).SetLimit(100)
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	notifications := []models.Notification{}
+	if err = cursor.All(ctx, &notifications); err != nil {
+		return nil, 0, err
+	}
+
+	unread, err := collection.CountDocuments(ctx, bson.M{"user_id": userID, "read": false})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return notifications, unread, nil
+}
+
+// MarkNotificationRead marks one of the user's notifications as read
+func MarkNotificationRead(id string, userID string) error {
+	collection := config.GetCollection("notifications")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("invalid notification ID")
+	}
+
+	result, err := collection.UpdateOne(ctx,
+		bson.M{"_id": objID, "user_id": userID},
+		bson.M{"$set": bson.M{"read": true, "read_at": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("notification not found")
+	}
+
+	return nil
+}
@@ -2,32 +2,79 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"regexp"
+	"strings"
 	"time"
 	"web_AI/config"
 	"web_AI/models"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-// GetProcedures retrieves all procedures with optional filtering
-func GetProcedures(category string, limit int64) ([]models.Procedure, error) {
+var (
+	// ErrProcedureNotFound is returned when a procedure does not exist or is not visible to the caller
+	ErrProcedureNotFound = errors.New("procedure not found")
+	// ErrVersionConflict is returned when a procedure was changed since the version the caller edited
+	ErrVersionConflict = errors.New("procedure was modified by someone else")
+)
+
+// versionMatch builds a filter value for an expected version; version 0 also matches
+// procedures saved before versioning existed
+func versionMatch(version int64) interface{} {
+	if version == 0 {
+		return bson.M{"$in": bson.A{0, nil}}
+	}
+	return version
+}
+
+// publishedOnly restricts a filter to procedures visible to the public: published ones
+// (or legacy ones without a status) whose scheduled publish date has passed and that are not in the trash
+func publishedOnly(filter bson.M) bson.M {
+	published := bson.M{
+		"deleted_at": nil,
+		"status":     bson.M{"$in": bson.A{models.ProcedureStatusPublished, nil}},
+		"$or": []bson.M{
+			{"publish_at": nil},
+			{"publish_at": bson.M{"$lte": time.Now()}},
+		},
+	}
+	if len(filter) == 0 {
+		return published
+	}
+	return bson.M{"$and": []bson.M{filter, published}}
+}
+
+// notDeleted restricts a filter to documents that are not in the trash
+func notDeleted(filter bson.M) bson.M {
+	if len(filter) == 0 {
+		return bson.M{"deleted_at": nil}
+	}
+	return bson.M{"$and": []bson.M{filter, {"deleted_at": nil}}}
+}
+
+// GetProcedures retrieves published procedures visible to query.Viewer, optionally filtered by
+// category (by ID or name, including its sub-categories) and tags
+func GetProcedures(query models.ProcedureQuery) ([]models.Procedure, error) {
 	collection := config.GetCollection("procedures")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	filter := bson.M{}
-	if category != "" {
-		filter["category"] = category
+	filter, err := procedureQueryFilter(ctx, query)
+	if err != nil {
+		return nil, err
 	}
+	filter = visibleTo(filter, query.Viewer)
 
 	opts := options.Find()
-	if limit > 0 {
-		opts.SetLimit(limit)
+	if query.Limit > 0 {
+		opts.SetLimit(query.Limit)
 	}
-	opts.SetSort(bson.D{bson.E{Key: "createdAt", Value: -1}})
+	opts.SetSort(bson.D{bson.E{Key: "created_at", Value: -1}})
 
 	cursor, err := collection.Find(ctx, filter, opts)
 	if err != nil {
@@ -43,8 +90,77 @@ func GetProcedures(category string, limit int64) ([]models.Procedure, error) {
 	return procedures, nil
 }
 
-// GetProcedureByID retrieves a single procedure by ID
+// procedureQueryFilter builds the category and tag part of a procedure filter
+func procedureQueryFilter(ctx context.Context, query models.ProcedureQuery) (bson.M, error) {
+	var conditions []bson.M
+	if query.Category != "" {
+		filter, err := categoryFilter(ctx, query.Category)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, filter)
+	}
+	if tags := NormalizeTags(query.Tags); len(tags) > 0 {
+		conditions = append(conditions, tagFilter(tags, query.TagMode))
+	}
+
+	switch len(conditions) {
+	case 0:
+		return bson.M{}, nil
+	case 1:
+		return conditions[0], nil
+	default:
+		return bson.M{"$and": conditions}, nil
+	}
+}
+
+// GetAdminProcedures retrieves procedures in any workflow state, optionally filtered by status and reviewer
+func GetAdminProcedures(status string, reviewerID string) ([]models.Procedure, error) {
+	collection := config.GetCollection("procedures")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	filter := bson.M{}
+	switch status {
+	case "":
+	case models.ProcedureStatusPublished:
+		filter["status"] = bson.M{"$in": bson.A{models.ProcedureStatusPublished, nil}}
+	default:
+		filter["status"] = status
+	}
+	if reviewerID != "" {
+		filter["reviewer_id"] = reviewerID
+	}
+
+	opts := options.Find().SetSort(bson.D{bson.E{Key: "updated_at", Value: -1}})
+	cursor, err := collection.Find(ctx, notDeleted(filter), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var procedures []models.Procedure
+	if err = cursor.All(ctx, &procedures); err != nil {
+		return nil, err
+	}
+
+	return procedures, nil
+}
+
+// GetProcedureByID retrieves a single procedure by ID regardless of its workflow state or visibility,
+// unless it is in the trash
 func GetProcedureByID(id string) (*models.Procedure, error) {
+	return findProcedure(id, nil)
+}
+
+// GetPublishedProcedureByID retrieves a single published procedure by ID if the viewer may see it.
+// Procedures the viewer may not see are reported as not found.
+func GetPublishedProcedureByID(id string, viewer models.Viewer) (*models.Procedure, error) {
+	return findProcedure(id, &viewer)
+}
+
+// findProcedure loads a procedure; with a viewer only published procedures visible to them are found
+func findProcedure(id string, viewer *models.Viewer) (*models.Procedure, error) {
 	collection := config.GetCollection("procedures")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -54,9 +170,18 @@ func GetProcedureByID(id string) (*models.Procedure, error) {
 		return nil, fmt.Errorf("invalid procedure ID")
 	}
 
+	filter := bson.M{"_id": objID}
+	if viewer != nil {
+		filter = visibleTo(filter, *viewer)
+	} else {
+		filter = notDeleted(filter)
+	}
+
 	var procedure models.Procedure
-	err = collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&procedure)
-	if err != nil {
+	err = collection.FindOne(ctx, filter).Decode(&procedure)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrProcedureNotFound
+	} else if err != nil {
 		return nil, err
 	}
 
@@ -69,16 +194,129 @@ func CreateProcedure(procedure *models.Procedure) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	if err := prepareProcedureSteps(procedure); err != nil {
+		return err
+	}
+	if err := resolveProcedureCategory(ctx, procedure); err != nil {
+		return err
+	}
+	if err := validateReviewSchedule(procedure); err != nil {
+		return err
+	}
+	if procedure.ReviewerID != "" && procedure.ReviewerID == procedure.CreatedBy.Hex() {
+		return ErrAuthorAsReviewer
+	}
+	if err := normalizeAccess(&procedure.ProcedureAccess); err != nil {
+		return err
+	}
+	if procedure.SourceLanguage != "" {
+		if procedure.SourceLanguage = NormalizeLanguage(procedure.SourceLanguage); procedure.SourceLanguage == "" {
+			return fmt.Errorf("%w: supported languages are %s", ErrInvalidLanguage, strings.Join(models.SupportedLanguages, ", "))
+		}
+	}
+
+	procedure.Tags = NormalizeTags(procedure.Tags)
+	procedure.DuplicateSignature = storedSignature(minhashSignature(procedure))
 	procedure.ID = primitive.NewObjectID()
+	if procedure.FileKey != "" {
+		procedure.FileURL = ProcedureFileURL(procedure.ID)
+	}
+	procedure.Version = 1
 	procedure.CreatedAt = time.Now()
 	procedure.UpdatedAt = time.Now()
+	if procedure.Status == "" {
+		procedure.Status = models.ProcedureStatusDraft
+	}
 
 	_, err := collection.InsertOne(ctx, procedure)
-	return err
+	if err != nil {
+		return err
+	}
+
+	if err := recordRevision(ctx, procedure, models.RevisionActionCreate, procedure.UpdatedBy, 0); err != nil {
+		fmt.Printf("⚠️ Failed to record revision for procedure %s: %v\n", procedure.ID.Hex(), err)
+	}
+
+	InvalidateSuggestIndex()
+	EmitWebhookEvent(models.WebhookEventProcedureCreated, procedureWebhookData(procedure, models.RevisionActionCreate, procedure.UpdatedBy))
+	return nil
+}
+
+// UpdateProcedure updates an existing procedure. When expectedVersion is set the update only
+// succeeds if nobody changed the procedure since that version.
+func UpdateProcedure(id string, procedure *models.Procedure, expectedVersion *int64) error {
+	return updateProcedure(id, procedure, models.RevisionActionUpdate, 0, expectedVersion)
+}
+
+// UpdateProcedureFromUpload replaces a procedure's content with a re-uploaded file
+func UpdateProcedureFromUpload(id string, procedure *models.Procedure) error {
+	return updateProcedure(id, procedure, models.RevisionActionUpload, 0, nil)
+}
+
+// PatchProcedure changes only the fields sent in the request, failing with ErrVersionConflict
+// if the procedure changed since expectedVersion (or since it was read, when no version is given)
+func PatchProcedure(id string, req models.PatchProcedureRequest, actorID string, expectedVersion *int64) (*models.Procedure, error) {
+	existing, err := GetProcedureByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if expectedVersion != nil && *expectedVersion != existing.Version {
+		return nil, ErrVersionConflict
+	}
+
+	procedure := &models.Procedure{
+		Title:       existing.Title,
+		Content:     existing.Content,
+		Category:    existing.Category,
+		CategoryID:  existing.CategoryID,
+		Description: existing.Description,
+		UpdatedBy:   actorID,
+	}
+	if req.Title != nil {
+		procedure.Title = *req.Title
+	}
+	if req.Description != nil {
+		procedure.Description = *req.Description
+	}
+	if req.CategoryID != nil {
+		procedure.CategoryID, err = primitive.ObjectIDFromHex(*req.CategoryID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid category ID", ErrInvalidCategory)
+		}
+		procedure.Category = ""
+	} else if req.Category != nil {
+		procedure.Category = *req.Category
+		procedure.CategoryID = primitive.NilObjectID
+	}
+	if req.Tags != nil {
+		procedure.Tags = *req.Tags
+		if procedure.Tags == nil {
+			procedure.Tags = []string{}
+		}
+	}
+	if req.Steps != nil {
+		procedure.Steps = *req.Steps
+		if procedure.Steps == nil {
+			procedure.Steps = []models.ProcedureStep{}
+		}
+		// Content generated from the old steps follows the new ones unless new content was sent
+		if req.Content == nil && contentFromSteps(existing) {
+			procedure.Content = ""
+		}
+	}
+	if req.Content != nil {
+		procedure.Content = *req.Content
+	}
+
+	version := existing.Version
+	if err := updateProcedure(id, procedure, models.RevisionActionUpdate, 0, &version); err != nil {
+		return nil, err
+	}
+	return GetProcedureByID(id)
 }
 
-// UpdateProcedure updates an existing procedure
-func UpdateProcedure(id string, procedure *models.Procedure) error {
+// updateProcedure overwrites the editable fields and records the new state as a revision
+func updateProcedure(id string, procedure *models.Procedure, action string, restoredFrom int, expectedVersion *int64) error {
 	collection := config.GetCollection("procedures")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -88,23 +326,103 @@ func UpdateProcedure(id string, procedure *models.Procedure) error {
 		return fmt.Errorf("invalid procedure ID")
 	}
 
+	if err := prepareProcedureSteps(procedure); err != nil {
+		return err
+	}
+
+	var current models.Procedure
+	projection := bson.M{"status": 1, "category": 1, "category_id": 1}
+	err = collection.FindOne(ctx, bson.M{"_id": objID, "deleted_at": nil}, options.FindOne().SetProjection(projection)).Decode(&current)
+	if err == mongo.ErrNoDocuments {
+		return ErrProcedureNotFound
+	} else if err != nil {
+		return err
+	}
+
+	err = resolveProcedureCategory(ctx, procedure)
+	// Procedures saved before categories were linked by ID keep their category name until
+	// "migrate categories" creates the missing categories
+	legacyCategory := current.CategoryID.IsZero() && procedure.CategoryID.IsZero() &&
+		strings.TrimSpace(procedure.Category) == current.Category
+	if err != nil && !(errors.Is(err, ErrCategoryNotFound) && legacyCategory) {
+		return err
+	}
+	if err := ensureBaselineRevision(ctx, objID); err != nil {
+		return err
+	}
+
 	procedure.UpdatedAt = time.Now()
-	update := bson.M{
-		"$set": bson.M{
-			"title":       procedure.Title,
-			"content":     procedure.Content,
-			"category":    procedure.Category,
-			"description": procedure.Description,
-			"updatedAt":   procedure.UpdatedAt,
-		},
+	set := bson.M{
+		"title":       procedure.Title,
+		"content":     procedure.Content,
+		"category":    procedure.Category,
+		"category_id": procedure.CategoryID,
+		"description": procedure.Description,
+		"updated_at":  procedure.UpdatedAt,
+		"updated_by":  procedure.UpdatedBy,
+	}
+	// Without content the signature comes from the stored steps; dropping it has the next duplicate check compute it
+	unset := bson.M{}
+	if procedure.Steps != nil || strings.TrimSpace(procedure.Content) != "" {
+		set["duplicate_signature"] = storedSignature(minhashSignature(procedure))
+	} else {
+		unset["duplicate_signature"] = ""
+	}
+	// A legacy category stays unlinked so the migration still finds it
+	if procedure.CategoryID.IsZero() {
+		delete(set, "category_id")
+	}
+	// nil steps means "unchanged" so clients that only know about content keep working
+	if procedure.Steps != nil {
+		set["steps"] = procedure.Steps
+	}
+	if procedure.Tags != nil {
+		set["tags"] = NormalizeTags(procedure.Tags)
+	}
+	// The original file only changes with a new upload
+	if procedure.FileKey != "" {
+		set["file_key"] = procedure.FileKey
+		set["file_name"] = procedure.FileName
+		set["file_url"] = ProcedureFileURL(objID)
+	}
+	// An approval covers the content that was reviewed, so editing during review starts it over
+	if current.Status == models.ProcedureStatusInReview {
+		set["status"] = models.ProcedureStatusDraft
+	}
+	update := bson.M{"$set": set, "$inc": bson.M{"version": 1}}
+	if len(unset) > 0 {
+		update["$unset"] = unset
 	}
 
-	_, err = collection.UpdateOne(ctx, bson.M{"_id": objID}, update)
-	return err
+	filter := bson.M{"_id": objID, "status": statusMatch(current.Status), "deleted_at": nil}
+	if expectedVersion != nil {
+		filter["version"] = versionMatch(*expectedVersion)
+	}
+
+	var updated models.Procedure
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		// The procedure still exists, so its version or status changed since it was read
+		if _, findErr := GetProcedureByID(id); findErr == nil {
+			return ErrVersionConflict
+		}
+		return ErrProcedureNotFound
+	} else if err != nil {
+		return err
+	}
+
+	if err := recordRevision(ctx, &updated, action, procedure.UpdatedBy, restoredFrom); err != nil {
+		fmt.Printf("⚠️ Failed to record revision for procedure %s: %v\n", id, err)
+	}
+
+	InvalidateSuggestIndex()
+	EmitWebhookEvent(models.WebhookEventProcedureUpdated, procedureWebhookData(&updated, action, procedure.UpdatedBy))
+	return nil
 }
 
-// DeleteProcedure deletes a procedure by ID
-func DeleteProcedure(id string) error {
+// DeleteProcedure moves a procedure to the trash; it can be restored until the trash is purged
+func DeleteProcedure(id string, actorID string) error {
 	collection := config.GetCollection("procedures")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -114,33 +432,70 @@ func DeleteProcedure(id string) error {
 		return fmt.Errorf("invalid procedure ID")
 	}
 
-	result, err := collection.DeleteOne(ctx, bson.M{"_id": objID})
-	if err != nil {
+	update := bson.M{"$set": bson.M{"deleted_at": time.Now(), "deleted_by": actorID}, "$inc": bson.M{"version": 1}}
+	var deleted models.Procedure
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = collection.FindOneAndUpdate(ctx, bson.M{"_id": objID, "deleted_at": nil}, update, opts).Decode(&deleted)
+	if err == mongo.ErrNoDocuments {
+		return ErrProcedureNotFound
+	} else if err != nil {
 		return err
 	}
 
-	if result.DeletedCount == 0 {
-		return fmt.Errorf("procedure not found")
-	}
-
+	InvalidateSuggestIndex()
+	EmitWebhookEvent(models.WebhookEventProcedureDeleted, procedureWebhookData(&deleted, "delete", actorID))
 	return nil
 }
 
-// SearchProcedures searches procedures by title and content
-func SearchProcedures(query string) ([]models.Procedure, error) {
+// SearchProcedures searches published procedures visible to query.Viewer by title, content, description and tags,
+// expanding glossary synonyms and acronyms; category and tag filters narrow the results
+func SearchProcedures(query models.ProcedureQuery) ([]models.Procedure, error) {
 	collection := config.GetCollection("procedures")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	filter := bson.M{
-		"$or": []bson.M{
-			{"title": bson.M{"$regex": query, "$options": "i"}},
-			{"content": bson.M{"$regex": query, "$options": "i"}},
-			{"description": bson.M{"$regex": query, "$options": "i"}},
-		},
+	var conditions []bson.M
+	for _, term := range ExpandQuery(query.Search) {
+		pattern := regexp.QuoteMeta(term)
+		conditions = append(conditions,
+			bson.M{"title": bson.M{"$regex": pattern, "$options": "i"}},
+			bson.M{"content": bson.M{"$regex": pattern, "$options": "i"}},
+			bson.M{"description": bson.M{"$regex": pattern, "$options": "i"}},
+			bson.M{"tags": bson.M{"$regex": pattern, "$options": "i"}},
+		)
+		// Approved AI summaries and FAQs are searched as well, as are approved translations in the reader's language
+		for _, field := range []string{"summary", "faq.question", "faq.answer"} {
+			conditions = append(conditions, bson.M{
+				"enrichment.status":   models.EnrichmentStatusApproved,
+				"enrichment." + field: bson.M{"$regex": pattern, "$options": "i"},
+			})
+		}
+		if lang := NormalizeLanguage(query.Language); lang != "" {
+			prefix := "translations." + lang + "."
+			for _, field := range []string{"title", "content", "description"} {
+				conditions = append(conditions, bson.M{
+					prefix + "status": models.TranslationStatusApproved,
+					prefix + field:    bson.M{"$regex": pattern, "$options": "i"},
+				})
+			}
+		}
 	}
+	filter := bson.M{"$or": conditions}
 
-	cursor, err := collection.Find(ctx, filter)
+	extra, err := procedureQueryFilter(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if len(extra) > 0 {
+		filter = bson.M{"$and": []bson.M{filter, extra}}
+	}
+	filter = visibleTo(filter, query.Viewer)
+
+	opts := options.Find()
+	if query.Limit > 0 {
+		opts.SetLimit(query.Limit)
+	}
+	cursor, err := collection.Find(ctx, filter, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -154,13 +509,18 @@ func SearchProcedures(query string) ([]models.Procedure, error) {
 	return procedures, nil
 }
 
-// GetProceduresByCategory retrieves procedures by category
-func GetProceduresByCategory(category string) ([]models.Procedure, error) {
+// GetProceduresByCategory retrieves published procedures visible to the viewer in a category
+// (by ID or name) and its sub-categories
+func GetProceduresByCategory(category string, viewer models.Viewer) ([]models.Procedure, error) {
 	collection := config.GetCollection("procedures")
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	filter := bson.M{"category": category}
+	filter, err := categoryFilter(ctx, category)
+	if err != nil {
+		return nil, err
+	}
+	filter = visibleTo(filter, viewer)
 	cursor, err := collection.Find(ctx, filter)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,493 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"web_AI/config"
+	"web_AI/models"
+	"web_AI/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var ErrQuestionNotFound = errors.New("question not found")
+
+const (
+	// lowRetrievalScore marks answers whose best procedure covers less than this share of the question
+	lowRetrievalScore = 0.3
+	// clusterSimilarity is the keyword overlap (Jaccard) for two questions to be about the same topic
+	clusterSimilarity = 0.5
+	// minSuggestionDemand is how often a topic must go unanswered before a new procedure is suggested
+	minSuggestionDemand = 2
+	// maxClusterWordings caps how many distinct wordings are clustered, most frequent first
+	maxClusterWordings = 2000
+)
+
+// questionRetentionDays returns how long question logs and search misses are kept (QUESTION_RETENTION_DAYS, default 180)
+func questionRetentionDays() int {
+	if days, err := strconv.Atoi(os.Getenv("QUESTION_RETENTION_DAYS")); err == nil && days > 0 {
+		return days
+	}
+	return 180
+}
+
+// EnsureQuestionIndexes creates the TTL indexes that expire question logs and search misses;
+// they also serve the created_at range of every analytics query
+func EnsureQuestionIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var errs []error
+	for _, name := range []string{"question_logs", "search_misses"} {
+		if err := ensureTTLIndex(ctx, config.GetCollection(name), "created_at", questionRetentionDays()); err != nil {
+			errs = append(errs, fmt.Errorf("%s TTL index: %v", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// stopWords are frequent Vietnamese and English words that say nothing about the topic of a question
+var stopWords = map[string]bool{
+	"la": true, "gi": true, "nao": true, "nhu": true, "cach": true, "lam": true, "toi": true, "co": true,
+	"khong": true, "duoc": true, "can": true, "cho": true, "cua": true, "va": true, "thi": true, "o": true,
+	"de": true, "ve": true, "mot": true, "cac": true, "nhung": true, "nhieu": true, "dau": true,
+	"a": true, "an": true, "and": true, "how": true, "what": true, "is": true, "are": true, "do": true,
+	"i": true, "to": true, "of": true, "for": true, "in": true, "my": true, "where": true, "the": true,
+}
+
+// keywords returns the distinct topic words of a text
+func keywords(text string) []string {
+	var words []string
+	for _, tok := range utils.Tokenize(text) {
+		if len(tok) < 2 || stopWords[tok] || containsString(words, tok) {
+			continue
+		}
+		words = append(words, tok)
+	}
+	return words
+}
+
+// retrievalScore returns the share of the question's keywords that appear in the procedure
+func retrievalScore(question string, procedure *models.Procedure) float64 {
+	words := keywords(question)
+	if len(words) == 0 {
+		return 0
+	}
+	text := " " + utils.NormalizeText(strings.Join([]string{
+		procedure.Title, procedure.Description, procedure.Content, RenderStepsContent(procedure.Steps), strings.Join(procedure.Tags, " "),
+	}, " ")) + " "
+
+	found := 0
+	for _, w := range words {
+		if strings.Contains(text, " "+w+" ") {
+			found++
+		}
+	}
+	return float64(found) / float64(len(words))
+}
+
+// unansweredExpr is an aggregation expression that is true when retrieval found nothing useful for a
+// logged question or the asker disliked the answer. Questions about one procedure always have it as context.
+var unansweredExpr = bson.M{"$cond": bson.A{
+	bson.M{"$eq": bson.A{"$source", models.QuestionSourceProcedureChat}},
+	notHelpfulExpr,
+	bson.M{"$or": bson.A{
+		bson.M{"$eq": bson.A{"$hits", 0}},
+		bson.M{"$lt": bson.A{"$top_score", lowRetrievalScore}},
+		notHelpfulExpr,
+	}},
+}}
+
+var notHelpfulExpr = bson.M{"$eq": bson.A{"$helpful", false}}
+
+// countIf sums 1 for every grouped document where cond holds
+func countIf(cond interface{}) bson.M {
+	return bson.M{"$sum": bson.M{"$cond": bson.A{cond, 1, 0}}}
+}
+
+// LogQuestion stores a chat question and its retrieval outcome
+func LogQuestion(log *models.QuestionLog) error {
+	collection := config.GetCollection("question_logs")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	log.ID = primitive.NewObjectID()
+	log.Question = strings.TrimSpace(log.Question)
+	log.Normalized = utils.NormalizeText(log.Question)
+	log.CreatedAt = time.Now()
+
+	_, err := collection.InsertOne(ctx, log)
+	return err
+}
+
+// RecordSearchMiss stores a search query that found nothing, in the background
+func RecordSearchMiss(query string, userID string) {
+	miss := models.SearchMiss{
+		ID:         primitive.NewObjectID(),
+		Query:      truncateText(strings.TrimSpace(query), 200),
+		Normalized: utils.NormalizeText(query),
+		UserID:     userID,
+		CreatedAt:  time.Now(),
+	}
+	if miss.Normalized == "" {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if _, err := config.GetCollection("search_misses").InsertOne(ctx, miss); err != nil {
+			fmt.Printf("⚠️ Failed to record search miss: %v\n", err)
+		}
+	}()
+}
+
+// SubmitQuestionFeedback records whether an answer helped; only the asker can rate their own question
+func SubmitQuestionFeedback(id string, userID string, helpful bool, comment string) error {
+	collection := config.GetCollection("question_logs")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrQuestionNotFound
+	}
+
+	filter := bson.M{"_id": objID}
+	if userID != "" {
+		filter["user_id"] = userID
+	} else {
+		filter["user_id"] = bson.M{"$in": bson.A{"", nil}}
+	}
+
+	now := time.Now()
+	var log models.QuestionLog
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = collection.FindOneAndUpdate(ctx, filter, bson.M{"$set": bson.M{
+		"helpful":          helpful,
+		"feedback_comment": truncateText(strings.TrimSpace(comment), maxCommentLength),
+		"feedback_at":      now,
+	}}, opts).Decode(&log)
+	if err == mongo.ErrNoDocuments {
+		return ErrQuestionNotFound
+	} else if err != nil {
+		return err
+	}
+
+	if !helpful {
+		escalation := models.WebhookChatEscalation{
+			QuestionID:   log.ID,
+			Question:     log.Question,
+			UserID:       log.UserID,
+			Comment:      log.FeedbackComment,
+			Source:       log.Source,
+			ProcedureIDs: log.RetrievedIDs,
+		}
+		if log.ProcedureID != nil && len(escalation.ProcedureIDs) == 0 {
+			escalation.ProcedureIDs = []primitive.ObjectID{*log.ProcedureID}
+		}
+		EmitWebhookEvent(models.WebhookEventChatEscalated, escalation)
+	}
+	return nil
+}
+
+// GetTrendingQuestions returns the most asked questions of the last days days, with their counts
+// in the period before for comparison. Questions differing only in case, accents or punctuation are merged.
+func GetTrendingQuestions(days int, limit int) ([]models.TrendingQuestion, error) {
+	collection := config.GetCollection("question_logs")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	since := time.Now().AddDate(0, 0, -days)
+	current := bson.M{"$gte": bson.A{"$created_at", since}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"created_at": bson.M{"$gte": since.AddDate(0, 0, -days)}, "normalized": bson.M{"$ne": ""}}}},
+		// Newest first, so the first wording of each group is the latest one
+		{{Key: "$sort", Value: bson.M{"created_at": -1}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":            "$normalized",
+			"question":       bson.M{"$first": "$question"},
+			"count":          countIf(current),
+			"previous_count": countIf(bson.M{"$lt": bson.A{"$created_at", since}}),
+			"unanswered":     countIf(bson.M{"$and": bson.A{current, unansweredExpr}}),
+			"not_helpful":    countIf(bson.M{"$and": bson.A{current, notHelpfulExpr}}),
+			"last_asked_at":  bson.M{"$max": "$created_at"},
+		}}},
+		{{Key: "$match", Value: bson.M{"count": bson.M{"$gt": 0}}}},
+		// Among equally frequent questions, the ones that grew the most come first
+		{{Key: "$addFields", Value: bson.M{"growth": bson.M{"$subtract": bson.A{"$count", "$previous_count"}}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "growth", Value: -1}, {Key: "last_asked_at", Value: -1}}}},
+	}
+	if limit > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: limit}})
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		Question      string    `bson:"question"`
+		Count         int       `bson:"count"`
+		PreviousCount int       `bson:"previous_count"`
+		Unanswered    int       `bson:"unanswered"`
+		NotHelpful    int       `bson:"not_helpful"`
+		LastAskedAt   time.Time `bson:"last_asked_at"`
+	}
+	if err = cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	trending := make([]models.TrendingQuestion, 0, len(rows))
+	for _, row := range rows {
+		trending = append(trending, models.TrendingQuestion{
+			Question:      row.Question,
+			Count:         row.Count,
+			PreviousCount: row.PreviousCount,
+			Unanswered:    row.Unanswered,
+			NotHelpful:    row.NotHelpful,
+			LastAskedAt:   row.LastAskedAt,
+		})
+	}
+	return trending, nil
+}
+
+// wordingCounts groups the documents of a collection matching filter by normalized text, with the
+// latest wording, how often it occurred and when it last did; most frequent first, at most limit groups
+func wordingCounts(ctx context.Context, collectionName string, textField string, filter bson.M, limit int) ([]clusterItem, error) {
+	collection := config.GetCollection(collectionName)
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$sort", Value: bson.M{"created_at": -1}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   "$normalized",
+			"text":  bson.M{"$first": "$" + textField},
+			"count": bson.M{"$sum": 1},
+			"last":  bson.M{"$max": "$created_at"},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "last", Value: -1}}}},
+	}
+	if limit > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$limit", Value: limit}})
+	}
+
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		Normalized string    `bson:"_id"`
+		Text       string    `bson:"text"`
+		Count      int       `bson:"count"`
+		Last       time.Time `bson:"last"`
+	}
+	if err = cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	items := make([]clusterItem, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, clusterItem{text: row.Text, normalized: row.Normalized, count: row.Count, at: row.Last})
+	}
+	return items, nil
+}
+
+// GetUnansweredClusters groups the unanswered questions and zero-result searches of the last days days
+// by topic, largest group first
+func GetUnansweredClusters(days int) ([]models.QuestionCluster, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	since := time.Now().AddDate(0, 0, -days)
+	questions, err := wordingCounts(ctx, "question_logs", "question", bson.M{
+		"created_at": bson.M{"$gte": since},
+		"normalized": bson.M{"$ne": ""},
+		"$expr":      unansweredExpr,
+	}, maxClusterWordings)
+	if err != nil {
+		return nil, err
+	}
+	searches, err := wordingCounts(ctx, "search_misses", "query", bson.M{
+		"created_at": bson.M{"$gte": since},
+		"normalized": bson.M{"$ne": ""},
+	}, maxClusterWordings)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range searches {
+		searches[i].search = true
+	}
+	return clusterQuestions(append(questions, searches...)), nil
+}
+
+// GetProcedureSuggestions proposes procedures to write for topics that keep going unanswered
+func GetProcedureSuggestions(days int) ([]models.ProcedureSuggestion, error) {
+	clusters, err := GetUnansweredClusters(days)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := []models.ProcedureSuggestion{}
+	for _, cluster := range clusters {
+		if cluster.Count < minSuggestionDemand || len(cluster.Keywords) == 0 {
+			continue
+		}
+		suggestions = append(suggestions, models.ProcedureSuggestion{
+			SuggestedTitle:  strings.TrimRight(cluster.Label, "?!. "),
+			Keywords:        cluster.Keywords,
+			Demand:          cluster.Count,
+			SampleQuestions: cluster.Samples,
+		})
+	}
+	return suggestions, nil
+}
+
+// GetSearchMisses lists the zero-result search queries of the last days days, most frequent first
+func GetSearchMisses(days int, limit int) ([]models.SearchMissCount, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	misses, err := wordingCounts(ctx, "search_misses", "query", bson.M{"created_at": bson.M{"$gte": time.Now().AddDate(0, 0, -days)}}, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	queries := make([]models.SearchMissCount, 0, len(misses))
+	for _, m := range misses {
+		queries = append(queries, models.SearchMissCount{Query: m.text, Count: m.count, LastSearched: m.at})
+	}
+	return queries, nil
+}
+
+// clusterItem is one wording with how often it was asked (or searched) and when it last was
+type clusterItem struct {
+	text       string
+	normalized string
+	count      int
+	at         time.Time
+	search     bool
+}
+
+// clusterQuestions merges identical wordings, then greedily adds each wording (most frequent first)
+// to the first cluster whose keywords overlap enough, or starts a new cluster
+func clusterQuestions(items []clusterItem) []models.QuestionCluster {
+	type wording struct {
+		text     string
+		count    int
+		searches int
+		last     time.Time
+		words    []string
+	}
+	wordings := make(map[string]*wording)
+	var order []string
+	for _, item := range items {
+		if item.normalized == "" {
+			continue
+		}
+		w, ok := wordings[item.normalized]
+		if !ok {
+			w = &wording{text: item.text, last: item.at, words: keywords(item.text)}
+			wordings[item.normalized] = w
+			order = append(order, item.normalized)
+		}
+		w.count += item.count
+		if item.search {
+			w.searches += item.count
+		}
+		if item.at.After(w.last) {
+			w.last = item.at
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool { return wordings[order[i]].count > wordings[order[j]].count })
+
+	type cluster struct {
+		models.QuestionCluster
+		words     []string
+		wordCount map[string]int
+	}
+	var clusters []*cluster
+	for _, key := range order {
+		w := wordings[key]
+		var target *cluster
+		for _, c := range clusters {
+			if jaccard(c.words, w.words) >= clusterSimilarity {
+				target = c
+				break
+			}
+		}
+		if target == nil {
+			target = &cluster{
+				QuestionCluster: models.QuestionCluster{Label: w.text, LastAskedAt: w.last},
+				words:           w.words,
+				wordCount:       make(map[string]int),
+			}
+			clusters = append(clusters, target)
+		}
+
+		target.Count += w.count
+		target.SearchMisses += w.searches
+		if len(target.Samples) < 5 {
+			target.Samples = append(target.Samples, w.text)
+		}
+		if w.last.After(target.LastAskedAt) {
+			target.LastAskedAt = w.last
+		}
+		for _, word := range w.words {
+			target.wordCount[word] += w.count
+		}
+	}
+
+	result := make([]models.QuestionCluster, 0, len(clusters))
+	for _, c := range clusters {
+		c.Keywords = topWords(c.wordCount, 5)
+		result = append(result, c.QuestionCluster)
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Count > result[j].Count })
+	return result
+}
+
+// jaccard returns the overlap of two word sets, 0..1
+func jaccard(a, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for _, w := range a {
+		if containsString(b, w) {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+// topWords returns the n most frequent words, alphabetically among equals
+func topWords(counts map[string]int, n int) []string {
+	words := make([]string, 0, len(counts))
+	for w := range counts {
+		words = append(words, w)
+	}
+	sort.Slice(words, func(i, j int) bool {
+		if counts[words[i]] != counts[words[j]] {
+			return counts[words[i]] > counts[words[j]]
+		}
+		return words[i] < words[j]
+	})
+	if len(words) > n {
+		words = words[:n]
+	}
+	return words
+}
@@ -0,0 +1,244 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"web_AI/config"
+	"web_AI/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrRevisionNotFound is returned when a procedure has no revision with the requested number
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// revisionAttempts is how often recordRevision retries when a concurrent save took the same number
+const revisionAttempts = 5
+
+// EnsureRevisionIndexes makes revision numbers unique per procedure, so concurrent saves cannot
+// both record the same revision
+func EnsureRevisionIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := config.GetCollection("procedure_revisions").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "procedure_id", Value: 1}, {Key: "revision", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// recordRevision stores an immutable snapshot of the procedure as its next revision
+func recordRevision(ctx context.Context, procedure *models.Procedure, action string, authorID string, restoredFrom int) error {
+	collection := config.GetCollection("procedure_revisions")
+
+	revision := revisionSnapshot(procedure, action, authorID, restoredFrom)
+	for attempt := 1; ; attempt++ {
+		latest, err := latestRevisionNumber(ctx, procedure.ID)
+		if err != nil {
+			return err
+		}
+		revision.ID = primitive.NewObjectID()
+		revision.Revision = latest + 1
+
+		_, err = collection.InsertOne(ctx, revision)
+		if !mongo.IsDuplicateKeyError(err) || attempt == revisionAttempts {
+			return err
+		}
+	}
+}
+
+func revisionSnapshot(procedure *models.Procedure, action string, authorID string, restoredFrom int) models.ProcedureRevision {
+	return models.ProcedureRevision{
+		ProcedureID:  procedure.ID,
+		Action:       action,
+		Title:        procedure.Title,
+		Content:      procedure.Content,
+		Steps:        procedure.Steps,
+		Category:     procedure.Category,
+		CategoryID:   procedure.CategoryID,
+		Description:  procedure.Description,
+		Access:       procedure.ProcedureAccess,
+		AuthorID:     authorID,
+		RestoredFrom: restoredFrom,
+		CreatedAt:    time.Now(),
+	}
+}
+
+// ensureBaselineRevision snapshots procedures created before revision history existed,
+// so their original text is not lost on the first update
+func ensureBaselineRevision(ctx context.Context, procedureID primitive.ObjectID) error {
+	latest, err := latestRevisionNumber(ctx, procedureID)
+	if err != nil || latest > 0 {
+		return err
+	}
+
+	var current models.Procedure
+	err = config.GetCollection("procedures").FindOne(ctx, bson.M{"_id": procedureID}).Decode(&current)
+	if err == mongo.ErrNoDocuments {
+		return ErrProcedureNotFound
+	} else if err != nil {
+		return err
+	}
+
+	author := current.UpdatedBy
+	if author == "" && !current.CreatedBy.IsZero() {
+		author = current.CreatedBy.Hex()
+	}
+	baseline := revisionSnapshot(&current, models.RevisionActionBaseline, author, 0)
+	baseline.ID = primitive.NewObjectID()
+	baseline.Revision = 1
+	// A concurrent save may have recorded the baseline first, which is just as good
+	if _, err := config.GetCollection("procedure_revisions").InsertOne(ctx, baseline); err != nil && !mongo.IsDuplicateKeyError(err) {
+		return err
+	}
+	return nil
+}
+
+// latestRevisionNumber returns the highest revision number of a procedure, or 0 if it has none
+func latestRevisionNumber(ctx context.Context, procedureID primitive.ObjectID) (int, error) {
+	latest, err := latestRevision(ctx, procedureID)
+	if err != nil || latest == nil {
+		return 0, err
+	}
+	return latest.Revision, nil
+}
+
+// latestRevision returns the newest revision of a procedure, or nil if it has none
+func latestRevision(ctx context.Context, procedureID primitive.ObjectID) (*models.ProcedureRevision, error) {
+	collection := config.GetCollection("procedure_revisions")
+
+	opts := options.FindOne().SetSort(bson.D{bson.E{Key: "revision", Value: -1}})
+	var latest models.ProcedureRevision
+	err := collection.FindOne(ctx, bson.M{"procedure_id": procedureID}, opts).Decode(&latest)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &latest, nil
+}
+
+// GetProcedureRevisions lists all revisions of a procedure, newest first
+func GetProcedureRevisions(procedureID string) ([]models.ProcedureRevision, error) {
+	collection := config.GetCollection("procedure_revisions")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(procedureID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid procedure ID")
+	}
+
+	opts := options.Find().SetSort(bson.D{bson.E{Key: "revision", Value: -1}})
+	cursor, err := collection.Find(ctx, bson.M{"procedure_id": objID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	revisions := []models.ProcedureRevision{}
+	if err = cursor.All(ctx, &revisions); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// GetProcedureRevision retrieves a single revision of a procedure
+func GetProcedureRevision(procedureID string, revision int) (*models.ProcedureRevision, error) {
+	collection := config.GetCollection("procedure_revisions")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(procedureID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid procedure ID")
+	}
+
+	var rev models.ProcedureRevision
+	err = collection.FindOne(ctx, bson.M{"procedure_id": objID, "revision": revision}).Decode(&rev)
+	if err == mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("%w: %d", ErrRevisionNotFound, revision)
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &rev, nil
+}
+
+// DiffProcedureRevisions compares two revisions of a procedure
+func DiffProcedureRevisions(procedureID string, from, to int) (*models.RevisionDiffResponse, error) {
+	oldRev, err := GetProcedureRevision(procedureID, from)
+	if err != nil {
+		return nil, err
+	}
+	newRev, err := GetProcedureRevision(procedureID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &models.RevisionDiffResponse{
+		ProcedureID: procedureID,
+		From:        from,
+		To:          to,
+		Changes:     []models.FieldChange{},
+		ContentDiff: diffLines(splitLines(oldRev.Content), splitLines(newRev.Content)),
+	}
+
+	fields := []struct{ name, old, new string }{
+		{"title", oldRev.Title, newRev.Title},
+		{"category", oldRev.Category, newRev.Category},
+		{"description", oldRev.Description, newRev.Description},
+	}
+	for _, f := range fields {
+		if f.old != f.new {
+			diff.Changes = append(diff.Changes, models.FieldChange{Field: f.name, From: f.old, To: f.new})
+		}
+	}
+
+	diff.StepsDiff = diffLines(splitLines(RenderStepsContent(oldRev.Steps)), splitLines(RenderStepsContent(newRev.Steps)))
+
+	for _, line := range diff.ContentDiff {
+		switch line.Op {
+		case diffInsert:
+			diff.Added++
+		case diffDelete:
+			diff.Removed++
+		}
+	}
+
+	return diff, nil
+}
+
+// RestoreProcedureRevision makes an older revision the current procedure, recording the restore as a new revision
+func RestoreProcedureRevision(procedureID string, revision int, authorID string) error {
+	rev, err := GetProcedureRevision(procedureID, revision)
+	if err != nil {
+		return err
+	}
+
+	// A non-nil slice makes the update clear steps added after this revision
+	steps := rev.Steps
+	if steps == nil {
+		steps = []models.ProcedureStep{}
+	}
+
+	procedure := &models.Procedure{
+		Title:       rev.Title,
+		Content:     rev.Content,
+		Steps:       steps,
+		Category:    rev.Category,
+		CategoryID:  rev.CategoryID,
+		Description: rev.Description,
+		UpdatedBy:   authorID,
+	}
+
+	return updateProcedure(procedureID, procedure, models.RevisionActionRestore, revision, nil)
+}
@@ -0,0 +1,418 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"web_AI/config"
+	"web_AI/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrRunNotFound  = errors.New("run not found")
+	ErrInvalidRun   = errors.New("invalid run")
+	ErrRunForbidden = errors.New("not allowed to change this run")
+)
+
+// StartRun starts a checklist run of a procedure the viewer may see
+func StartRun(procedureID string, viewer models.Viewer, req models.StartRunRequest) (*models.ProcedureRun, error) {
+	collection := config.GetCollection("procedure_runs")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	procedure, err := GetPublishedProcedureByID(procedureID, viewer)
+	if err != nil {
+		return nil, err
+	}
+	steps := flattenRunSteps(procedure.Steps, "")
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("%w: the procedure has no steps to check off", ErrInvalidRun)
+	}
+
+	now := time.Now()
+	run := &models.ProcedureRun{
+		ID:               primitive.NewObjectID(),
+		ProcedureID:      procedure.ID,
+		ProcedureTitle:   procedure.Title,
+		ProcedureVersion: procedure.Version,
+		Title:            strings.TrimSpace(req.Title),
+		StartedBy:        viewer.UserID,
+		Status:           models.RunStatusInProgress,
+		Steps:            steps,
+		DueDate:          req.DueDate,
+		StartedAt:        now,
+		UpdatedAt:        now,
+	}
+	run.Progress = runProgress(run.Steps)
+
+	if _, err := collection.InsertOne(ctx, run); err != nil {
+		return nil, err
+	}
+	return run, nil
+}
+
+// flattenRunSteps turns nested procedure steps into a numbered checklist
+func flattenRunSteps(steps []models.ProcedureStep, prefix string) []models.RunStep {
+	var result []models.RunStep
+	for i, step := range steps {
+		number := prefix + strconv.Itoa(i+1)
+		result = append(result, models.RunStep{Number: number, Title: step.Title})
+		result = append(result, flattenRunSteps(step.SubSteps, number+".")...)
+	}
+	return result
+}
+
+func runProgress(steps []models.RunStep) models.RunProgress {
+	progress := models.RunProgress{Total: len(steps)}
+	for _, step := range steps {
+		if step.Done {
+			progress.Done++
+		}
+	}
+	if progress.Total > 0 {
+		progress.Percent = progress.Done * 100 / progress.Total
+	}
+	return progress
+}
+
+// isRunOverdue reports whether an open run is past its due date or has an unfinished step past its own
+func isRunOverdue(run *models.ProcedureRun, now time.Time) bool {
+	if run.Status != models.RunStatusInProgress {
+		return false
+	}
+	if run.DueDate != nil && run.DueDate.Before(now) {
+		return true
+	}
+	for _, step := range run.Steps {
+		if !step.Done && step.DueDate != nil && step.DueDate.Before(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// overdueRunFilter matches the runs isRunOverdue reports
+func overdueRunFilter(now time.Time) bson.M {
+	return bson.M{
+		"status": models.RunStatusInProgress,
+		"$or": []bson.M{
+			{"due_date": bson.M{"$lt": now}},
+			{"steps": bson.M{"$elemMatch": bson.M{"done": false, "due_date": bson.M{"$lt": now}}}},
+		},
+	}
+}
+
+// canViewRun reports whether the viewer started the run, has a step in it or is an admin
+func canViewRun(run *models.ProcedureRun, viewer models.Viewer) bool {
+	return viewer.IsAdmin || run.StartedBy == viewer.UserID || containsString(run.Assignees, viewer.UserID)
+}
+
+// GetRun returns a run the viewer takes part in
+func GetRun(id string, viewer models.Viewer) (*models.ProcedureRun, error) {
+	run, err := findRun(id)
+	if err != nil {
+		return nil, err
+	}
+	if !canViewRun(run, viewer) {
+		return nil, ErrRunNotFound
+	}
+	return run, nil
+}
+
+func findRun(id string) (*models.ProcedureRun, error) {
+	collection := config.GetCollection("procedure_runs")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrRunNotFound
+	}
+
+	var run models.ProcedureRun
+	err = collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&run)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrRunNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	run.Overdue = isRunOverdue(&run, time.Now())
+	return &run, nil
+}
+
+// GetMyRuns lists the runs a user started or has steps in, newest first
+func GetMyRuns(userID string, status string) ([]models.ProcedureRun, error) {
+	filter := bson.M{"$or": []bson.M{{"started_by": userID}, {"assignees": userID}}}
+	if status != "" {
+		filter["status"] = status
+	}
+	return findRuns(filter)
+}
+
+// GetRuns lists runs for admins, optionally of one procedure; status "overdue" lists open runs past a due date
+func GetRuns(procedureID string, status string) ([]models.ProcedureRun, error) {
+	filter := bson.M{}
+	if status == "overdue" {
+		filter = overdueRunFilter(time.Now())
+	} else if status != "" {
+		filter["status"] = status
+	}
+	if procedureID != "" {
+		objID, err := primitive.ObjectIDFromHex(procedureID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid procedure ID", ErrInvalidRun)
+		}
+		filter["procedure_id"] = objID
+	}
+	return findRuns(filter)
+}
+
+func findRuns(filter bson.M) ([]models.ProcedureRun, error) {
+	collection := config.GetCollection("procedure_runs")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{bson.E{Key: "started_at", Value: -1}}).SetLimit(500)
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	runs := []models.ProcedureRun{}
+	if err = cursor.All(ctx, &runs); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	for i := range runs {
+		runs[i].Overdue = isRunOverdue(&runs[i], now)
+	}
+	return runs, nil
+}
+
+// GetRunSummary counts in-progress and overdue runs per procedure, most overdue first
+func GetRunSummary() ([]models.ProcedureRunSummary, error) {
+	collection := config.GetCollection("procedure_runs")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	count := func(filter bson.M, field string) ([]models.ProcedureRunSummary, error) {
+		pipeline := mongo.Pipeline{
+			{{Key: "$match", Value: filter}},
+			{{Key: "$group", Value: bson.M{
+				"_id":             "$procedure_id",
+				"procedure_title": bson.M{"$last": "$procedure_title"},
+				field:             bson.M{"$sum": 1},
+			}}},
+		}
+		cursor, err := collection.Aggregate(ctx, pipeline)
+		if err != nil {
+			return nil, err
+		}
+		defer cursor.Close(ctx)
+
+		var rows []models.ProcedureRunSummary
+		err = cursor.All(ctx, &rows)
+		return rows, err
+	}
+
+	open, err := count(bson.M{"status": models.RunStatusInProgress}, "in_progress")
+	if err != nil {
+		return nil, err
+	}
+	overdue, err := count(overdueRunFilter(time.Now()), "overdue")
+	if err != nil {
+		return nil, err
+	}
+
+	overdueByProcedure := make(map[primitive.ObjectID]int64, len(overdue))
+	for _, row := range overdue {
+		overdueByProcedure[row.ProcedureID] = row.Overdue
+	}
+	for i := range open {
+		open[i].Overdue = overdueByProcedure[open[i].ProcedureID]
+	}
+	sort.Slice(open, func(i, j int) bool {
+		if open[i].Overdue != open[j].Overdue {
+			return open[i].Overdue > open[j].Overdue
+		}
+		return open[i].InProgress > open[j].InProgress
+	})
+	return open, nil
+}
+
+// UpdateRunStep checks or unchecks a step and/or adds a note; the run completes when every step is done.
+// The run's starter, the step's assignee and admins may do this.
+func UpdateRunStep(id string, number string, viewer models.Viewer, req models.UpdateRunStepRequest) (*models.ProcedureRun, error) {
+	run, err := GetRun(id, viewer)
+	if err != nil {
+		return nil, err
+	}
+	if run.Status != models.RunStatusInProgress {
+		return nil, fmt.Errorf("%w: the run is %s", ErrInvalidRun, run.Status)
+	}
+	index := runStepIndex(run, number)
+	if index < 0 {
+		return nil, fmt.Errorf("%w: step %s not found", ErrInvalidRun, number)
+	}
+	step := &run.Steps[index]
+	if !viewer.IsAdmin && run.StartedBy != viewer.UserID && step.AssigneeID != viewer.UserID {
+		return nil, ErrRunForbidden
+	}
+
+	note := strings.TrimSpace(req.Note)
+	if req.Done == nil && note == "" {
+		return nil, fmt.Errorf("%w: nothing to update", ErrInvalidRun)
+	}
+
+	now := time.Now()
+	if req.Done != nil && *req.Done != step.Done {
+		step.Done = *req.Done
+		if step.Done {
+			step.DoneAt = &now
+			step.DoneBy = viewer.UserID
+		} else {
+			step.DoneAt = nil
+			step.DoneBy = ""
+		}
+	}
+	if note != "" {
+		step.Notes = append(step.Notes, models.RunNote{UserID: viewer.UserID, Text: truncateText(note, maxCommentLength), CreatedAt: now})
+	}
+
+	return saveRun(run, now)
+}
+
+// AssignRunStep assigns a step to a colleague and notifies them; only the starter and admins may assign
+func AssignRunStep(id string, number string, viewer models.Viewer, req models.AssignRunStepRequest) (*models.ProcedureRun, error) {
+	run, err := GetRun(id, viewer)
+	if err != nil {
+		return nil, err
+	}
+	if !viewer.IsAdmin && run.StartedBy != viewer.UserID {
+		return nil, ErrRunForbidden
+	}
+	if run.Status != models.RunStatusInProgress {
+		return nil, fmt.Errorf("%w: the run is %s", ErrInvalidRun, run.Status)
+	}
+	index := runStepIndex(run, number)
+	if index < 0 {
+		return nil, fmt.Errorf("%w: step %s not found", ErrInvalidRun, number)
+	}
+
+	assignee := strings.TrimSpace(req.AssigneeID)
+	if assignee != "" {
+		user, err := GetUserByID(assignee)
+		if err != nil {
+			return nil, fmt.Errorf("%w: assignee not found", ErrInvalidRun)
+		}
+		procedure, err := GetProcedureByID(run.ProcedureID.Hex())
+		if err != nil {
+			return nil, err
+		}
+		if !CanView(procedure.ProcedureAccess, viewerForUser(user)) {
+			return nil, fmt.Errorf("%w: the assignee may not see this procedure", ErrInvalidRun)
+		}
+	}
+
+	step := &run.Steps[index]
+	previous := step.AssigneeID
+	step.AssigneeID = assignee
+	step.DueDate = req.DueDate
+
+	updated, err := saveRun(run, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if assignee != "" && assignee != previous && assignee != viewer.UserID {
+		notification := &models.Notification{
+			UserID:      assignee,
+			Type:        models.NotificationTypeRunStepAssigned,
+			Title:       "Bạn được giao một bước quy trình",
+			Message:     fmt.Sprintf("Bước %s \"%s\" của quy trình \"%s\" đã được giao cho bạn.", step.Number, step.Title, run.ProcedureTitle),
+			ProcedureID: run.ProcedureID,
+		}
+		if err := CreateNotification(notification); err != nil {
+			fmt.Printf("⚠️ Failed to notify assignee of run %s: %v\n", run.ID.Hex(), err)
+		}
+	}
+	return updated, nil
+}
+
+// CancelRun stops a run; only the starter and admins may cancel it
+func CancelRun(id string, viewer models.Viewer) (*models.ProcedureRun, error) {
+	run, err := GetRun(id, viewer)
+	if err != nil {
+		return nil, err
+	}
+	if !viewer.IsAdmin && run.StartedBy != viewer.UserID {
+		return nil, ErrRunForbidden
+	}
+	if run.Status != models.RunStatusInProgress {
+		return nil, fmt.Errorf("%w: the run is %s", ErrInvalidRun, run.Status)
+	}
+
+	run.Status = models.RunStatusCancelled
+	return saveRun(run, time.Now())
+}
+
+func runStepIndex(run *models.ProcedureRun, number string) int {
+	number = strings.Trim(number, ".")
+	for i, step := range run.Steps {
+		if step.Number == number {
+			return i
+		}
+	}
+	return -1
+}
+
+// saveRun stores the run's steps with recomputed progress, assignees and status.
+// The update only applies if nobody saved the run since it was read, so concurrent check-offs are not lost.
+func saveRun(run *models.ProcedureRun, now time.Time) (*models.ProcedureRun, error) {
+	collection := config.GetCollection("procedure_runs")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	run.Progress = runProgress(run.Steps)
+	run.Assignees = nil
+	for _, step := range run.Steps {
+		if step.AssigneeID != "" && !containsString(run.Assignees, step.AssigneeID) {
+			run.Assignees = append(run.Assignees, step.AssigneeID)
+		}
+	}
+	if run.Status != models.RunStatusCancelled {
+		if run.Progress.Done == run.Progress.Total {
+			run.Status = models.RunStatusCompleted
+			run.CompletedAt = &now
+		} else {
+			run.Status = models.RunStatusInProgress
+			run.CompletedAt = nil
+		}
+	}
+
+	previousVersion := run.Version
+	run.Version++
+	run.UpdatedAt = now
+	result, err := collection.ReplaceOne(ctx, bson.M{"_id": run.ID, "version": versionMatch(previousVersion)}, run)
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		return nil, fmt.Errorf("%w: the run was changed by someone else, reload and try again", ErrInvalidRun)
+	}
+
+	run.Overdue = isRunOverdue(run, now)
+	return run, nil
+}
@@ -0,0 +1,292 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"web_AI/config"
+	"web_AI/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrInvalidReviewSchedule wraps validation errors in ownership and review dates
+var ErrInvalidReviewSchedule = errors.New("invalid review schedule")
+
+// staleRenotifyInterval is how long the scheduler waits before reminding an owner again
+const staleRenotifyInterval = 7 * 24 * time.Hour
+
+// defaultReviewIntervalDays is used for procedures without their own review interval
+func defaultReviewIntervalDays() int {
+	if days, err := strconv.Atoi(os.Getenv("REVIEW_INTERVAL_DAYS")); err == nil && days > 0 {
+		return days
+	}
+	return 365
+}
+
+// ReviewDueAt returns when a procedure is next due for review, counting from its last
+// review, or from when it was published or last updated if it was never reviewed
+func ReviewDueAt(procedure *models.Procedure) time.Time {
+	base := procedure.UpdatedAt
+	switch {
+	case procedure.LastReviewedAt != nil:
+		base = *procedure.LastReviewedAt
+	case procedure.PublishedAt != nil:
+		base = *procedure.PublishedAt
+	case base.IsZero():
+		base = procedure.CreatedAt
+	}
+
+	days := procedure.ReviewIntervalDays
+	if days <= 0 {
+		days = defaultReviewIntervalDays()
+	}
+	return base.AddDate(0, 0, days)
+}
+
+// isProcedureStale reports whether a procedure is overdue for review or past its expiry date
+func isProcedureStale(procedure *models.Procedure, now time.Time) bool {
+	if procedure.ExpiryDate != nil && !procedure.ExpiryDate.After(now) {
+		return true
+	}
+	return !ReviewDueAt(procedure).After(now)
+}
+
+// validateReviewSchedule checks the ownership fields of a procedure
+func validateReviewSchedule(procedure *models.Procedure) error {
+	if procedure.ReviewIntervalDays < 0 {
+		return fmt.Errorf("%w: review_interval_days cannot be negative", ErrInvalidReviewSchedule)
+	}
+	if procedure.EffectiveDate != nil && procedure.ExpiryDate != nil && !procedure.ExpiryDate.After(*procedure.EffectiveDate) {
+		return fmt.Errorf("%w: expiry_date must be after effective_date", ErrInvalidReviewSchedule)
+	}
+	return nil
+}
+
+// GetStaleProcedures lists published procedures that are overdue for review or expired, most overdue first
+func GetStaleProcedures() ([]models.StaleProcedure, error) {
+	procedures, err := GetProcedures(models.ProcedureQuery{Viewer: systemViewer})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	stale := []models.StaleProcedure{}
+	for i := range procedures {
+		p := &procedures[i]
+		if !isProcedureStale(p, now) {
+			continue
+		}
+
+		due := ReviewDueAt(p)
+		expired := p.ExpiryDate != nil && !p.ExpiryDate.After(now)
+		if expired && p.ExpiryDate.Before(due) {
+			due = *p.ExpiryDate
+		}
+		stale = append(stale, models.StaleProcedure{
+			ID:             p.ID,
+			Title:          p.Title,
+			Category:       p.Category,
+			OwnerID:        procedureOwner(p),
+			LastReviewedAt: p.LastReviewedAt,
+			ReviewDueAt:    due,
+			ExpiryDate:     p.ExpiryDate,
+			Expired:        expired,
+			DaysOverdue:    int(math.Floor(now.Sub(due).Hours() / 24)),
+		})
+	}
+
+	sort.SliceStable(stale, func(i, j int) bool { return stale[i].DaysOverdue > stale[j].DaysOverdue })
+	return stale, nil
+}
+
+// procedureOwner returns who is responsible for a procedure, falling back to its creator
+func procedureOwner(procedure *models.Procedure) string {
+	if procedure.OwnerID != "" {
+		return procedure.OwnerID
+	}
+	if !procedure.CreatedBy.IsZero() {
+		return procedure.CreatedBy.Hex()
+	}
+	return ""
+}
+
+// UpdateProcedureOwnership changes the owner, validity dates and review interval of a procedure
+func UpdateProcedureOwnership(id string, req models.UpdateOwnershipRequest, actorID string) (*models.Procedure, error) {
+	procedure, err := GetProcedureByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	set := bson.M{"updated_at": time.Now(), "updated_by": actorID}
+	if req.OwnerID != nil {
+		procedure.OwnerID = strings.TrimSpace(*req.OwnerID)
+		set["owner_id"] = procedure.OwnerID
+	}
+	if req.EffectiveDate != nil {
+		procedure.EffectiveDate = req.EffectiveDate
+		set["effective_date"] = *req.EffectiveDate
+	}
+	if req.ExpiryDate != nil {
+		procedure.ExpiryDate = req.ExpiryDate
+		set["expiry_date"] = *req.ExpiryDate
+	}
+	if req.ReviewIntervalDays != nil {
+		procedure.ReviewIntervalDays = *req.ReviewIntervalDays
+		set["review_interval_days"] = *req.ReviewIntervalDays
+	}
+	if err := validateReviewSchedule(procedure); err != nil {
+		return nil, err
+	}
+
+	// A new schedule may no longer be overdue, so the next reminder starts from scratch
+	update := bson.M{"$set": set, "$unset": bson.M{"stale_notified_at": ""}}
+	if err := updateProcedureFields(procedure.ID, update); err != nil {
+		return nil, err
+	}
+	return GetProcedureByID(id)
+}
+
+// MarkProcedureReviewed records that the content of a procedure was checked and is still correct
+func MarkProcedureReviewed(id string, comment string, actorID string) (*models.Procedure, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid procedure ID")
+	}
+
+	now := time.Now()
+	update := bson.M{
+		"$set":   bson.M{"last_reviewed_at": now},
+		"$unset": bson.M{"stale_notified_at": ""},
+		"$push": bson.M{"review_comments": models.ReviewComment{
+			AuthorID:  actorID,
+			Action:    models.ReviewActionReviewed,
+			Comment:   strings.TrimSpace(comment),
+			CreatedAt: now,
+		}},
+	}
+	if err := updateProcedureFields(objID, update); err != nil {
+		return nil, err
+	}
+	return GetProcedureByID(id)
+}
+
+func updateProcedureFields(id primitive.ObjectID, update bson.M) error {
+	collection := config.GetCollection("procedures")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	update["$inc"] = bson.M{"version": 1}
+	result, err := collection.UpdateOne(ctx, bson.M{"_id": id, "deleted_at": nil}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrProcedureNotFound
+	}
+	return nil
+}
+
+// CheckStaleProcedures notifies the owners of stale procedures, at most once per staleRenotifyInterval,
+// and returns how many notifications were sent
+func CheckStaleProcedures() (int, error) {
+	procedures, err := GetProcedures(models.ProcedureQuery{Viewer: systemViewer})
+	if err != nil {
+		return 0, err
+	}
+
+	collection := config.GetCollection("procedures")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	now := time.Now()
+	sent := 0
+	for i := range procedures {
+		p := &procedures[i]
+		if !isProcedureStale(p, now) {
+			continue
+		}
+		if p.StaleNotifiedAt != nil && now.Sub(*p.StaleNotifiedAt) < staleRenotifyInterval {
+			continue
+		}
+		owner := procedureOwner(p)
+		if owner == "" {
+			continue
+		}
+
+		// Claim the reminder first so two instances running the scheduler do not both send it
+		claim := bson.M{"_id": p.ID, "stale_notified_at": p.StaleNotifiedAt}
+		result, err := collection.UpdateOne(ctx, claim, bson.M{"$set": bson.M{"stale_notified_at": now}})
+		if err != nil {
+			return sent, err
+		}
+		if result.ModifiedCount == 0 {
+			continue
+		}
+
+		notification := &models.Notification{
+			UserID:      owner,
+			Type:        models.NotificationTypeProcedureStale,
+			Title:       "Quy trình cần rà soát",
+			Message:     staleMessage(p, now),
+			ProcedureID: p.ID,
+		}
+		if err := CreateNotification(notification); err != nil {
+			return sent, err
+		}
+		sent++
+	}
+
+	return sent, nil
+}
+
+func staleMessage(procedure *models.Procedure, now time.Time) string {
+	if procedure.ExpiryDate != nil && !procedure.ExpiryDate.After(now) {
+		return fmt.Sprintf("Quy trình \"%s\" đã hết hiệu lực từ %s. Vui lòng cập nhật hoặc lưu trữ.", procedure.Title, procedure.ExpiryDate.Format("02/01/2006"))
+	}
+	return fmt.Sprintf("Quy trình \"%s\" đã quá hạn rà soát từ %s. Vui lòng kiểm tra lại nội dung.", procedure.Title, ReviewDueAt(procedure).Format("02/01/2006"))
+}
+
+// StartStalenessScheduler checks for stale procedures now and then on every interval
+func StartStalenessScheduler(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			sent, err := CheckStaleProcedures()
+			if err != nil {
+				fmt.Printf("⚠️ Staleness check failed: %v\n", err)
+			} else if sent > 0 {
+				fmt.Printf("🔔 Sent %d stale procedure reminders\n", sent)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// StaleProcedureWarning returns a note to append to chat answers that cite stale procedures.
+// It is empty unless CHAT_STALE_WARNING is enabled.
+func StaleProcedureWarning(procedures []models.Procedure) string {
+	if enabled, _ := strconv.ParseBool(os.Getenv("CHAT_STALE_WARNING")); !enabled {
+		return ""
+	}
+
+	now := time.Now()
+	var titles []string
+	for i := range procedures {
+		if isProcedureStale(&procedures[i], now) {
+			titles = append(titles, "\""+procedures[i].Title+"\"")
+		}
+	}
+	if len(titles) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("\n\n⚠️ Lưu ý: thông tin từ quy trình %s có thể đã lỗi thời, vui lòng xác nhận lại với bộ phận phụ trách.", strings.Join(titles, ", "))
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"web_AI/models"
+)
+
+// ErrInvalidSteps wraps validation errors in structured steps
+var ErrInvalidSteps = errors.New("invalid steps")
+
+// maxStepDepth limits how deeply sub-steps can be nested
+const maxStepDepth = 3
+
+// RenderStepsContent renders structured steps as the plain-text content used by older clients and search
+func RenderStepsContent(steps []models.ProcedureStep) string {
+	var sb strings.Builder
+	renderSteps(&sb, steps, "", "")
+	return strings.TrimRight(sb.String(), "\n")
+}
+
+func renderSteps(sb *strings.Builder, steps []models.ProcedureStep, prefix string, indent string) {
+	for i, step := range steps {
+		number := prefix + strconv.Itoa(i+1)
+		sb.WriteString(fmt.Sprintf("%s%s. %s\n", indent, number, step.Title))
+		detailIndent := indent + "   "
+		if step.Instructions != "" {
+			for _, line := range splitLines(step.Instructions) {
+				sb.WriteString(detailIndent + line + "\n")
+			}
+		}
+		if step.ResponsibleRole != "" {
+			sb.WriteString(detailIndent + "- Người phụ trách: " + step.ResponsibleRole + "\n")
+		}
+		if step.ExpectedDuration != "" {
+			sb.WriteString(detailIndent + "- Thời gian dự kiến: " + step.ExpectedDuration + "\n")
+		}
+		if len(step.RequiredDocuments) > 0 {
+			sb.WriteString(detailIndent + "- Giấy tờ cần có: " + strings.Join(step.RequiredDocuments, ", ") + "\n")
+		}
+		renderSteps(sb, step.SubSteps, number+".", detailIndent)
+	}
+}
+
+// validateSteps checks that every step has a title and sub-steps are not nested too deeply
+func validateSteps(steps []models.ProcedureStep) error {
+	return validateStepLevel(steps, "", 1)
+}
+
+func validateStepLevel(steps []models.ProcedureStep, prefix string, depth int) error {
+	if len(steps) > 0 && depth > maxStepDepth {
+		return fmt.Errorf("%w: steps cannot be nested more than %d levels", ErrInvalidSteps, maxStepDepth)
+	}
+	for i := range steps {
+		number := prefix + strconv.Itoa(i+1)
+		steps[i].Title = strings.TrimSpace(steps[i].Title)
+		if steps[i].Title == "" {
+			return fmt.Errorf("%w: step %s: title is required", ErrInvalidSteps, number)
+		}
+		if err := validateStepLevel(steps[i].SubSteps, number+".", depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FindStep returns the step with the given number, e.g. "2" or "2.1"
+func FindStep(steps []models.ProcedureStep, number string) (*models.ProcedureStep, error) {
+	parts := strings.Split(strings.Trim(number, "."), ".")
+	current := steps
+	var step *models.ProcedureStep
+	for _, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid step number '%s'", number)
+		}
+		if n > len(current) {
+			return nil, fmt.Errorf("step %s not found", number)
+		}
+		step = &current[n-1]
+		current = step.SubSteps
+	}
+	if step == nil {
+		return nil, fmt.Errorf("invalid step number '%s'", number)
+	}
+	return step, nil
+}
+
+// prepareProcedureSteps validates steps and fills in Content from them when no content was given
+func prepareProcedureSteps(procedure *models.Procedure) error {
+	if err := validateSteps(procedure.Steps); err != nil {
+		return err
+	}
+	if strings.TrimSpace(procedure.Content) == "" && len(procedure.Steps) > 0 {
+		procedure.Content = RenderStepsContent(procedure.Steps)
+	}
+	return nil
+}
+
+// UpdateProcedureSteps replaces all steps of a procedure, recording a revision.
+// It fails with ErrVersionConflict if the procedure changed since expectedVersion, or while it was being edited.
+func UpdateProcedureSteps(id string, steps []models.ProcedureStep, actorID string, expectedVersion *int64) (*models.Procedure, error) {
+	existing, err := GetProcedureByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return replaceProcedureSteps(existing, steps, contentFromSteps(existing), actorID, expectedVersion)
+}
+
+// UpdateProcedureStep replaces a single step (and its sub-steps) of a procedure
+func UpdateProcedureStep(id string, number string, step models.ProcedureStep, actorID string, expectedVersion *int64) (*models.Procedure, error) {
+	existing, err := GetProcedureByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := FindStep(existing.Steps, number)
+	if err != nil {
+		return nil, err
+	}
+	generated := contentFromSteps(existing)
+	*target = step
+
+	return replaceProcedureSteps(existing, existing.Steps, generated, actorID, expectedVersion)
+}
+
+// contentFromSteps reports whether a procedure's content was generated from its steps rather than written by hand
+func contentFromSteps(procedure *models.Procedure) bool {
+	return procedure.Content == "" || procedure.Content == RenderStepsContent(procedure.Steps)
+}
+
+func replaceProcedureSteps(existing *models.Procedure, steps []models.ProcedureStep, regenerateContent bool, actorID string, expectedVersion *int64) (*models.Procedure, error) {
+	if expectedVersion != nil && *expectedVersion != existing.Version {
+		return nil, ErrVersionConflict
+	}
+	if steps == nil {
+		steps = []models.ProcedureStep{}
+	}
+
+	procedure := &models.Procedure{
+		Title:       existing.Title,
+		Content:     existing.Content,
+		Steps:       steps,
+		Category:    existing.Category,
+		CategoryID:  existing.CategoryID,
+		Description: existing.Description,
+		UpdatedBy:   actorID,
+	}
+	// Content that was generated from the old steps follows the new ones; hand-written content is kept.
+	// Removing every step only removes the steps, so the body of the procedure is never lost.
+	if regenerateContent && len(steps) > 0 {
+		procedure.Content = ""
+	}
+
+	// The update is conditional on the version that was read, so concurrent edits are not lost
+	version := existing.Version
+	id := existing.ID.Hex()
+	if err := UpdateProcedure(id, procedure, &version); err != nil {
+		return nil, err
+	}
+	return GetProcedureByID(id)
+}
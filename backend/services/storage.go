@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+var (
+	ErrStoredFileNotFound = errors.New("stored file not found")
+	ErrInvalidFileName    = errors.New("invalid file name")
+)
+
+// Storage backends, chosen with STORAGE_BACKEND
+const (
+	StorageBackendLocal  = "local"  // files in UPLOAD_DIR (default)
+	StorageBackendS3     = "s3"     // an S3-compatible bucket such as AWS S3 or MinIO
+	StorageBackendMemory = "memory" // kept in memory and lost on restart; for development and tests
+)
+
+// FileInfo describes a stored file
+type FileInfo struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+}
+
+// FileStorage keeps uploaded files. Names are relative, slash-separated paths such as
+// "<sha256>.pdf"; implementations reject names that would escape their root.
+type FileStorage interface {
+	// Put stores data under name, replacing any file with that name
+	Put(ctx context.Context, name string, data []byte, contentType string) error
+	// Open returns the content of a file; the caller closes it
+	Open(ctx context.Context, name string) (io.ReadSeekCloser, *FileInfo, error)
+	Stat(ctx context.Context, name string) (*FileInfo, error)
+	Delete(ctx context.Context, name string) error
+	// List calls fn for every stored file
+	List(ctx context.Context, fn func(FileInfo) error) error
+	// String describes where files are kept, for logs
+	String() string
+}
+
+// Files is where uploads are stored; InitFileStorage replaces the default local directory
+// with the configured backend
+var Files FileStorage = NewLocalStorage(UploadDir)
+
+// InitFileStorage sets up Files from the environment:
+//
+//	STORAGE_BACKEND  local (default), s3 or memory
+//	UPLOAD_DIR       directory for local storage (default "uploads")
+//	S3_ENDPOINT, S3_REGION, S3_BUCKET, S3_PREFIX, S3_ACCESS_KEY_ID, S3_SECRET_ACCESS_KEY, S3_PATH_STYLE
+func InitFileStorage() error {
+	UploadDir = uploadDirFromEnv()
+	storage, err := NewFileStorage(os.Getenv("STORAGE_BACKEND"))
+	if err != nil {
+		return err
+	}
+	Files = storage
+	return nil
+}
+
+// NewFileStorage creates a storage backend configured from the environment
+func NewFileStorage(backend string) (FileStorage, error) {
+	switch strings.ToLower(strings.TrimSpace(backend)) {
+	case "", StorageBackendLocal:
+		return NewLocalStorage(uploadDirFromEnv()), nil
+	case StorageBackendS3:
+		return NewS3StorageFromEnv()
+	case StorageBackendMemory:
+		return NewMemoryStorage(), nil
+	default:
+		return nil, fmt.Errorf("unknown STORAGE_BACKEND '%s' (use %s, %s or %s)", backend, StorageBackendLocal, StorageBackendS3, StorageBackendMemory)
+	}
+}
+
+// cleanFileName checks that name is a relative path inside the storage root and returns it in canonical form
+func cleanFileName(name string) (string, error) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	cleaned := path.Clean("/" + name)[1:]
+	if cleaned == "" || cleaned != strings.TrimPrefix(name, "./") {
+		return "", fmt.Errorf("%w: %q", ErrInvalidFileName, name)
+	}
+	return cleaned, nil
+}
+
+// readStoredFile returns the whole content of a stored file
+func readStoredFile(ctx context.Context, storage FileStorage, name string) ([]byte, error) {
+	rc, _, err := storage.Open(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
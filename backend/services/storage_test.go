@@ -0,0 +1,66 @@
+package services
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCleanFileName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string // empty when the name must be rejected
+	}{
+		{"report.pdf", "report.pdf"},
+		{"2024/report.pdf", "2024/report.pdf"},
+		{"./report.pdf", "report.pdf"},
+		{`2024\report.pdf`, "2024/report.pdf"},
+		{"", ""},
+		{".", ""},
+		{"/", ""},
+		{"/etc/passwd", ""},
+		{"../report.pdf", ""},
+		{"..", ""},
+		{"2024/../../report.pdf", ""},
+		{"2024/../report.pdf", ""},
+		{`..\..\x.pdf`, ""},
+		{"2024//report.pdf", ""},
+		{"2024/report.pdf/", ""},
+	}
+	for _, tt := range tests {
+		got, err := cleanFileName(tt.name)
+		if tt.want == "" {
+			if !errors.Is(err, ErrInvalidFileName) {
+				t.Errorf("cleanFileName(%q) = %q, %v; want ErrInvalidFileName", tt.name, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("cleanFileName(%q) = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestMemoryStorage(t *testing.T) {
+	ctx := t.Context()
+	storage := NewMemoryStorage()
+
+	if err := storage.Put(ctx, "a/report.pdf", []byte("content"), ContentTypePDF); err != nil {
+		t.Fatal(err)
+	}
+	data, err := readStoredFile(ctx, storage, "./a/report.pdf")
+	if err != nil || string(data) != "content" {
+		t.Fatalf("read back %q, %v", data, err)
+	}
+	if _, err := storage.Stat(ctx, "a/missing.pdf"); !errors.Is(err, ErrStoredFileNotFound) {
+		t.Errorf("Stat of a missing file: %v, want ErrStoredFileNotFound", err)
+	}
+	if err := storage.Put(ctx, "../escape.pdf", nil, ""); !errors.Is(err, ErrInvalidFileName) {
+		t.Errorf("Put outside the root: %v, want ErrInvalidFileName", err)
+	}
+	if err := storage.Delete(ctx, "a/report.pdf"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := storage.Stat(ctx, "a/report.pdf"); !errors.Is(err, ErrStoredFileNotFound) {
+		t.Errorf("Stat after Delete: %v, want ErrStoredFileNotFound", err)
+	}
+}
@@ -0,0 +1,356 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"web_AI/config"
+	"web_AI/models"
+	"web_AI/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// suggestIndexTTL bounds how stale the index can get for data we are not notified about (chat questions)
+const suggestIndexTTL = 5 * time.Minute
+
+// minFAQCount is how many times a question must be asked before it is suggested
+const minFAQCount = 2
+
+// faqWindowDays is how far back questions count towards suggestions
+const faqWindowDays = 90
+
+type suggestEntry struct {
+	text        string
+	kind        string
+	procedureID string
+	weight      int
+	access      models.ProcedureAccess // who may see the suggestion; the zero value is public
+	normalized  string
+	tokens      []string
+}
+
+// suggestIndex is an in-memory prefix index over procedure titles, categories and frequent questions
+type suggestIndex struct {
+	mu       sync.RWMutex
+	entries  []suggestEntry
+	tokens   []string         // sorted, unique
+	postings map[string][]int // token -> entry indexes
+	builtAt  time.Time
+	dirty    bool
+	buildMu  sync.Mutex
+}
+
+var suggestions = &suggestIndex{dirty: true}
+
+// InvalidateSuggestIndex marks the suggestion index for rebuild on the next request
+func InvalidateSuggestIndex() {
+	suggestions.mu.Lock()
+	suggestions.dirty = true
+	suggestions.mu.Unlock()
+}
+
+// SuggestProcedures returns autocomplete suggestions for a partially typed query,
+// leaving out titles of procedures the viewer may not see
+func SuggestProcedures(query string, limit int, viewer models.Viewer) ([]models.Suggestion, error) {
+	if err := suggestions.ensureFresh(); err != nil {
+		return nil, err
+	}
+	return suggestions.search(query, limit, viewer), nil
+}
+
+func (idx *suggestIndex) ensureFresh() error {
+	idx.mu.RLock()
+	fresh := !idx.dirty && time.Since(idx.builtAt) < suggestIndexTTL
+	idx.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+
+	// Only one goroutine rebuilds; the others wait and reuse its result
+	idx.buildMu.Lock()
+	defer idx.buildMu.Unlock()
+	idx.mu.RLock()
+	fresh = !idx.dirty && time.Since(idx.builtAt) < suggestIndexTTL
+	idx.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+
+	entries, err := loadSuggestEntries()
+	if err != nil {
+		return err
+	}
+	idx.rebuild(entries)
+	return nil
+}
+
+func (idx *suggestIndex) rebuild(entries []suggestEntry) {
+	postings := make(map[string][]int)
+	for i := range entries {
+		entries[i].normalized = utils.NormalizeText(entries[i].text)
+		entries[i].tokens = strings.Fields(entries[i].normalized)
+		seen := make(map[string]bool)
+		for _, tok := range entries[i].tokens {
+			if seen[tok] {
+				continue
+			}
+			seen[tok] = true
+			postings[tok] = append(postings[tok], i)
+		}
+	}
+	tokens := make([]string, 0, len(postings))
+	for tok := range postings {
+		tokens = append(tokens, tok)
+	}
+	sort.Strings(tokens)
+
+	idx.mu.Lock()
+	idx.entries = entries
+	idx.tokens = tokens
+	idx.postings = postings
+	idx.builtAt = time.Now()
+	idx.dirty = false
+	idx.mu.Unlock()
+}
+
+// typoBudget allows more mistakes for longer words; short words must match exactly
+func typoBudget(word string) int {
+	n := len([]rune(word))
+	switch {
+	case n >= 8:
+		return 2
+	case n >= 4:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// matchToken scores how well a query word matches an indexed word: 0 means no match
+func matchToken(queryTok, tok string, prefix bool) int {
+	if prefix {
+		if strings.HasPrefix(tok, queryTok) {
+			return 3
+		}
+		if budget := typoBudget(queryTok); budget > 0 && utils.PrefixDistance(queryTok, tok) <= budget {
+			return 1
+		}
+		return 0
+	}
+	if tok == queryTok {
+		return 3
+	}
+	if budget := typoBudget(queryTok); budget > 0 && utils.LevenshteinDistance(queryTok, tok) <= budget {
+		return 1
+	}
+	return 0
+}
+
+func (idx *suggestIndex) search(query string, limit int, viewer models.Viewer) []models.Suggestion {
+	queryToks := utils.Tokenize(query)
+	if len(queryToks) == 0 {
+		return []models.Suggestion{}
+	}
+	last := queryToks[len(queryToks)-1]
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	// Candidate entries come from words matching the last (still being typed) query word
+	candidates := make(map[int]bool)
+	start := sort.SearchStrings(idx.tokens, last)
+	for i := start; i < len(idx.tokens) && strings.HasPrefix(idx.tokens[i], last); i++ {
+		for _, e := range idx.postings[idx.tokens[i]] {
+			candidates[e] = true
+		}
+	}
+	if typoBudget(last) > 0 {
+		for _, tok := range idx.tokens {
+			if !strings.HasPrefix(tok, last) && matchToken(last, tok, true) > 0 {
+				for _, e := range idx.postings[tok] {
+					candidates[e] = true
+				}
+			}
+		}
+	}
+
+	normalizedQuery := strings.Join(queryToks, " ")
+	var results []models.Suggestion
+	// Questions and categories are indexed once per procedure they belong to, so the same text can match more than once
+	seen := make(map[string]bool)
+	for i := range candidates {
+		entry := idx.entries[i]
+		if !CanView(entry.access, viewer) {
+			continue
+		}
+		if entry.procedureID == "" {
+			key := entry.kind + "\x00" + entry.text
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+		}
+		score := 0
+		for qi, qt := range queryToks {
+			best := 0
+			for _, tok := range entry.tokens {
+				if s := matchToken(qt, tok, qi == len(queryToks)-1); s > best {
+					best = s
+				}
+			}
+			if best == 0 {
+				score = 0
+				break
+			}
+			score += best
+		}
+		if score == 0 {
+			continue
+		}
+		if strings.HasPrefix(entry.normalized, normalizedQuery) {
+			score += 5
+		}
+		score += entry.weight
+		results = append(results, models.Suggestion{
+			Text:        entry.text,
+			Type:        entry.kind,
+			ProcedureID: entry.procedureID,
+			Score:       score,
+		})
+	}
+
+	sort.SliceStable(results, func(a, b int) bool {
+		if results[a].Score != results[b].Score {
+			return results[a].Score > results[b].Score
+		}
+		return results[a].Text < results[b].Text
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	if results == nil {
+		results = []models.Suggestion{}
+	}
+	return results
+}
+
+// loadSuggestEntries reads everything the index is built from
+func loadSuggestEntries() ([]suggestEntry, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var entries []suggestEntry
+	// A category is suggested once for every distinct access among its procedures, so a viewer only
+	// sees categories holding at least one procedure they may read
+	categoryAccess := make(map[string]map[string]models.ProcedureAccess)
+
+	// The index is shared by all users; visibility is checked per entry when searching
+	procedures, err := GetProcedures(models.ProcedureQuery{Viewer: systemViewer})
+	if err != nil {
+		return nil, fmt.Errorf("load procedures: %v", err)
+	}
+	accessByID := make(map[string]models.ProcedureAccess, len(procedures))
+	for _, p := range procedures {
+		accessByID[p.ID.Hex()] = p.ProcedureAccess
+		entries = append(entries, suggestEntry{text: p.Title, kind: models.SuggestionTypeTitle, procedureID: p.ID.Hex(), weight: 2, access: p.ProcedureAccess})
+		// Approved translated titles let readers find procedures in their own language
+		for _, t := range p.Translations {
+			if t.Status == models.TranslationStatusApproved && t.Title != p.Title {
+				entries = append(entries, suggestEntry{text: t.Title, kind: models.SuggestionTypeTitle, procedureID: p.ID.Hex(), weight: 2, access: p.ProcedureAccess})
+			}
+		}
+		if p.Category != "" {
+			if categoryAccess[p.Category] == nil {
+				categoryAccess[p.Category] = make(map[string]models.ProcedureAccess)
+			}
+			categoryAccess[p.Category][accessKey(p.ProcedureAccess)] = p.ProcedureAccess
+		}
+	}
+	for name, accesses := range categoryAccess {
+		for _, access := range accesses {
+			entries = append(entries, suggestEntry{text: name, kind: models.SuggestionTypeCategory, weight: 1, access: access})
+		}
+	}
+
+	faqs, err := loadFrequentQuestions(ctx, accessByID)
+	if err != nil {
+		// Questions are a nice-to-have; titles and categories are still useful without them
+		fmt.Printf("⚠️ Suggest: could not load frequent questions: %v\n", err)
+	}
+	entries = append(entries, faqs...)
+
+	return entries, nil
+}
+
+// accessKey identifies procedures that the same viewers may see
+func accessKey(access models.ProcedureAccess) string {
+	departments := append([]string(nil), access.VisibleDepartments...)
+	roles := append([]string(nil), access.VisibleRoles...)
+	sort.Strings(departments)
+	sort.Strings(roles)
+	return access.Visibility + "|" + strings.Join(departments, ",") + "|" + strings.Join(roles, ",")
+}
+
+// loadFrequentQuestions returns questions several people asked the chatbot and got an answer to from
+// a published procedure. Each question is only suggested to viewers who may see that procedure;
+// private chat history is never used.
+func loadFrequentQuestions(ctx context.Context, accessByID map[string]models.ProcedureAccess) ([]suggestEntry, error) {
+	collection := config.GetCollection("question_logs")
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"source":     bson.M{"$in": bson.A{models.QuestionSourceChat, models.QuestionSourceProcedureChat}},
+			"hits":       bson.M{"$gt": 0},
+			"helpful":    bson.M{"$ne": false},
+			"created_at": bson.M{"$gte": time.Now().AddDate(0, 0, -faqWindowDays)},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{
+				"normalized": "$normalized",
+				"procedure":  bson.M{"$ifNull": bson.A{"$procedure_id", bson.M{"$arrayElemAt": bson.A{"$retrieved_ids", 0}}}},
+			},
+			"question": bson.M{"$first": "$question"},
+			"count":    bson.M{"$sum": 1},
+			"askers":   bson.M{"$addToSet": bson.M{"$ifNull": bson.A{"$user_id", ""}}},
+		}}},
+		{{Key: "$match", Value: bson.M{"askers.1": bson.M{"$exists": true}}}}, // at least two different people
+		{{Key: "$sort", Value: bson.M{"count": -1}}},
+		{{Key: "$limit", Value: 1000}},
+	}
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		ID struct {
+			Normalized  string             `bson:"normalized"`
+			ProcedureID primitive.ObjectID `bson:"procedure"`
+		} `bson:"_id"`
+		Question string `bson:"question"`
+		Count    int    `bson:"count"`
+	}
+	if err = cursor.All(ctx, &rows); err != nil {
+		return nil, err
+	}
+
+	var entries []suggestEntry
+	for _, row := range rows {
+		access, ok := accessByID[row.ID.ProcedureID.Hex()]
+		if !ok || row.ID.Normalized == "" || row.Count < minFAQCount || len([]rune(row.Question)) > 150 {
+			continue
+		}
+		weight := 1
+		if row.Count >= 10 {
+			weight = 2
+		}
+		entries = append(entries, suggestEntry{text: strings.TrimSpace(row.Question), kind: models.SuggestionTypeFAQ, weight: weight, access: access})
+	}
+	return entries, nil
+}
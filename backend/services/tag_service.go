@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"web_AI/config"
+	"web_AI/models"
+	"web_AI/utils"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// NormalizeTags lowercases tags, collapses whitespace and drops empty and duplicate tags
+func NormalizeTags(tags []string) []string {
+	normalized := []string{}
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag = strings.Join(strings.Fields(strings.ToLower(tag)), " ")
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// tagFilter matches procedures with any (default) or all of the given tags
+func tagFilter(tags []string, mode string) bson.M {
+	if mode == models.TagModeAll {
+		return bson.M{"tags": bson.M{"$all": tags}}
+	}
+	return bson.M{"tags": bson.M{"$in": tags}}
+}
+
+// GetTags lists the tags used by published procedures the viewer may see, with how many procedures use each
+func GetTags(viewer models.Viewer) ([]models.TagCount, error) {
+	collection := config.GetCollection("procedures")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pipeline := []bson.M{
+		{"$match": visibleTo(bson.M{"tags.0": bson.M{"$exists": true}}, viewer)},
+		{"$unwind": "$tags"},
+		{"$group": bson.M{"_id": "$tags", "count": bson.M{"$sum": 1}}},
+		{"$sort": bson.D{bson.E{Key: "count", Value: -1}, bson.E{Key: "_id", Value: 1}}},
+	}
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	tags := []models.TagCount{}
+	if err = cursor.All(ctx, &tags); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// BulkUpdateTags adds and removes tags on several procedures
+func BulkUpdateTags(req models.BulkTagsRequest, actorID string) (*models.BulkTagsResponse, error) {
+	collection := config.GetCollection("procedures")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	add := NormalizeTags(req.Add)
+	remove := NormalizeTags(req.Remove)
+	if len(add) == 0 && len(remove) == 0 {
+		return nil, fmt.Errorf("add or remove tags are required")
+	}
+
+	ids := bson.A{}
+	for _, id := range req.ProcedureIDs {
+		objID, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid procedure ID '%s'", id)
+		}
+		ids = append(ids, objID)
+	}
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("procedure_ids is required")
+	}
+
+	filter := bson.M{"_id": bson.M{"$in": ids}, "deleted_at": nil}
+	set := bson.M{"updated_at": time.Now(), "updated_by": actorID}
+	result := &models.BulkTagsResponse{}
+
+	// $addToSet and $pull cannot touch the same field in one update, so they run separately
+	if len(add) > 0 {
+		res, err := collection.UpdateMany(ctx, filter, bson.M{
+			"$addToSet": bson.M{"tags": bson.M{"$each": add}},
+			"$set":      set,
+			"$inc":      bson.M{"version": 1},
+		})
+		if err != nil {
+			return nil, err
+		}
+		result.Matched = res.MatchedCount
+		result.Modified = res.ModifiedCount
+	}
+	if len(remove) > 0 {
+		res, err := collection.UpdateMany(ctx, filter, bson.M{
+			"$pull": bson.M{"tags": bson.M{"$in": remove}},
+			"$set":  set,
+			"$inc":  bson.M{"version": 1},
+		})
+		if err != nil {
+			return nil, err
+		}
+		result.Matched = res.MatchedCount
+		if res.ModifiedCount > result.Modified {
+			result.Modified = res.ModifiedCount
+		}
+	}
+
+	InvalidateSuggestIndex()
+
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		fmt.Printf("⚠️ Tags: could not load updated procedures for webhooks: %v\n", err)
+		return result, nil
+	}
+	defer cursor.Close(ctx)
+	var updated []models.Procedure
+	if err := cursor.All(ctx, &updated); err != nil {
+		fmt.Printf("⚠️ Tags: could not load updated procedures for webhooks: %v\n", err)
+		return result, nil
+	}
+	for i := range updated {
+		EmitWebhookEvent(models.WebhookEventProcedureUpdated, procedureWebhookData(&updated[i], "tags", actorID))
+	}
+	return result, nil
+}
+
+// findTagsInText returns the tags the viewer can see that appear as a phrase in text
+func findTagsInText(text string, viewer models.Viewer) []string {
+	tags, err := GetTags(viewer)
+	if err != nil {
+		fmt.Printf("⚠️ Tags: could not load tags: %v\n", err)
+		return nil
+	}
+
+	normalizedText := utils.NormalizeText(text)
+	var found []string
+	for _, tag := range tags {
+		if containsPhrase(normalizedText, utils.NormalizeText(tag.Tag)) {
+			found = append(found, tag.Tag)
+		}
+	}
+	return found
+}
+
+// rankProceduresByTags moves procedures sharing more of the boost tags to the front, keeping search order otherwise
+func rankProceduresByTags(procedures []models.Procedure, boostTags []string) {
+	if len(boostTags) == 0 {
+		return
+	}
+	boost := map[string]bool{}
+	for _, tag := range boostTags {
+		boost[tag] = true
+	}
+	score := func(p models.Procedure) int {
+		n := 0
+		for _, tag := range p.Tags {
+			if boost[tag] {
+				n++
+			}
+		}
+		return n
+	}
+	sort.SliceStable(procedures, func(i, j int) bool {
+		return score(procedures[i]) > score(procedures[j])
+	})
+}
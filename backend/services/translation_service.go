@@ -0,0 +1,308 @@
+package services
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"web_AI/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"golang.org/x/text/language"
+)
+
+var (
+	ErrInvalidLanguage     = errors.New("invalid language")
+	ErrTranslationNotFound = errors.New("translation not found")
+	ErrTranslationFailed   = errors.New("automatic translation failed")
+)
+
+// languageNames are used in prompts to tell the AI which language to write in
+var languageNames = map[string]string{
+	models.LanguageVietnamese: "tiếng Việt",
+	models.LanguageEnglish:    "tiếng Anh (English)",
+}
+
+// languageMatcher matches Accept-Language preferences against models.SupportedLanguages
+var languageMatcher = func() language.Matcher {
+	tags := make([]language.Tag, len(models.SupportedLanguages))
+	for i, lang := range models.SupportedLanguages {
+		tags[i] = language.Make(lang)
+	}
+	return language.NewMatcher(tags)
+}()
+
+// NormalizeLanguage returns the supported language code for a tag such as "en" or "en-US",
+// or "" when the language is not supported
+func NormalizeLanguage(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	if containsString(models.SupportedLanguages, tag) {
+		return tag
+	}
+	return ""
+}
+
+// NegotiateLanguage picks the language to answer in: an explicit choice first, then the user's
+// profile setting, then the Accept-Language header, then models.DefaultLanguage
+func NegotiateLanguage(explicit string, profile string, acceptLanguage string) string {
+	if lang := NormalizeLanguage(explicit); lang != "" {
+		return lang
+	}
+	if lang := NormalizeLanguage(profile); lang != "" {
+		return lang
+	}
+	if acceptLanguage != "" {
+		if tags, _, err := language.ParseAcceptLanguage(acceptLanguage); err == nil && len(tags) > 0 {
+			if _, index, confidence := languageMatcher.Match(tags...); confidence != language.No {
+				return models.SupportedLanguages[index]
+			}
+		}
+	}
+	return models.DefaultLanguage
+}
+
+// sourceLanguage returns the language a procedure is written in
+func sourceLanguage(procedure *models.Procedure) string {
+	if procedure.SourceLanguage == "" {
+		return models.DefaultLanguage
+	}
+	return procedure.SourceLanguage
+}
+
+// sourceHash fingerprints the translatable text so outdated translations can be detected
+func sourceHash(procedure *models.Procedure) string {
+	sum := sha256.Sum256([]byte(procedure.Title + "\n" + procedure.Description + "\n" + procedure.Content + "\n" + RenderStepsContent(procedure.Steps)))
+	return hex.EncodeToString(sum[:16])
+}
+
+// LocalizeProcedure replaces the procedure text with its approved translation in lang when there is one,
+// falling back to the source language, and records the language it is served in.
+// Translations and unapproved enrichments are removed so readers never see drafts.
+func LocalizeProcedure(procedure *models.Procedure, lang string) {
+	procedure.Language = sourceLanguage(procedure)
+	if t, ok := procedure.Translations[lang]; ok && lang != procedure.Language && t.Status == models.TranslationStatusApproved {
+		procedure.Title = t.Title
+		if t.Description != "" {
+			procedure.Description = t.Description
+		}
+		if len(t.Steps) > 0 {
+			procedure.Steps = t.Steps
+		}
+		if t.Content != "" {
+			procedure.Content = t.Content
+		} else if len(t.Steps) > 0 {
+			procedure.Content = RenderStepsContent(t.Steps)
+		}
+		procedure.Language = lang
+	}
+	procedure.Translations = nil
+	procedure.Enrichment = approvedEnrichment(procedure)
+}
+
+// LocalizeProcedures localizes every procedure in the list
+func LocalizeProcedures(procedures []models.Procedure, lang string) {
+	for i := range procedures {
+		LocalizeProcedure(&procedures[i], lang)
+	}
+}
+
+// translationLanguage validates the target language of a translation of procedure
+func translationLanguage(procedure *models.Procedure, lang string) (string, error) {
+	normalized := NormalizeLanguage(lang)
+	if normalized == "" {
+		return "", fmt.Errorf("%w: supported languages are %s", ErrInvalidLanguage, strings.Join(models.SupportedLanguages, ", "))
+	}
+	if normalized == sourceLanguage(procedure) {
+		return "", fmt.Errorf("%w: %s is the source language of this procedure", ErrInvalidLanguage, normalized)
+	}
+	return normalized, nil
+}
+
+// GetTranslations lists the translations of a procedure, flagging those made from older text
+func GetTranslations(id string) (*models.TranslationsResponse, error) {
+	procedure, err := GetProcedureByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sourceHash(procedure)
+	translations := make(map[string]models.ProcedureTranslation, len(procedure.Translations))
+	for lang, t := range procedure.Translations {
+		t.Outdated = t.SourceHash != hash
+		translations[lang] = t
+	}
+
+	return &models.TranslationsResponse{
+		SourceLanguage: sourceLanguage(procedure),
+		Translations:   translations,
+		Total:          int64(len(translations)),
+	}, nil
+}
+
+// UpsertTranslation saves a translation written by an admin; it has to be approved before readers see it
+func UpsertTranslation(id string, lang string, req models.UpsertTranslationRequest, actorID string) (*models.ProcedureTranslation, error) {
+	procedure, err := GetProcedureByID(id)
+	if err != nil {
+		return nil, err
+	}
+	lang, err = translationLanguage(procedure, lang)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Steps) > 0 {
+		if err := validateSteps(req.Steps); err != nil {
+			return nil, err
+		}
+	}
+
+	translation := models.ProcedureTranslation{
+		Title:       strings.TrimSpace(req.Title),
+		Description: req.Description,
+		Content:     req.Content,
+		Steps:       req.Steps,
+	}
+	return saveTranslation(procedure, lang, translation, false, actorID)
+}
+
+// DraftTranslation asks the AI to translate a procedure and saves the result as a draft for review
+func DraftTranslation(id string, lang string, actorID string) (*models.ProcedureTranslation, error) {
+	procedure, err := GetProcedureByID(id)
+	if err != nil {
+		return nil, err
+	}
+	lang, err = translationLanguage(procedure, lang)
+	if err != nil {
+		return nil, err
+	}
+
+	source := models.ProcedureTranslation{
+		Title:       procedure.Title,
+		Description: procedure.Description,
+		Steps:       procedure.Steps,
+	}
+	// Content generated from the steps is regenerated from the translated steps instead
+	if !contentFromSteps(procedure) {
+		source.Content = procedure.Content
+	}
+
+	answer, err := CallMistralAPI(buildTranslationPrompt(source, sourceLanguage(procedure), lang))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTranslationFailed, err)
+	}
+
+	var translation models.ProcedureTranslation
+	if err := json.Unmarshal([]byte(extractJSONObject(answer)), &translation); err != nil {
+		return nil, fmt.Errorf("%w: unreadable response: %v", ErrTranslationFailed, err)
+	}
+	if strings.TrimSpace(translation.Title) == "" {
+		return nil, fmt.Errorf("%w: the translation has no title", ErrTranslationFailed)
+	}
+	if len(translation.Steps) > 0 {
+		if err := validateSteps(translation.Steps); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrTranslationFailed, err)
+		}
+	}
+
+	return saveTranslation(procedure, lang, translation, true, actorID)
+}
+
+// buildTranslationPrompt asks for the procedure text translated in the same JSON shape
+func buildTranslationPrompt(source models.ProcedureTranslation, from string, to string) string {
+	payload, _ := json.MarshalIndent(struct {
+		Title       string                 `json:"title"`
+		Description string                 `json:"description,omitempty"`
+		Content     string                 `json:"content,omitempty"`
+		Steps       []models.ProcedureStep `json:"steps,omitempty"`
+	}{source.Title, source.Description, source.Content, source.Steps}, "", "  ")
+
+	return fmt.Sprintf(`Dịch quy trình nội bộ sau từ %s sang %s.
+
+YÊU CẦU:
+1. Giữ nguyên cấu trúc JSON, số lượng và thứ tự các bước
+2. Không dịch tên riêng, mã biểu mẫu, số liệu
+3. Chỉ trả về JSON hợp lệ, không giải thích thêm
+
+QUY TRÌNH (JSON):
+%s`, languageNames[from], languageNames[to], payload)
+}
+
+// extractJSONObject returns the outermost JSON object in an AI answer, which may wrap it in prose or code fences
+func extractJSONObject(answer string) string {
+	start := strings.Index(answer, "{")
+	end := strings.LastIndex(answer, "}")
+	if start < 0 || end < start {
+		return answer
+	}
+	return answer[start : end+1]
+}
+
+// saveTranslation stores a translation as a draft made from the current source text
+func saveTranslation(procedure *models.Procedure, lang string, translation models.ProcedureTranslation, machine bool, actorID string) (*models.ProcedureTranslation, error) {
+	translation.Status = models.TranslationStatusDraft
+	translation.MachineDraft = machine
+	translation.SourceHash = sourceHash(procedure)
+	translation.UpdatedAt = time.Now()
+	translation.UpdatedBy = actorID
+
+	update := bson.M{"$set": bson.M{"translations." + lang: translation}}
+	if err := updateProcedureFields(procedure.ID, update); err != nil {
+		return nil, err
+	}
+
+	// Saving always resets the status, so readers fall back to the source language until it is approved again
+	InvalidateSuggestIndex()
+	return &translation, nil
+}
+
+// ApproveTranslation publishes a translation to readers
+func ApproveTranslation(id string, lang string, actorID string) (*models.ProcedureTranslation, error) {
+	procedure, err := GetProcedureByID(id)
+	if err != nil {
+		return nil, err
+	}
+	lang = NormalizeLanguage(lang)
+	translation, ok := procedure.Translations[lang]
+	if !ok {
+		return nil, ErrTranslationNotFound
+	}
+
+	now := time.Now()
+	translation.Status = models.TranslationStatusApproved
+	translation.ApprovedAt = &now
+	translation.ApprovedBy = actorID
+
+	update := bson.M{"$set": bson.M{"translations." + lang: translation}}
+	if err := updateProcedureFields(procedure.ID, update); err != nil {
+		return nil, err
+	}
+
+	InvalidateSuggestIndex()
+	translation.Outdated = translation.SourceHash != sourceHash(procedure)
+	return &translation, nil
+}
+
+// DeleteTranslation removes a translation; readers get the source language again
+func DeleteTranslation(id string, lang string) error {
+	procedure, err := GetProcedureByID(id)
+	if err != nil {
+		return err
+	}
+	lang = NormalizeLanguage(lang)
+	if _, ok := procedure.Translations[lang]; !ok {
+		return ErrTranslationNotFound
+	}
+
+	update := bson.M{"$unset": bson.M{"translations." + lang: ""}}
+	if err := updateProcedureFields(procedure.ID, update); err != nil {
+		return err
+	}
+
+	InvalidateSuggestIndex()
+	return nil
+}
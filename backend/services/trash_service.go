@@ -0,0 +1,261 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+	"web_AI/config"
+	"web_AI/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TrashRetentionDays returns how many days deleted items are kept, from TRASH_RETENTION_DAYS (default 30).
+// Zero or a negative value disables automatic purging.
+func TrashRetentionDays() int {
+	value := os.Getenv("TRASH_RETENTION_DAYS")
+	if value == "" {
+		return 30
+	}
+	days, err := strconv.Atoi(value)
+	if err != nil {
+		return 30
+	}
+	return days
+}
+
+// GetDeletedProcedures lists procedures in the trash, most recently deleted first
+func GetDeletedProcedures() ([]models.Procedure, error) {
+	collection := config.GetCollection("procedures")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{bson.E{Key: "deleted_at", Value: -1}})
+	cursor, err := collection.Find(ctx, bson.M{"deleted_at": bson.M{"$ne": nil}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	procedures := []models.Procedure{}
+	if err = cursor.All(ctx, &procedures); err != nil {
+		return nil, err
+	}
+
+	return procedures, nil
+}
+
+// RestoreProcedure takes a procedure out of the trash
+func RestoreProcedure(id string, actorID string) error {
+	collection := config.GetCollection("procedures")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("invalid procedure ID")
+	}
+
+	filter := bson.M{"_id": objID, "deleted_at": bson.M{"$ne": nil}}
+	update := bson.M{"$unset": bson.M{"deleted_at": "", "deleted_by": ""}, "$inc": bson.M{"version": 1}}
+	var restored models.Procedure
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err = collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&restored)
+	if err == mongo.ErrNoDocuments {
+		return ErrProcedureNotFound
+	} else if err != nil {
+		return err
+	}
+
+	InvalidateSuggestIndex()
+	EmitWebhookEvent(models.WebhookEventProcedureUpdated, procedureWebhookData(&restored, "restore_from_trash", actorID))
+	return nil
+}
+
+// PurgeProcedure permanently deletes a procedure in the trash together with its revision history
+func PurgeProcedure(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("invalid procedure ID")
+	}
+
+	count, err := purgeProcedures(ctx, bson.M{"_id": objID, "deleted_at": bson.M{"$ne": nil}})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrProcedureNotFound
+	}
+	return nil
+}
+
+// purgeProcedures hard-deletes the procedures matching filter and their revisions
+func purgeProcedures(ctx context.Context, filter bson.M) (int64, error) {
+	collection := config.GetCollection("procedures")
+
+	ids, err := collection.Distinct(ctx, "_id", filter)
+	if err != nil || len(ids) == 0 {
+		return 0, err
+	}
+	byID := bson.M{"procedure_id": bson.M{"$in": ids}}
+
+	// Remember the uploaded files before their references are gone
+	fileKeys, err := collection.Distinct(ctx, "file_key", bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return 0, err
+	}
+	importFiles, err := config.GetCollection("procedure_imports").Distinct(ctx, "storage_name", byID)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return 0, err
+	}
+	// Revisions, reader feedback, checklist runs and the imports that produced the procedure belong to it and go with it
+	for _, name := range []string{"procedure_revisions", "procedure_ratings", "procedure_comments", "procedure_runs", "procedure_imports"} {
+		if _, err := config.GetCollection(name).DeleteMany(ctx, byID); err != nil {
+			return result.DeletedCount, err
+		}
+	}
+
+	var storageNames []string
+	for _, name := range append(fileKeys, importFiles...) {
+		if s, ok := name.(string); ok && s != "" && !containsString(storageNames, s) {
+			storageNames = append(storageNames, s)
+		}
+	}
+	if err := deleteUnusedUploads(ctx, storageNames); err != nil {
+		fmt.Printf("⚠️ Failed to delete uploaded files of purged procedures: %v\n", err)
+	}
+
+	return result.DeletedCount, nil
+}
+
+// GetDeletedChatConversations lists a user's conversations in the trash
+func GetDeletedChatConversations(userIDStr string) ([]models.ChatConversation, error) {
+	collection := config.GetCollection("chat_conversations")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	userObjID, err := primitive.ObjectIDFromHex(userIDStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID: %v", err)
+	}
+
+	filter := bson.M{"user_id": userObjID, "deleted_at": bson.M{"$ne": nil}}
+	opts := options.Find().SetSort(bson.D{bson.E{Key: "deleted_at", Value: -1}})
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	conversations := []models.ChatConversation{}
+	if err = cursor.All(ctx, &conversations); err != nil {
+		return nil, err
+	}
+
+	return conversations, nil
+}
+
+// RestoreChatConversation takes one of the user's conversations out of the trash
+func RestoreChatConversation(userIDStr, conversationID string) error {
+	return updateDeletedConversation(userIDStr, conversationID, false)
+}
+
+// PurgeChatConversation permanently deletes one of the user's conversations in the trash
+func PurgeChatConversation(userIDStr, conversationID string) error {
+	return updateDeletedConversation(userIDStr, conversationID, true)
+}
+
+func updateDeletedConversation(userIDStr, conversationID string, purge bool) error {
+	collection := config.GetCollection("chat_conversations")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	userObjID, err := primitive.ObjectIDFromHex(userIDStr)
+	if err != nil {
+		return fmt.Errorf("invalid user ID: %v", err)
+	}
+	convObjID, err := primitive.ObjectIDFromHex(conversationID)
+	if err != nil {
+		return fmt.Errorf("invalid conversation ID: %v", err)
+	}
+
+	filter := bson.M{"_id": convObjID, "user_id": userObjID, "deleted_at": bson.M{"$ne": nil}}
+	var matched int64
+	if purge {
+		result, err := collection.DeleteOne(ctx, filter)
+		if err != nil {
+			return err
+		}
+		matched = result.DeletedCount
+	} else {
+		result, err := collection.UpdateOne(ctx, filter, bson.M{"$unset": bson.M{"deleted_at": ""}})
+		if err != nil {
+			return err
+		}
+		matched = result.MatchedCount
+	}
+	if matched == 0 {
+		return fmt.Errorf("conversation not found in trash")
+	}
+	return nil
+}
+
+// PurgeExpiredTrash permanently deletes procedures and conversations that have been in the
+// trash longer than the retention period
+func PurgeExpiredTrash() (*models.PurgeTrashResponse, error) {
+	result := &models.PurgeTrashResponse{}
+	days := TrashRetentionDays()
+	if days <= 0 {
+		return result, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	cutoff := time.Now().AddDate(0, 0, -days)
+	expired := bson.M{"deleted_at": bson.M{"$ne": nil, "$lte": cutoff}}
+
+	procedures, err := purgeProcedures(ctx, expired)
+	if err != nil {
+		return nil, err
+	}
+	result.Procedures = procedures
+
+	conversations, err := config.GetCollection("chat_conversations").DeleteMany(ctx, expired)
+	if err != nil {
+		return nil, err
+	}
+	result.Conversations = conversations.DeletedCount
+
+	return result, nil
+}
+
+// StartTrashPurgeScheduler purges expired trash now and then on every interval
+func StartTrashPurgeScheduler(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			result, err := PurgeExpiredTrash()
+			if err != nil {
+				fmt.Printf("⚠️ Trash purge failed: %v\n", err)
+			} else if result.Procedures > 0 || result.Conversations > 0 {
+				fmt.Printf("🗑️ Purged %d procedures and %d conversations from the trash\n", result.Procedures, result.Conversations)
+			}
+			<-ticker.C
+		}
+	}()
+}
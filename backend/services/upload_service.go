@@ -0,0 +1,385 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+	"web_AI/config"
+	"web_AI/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	ErrUnsupportedFileType = errors.New("only PDF and Word documents are allowed")
+	ErrFileTooLarge        = errors.New("file size cannot exceed 10MB")
+)
+
+// Document types accepted for upload
+const (
+	ContentTypePDF  = "application/pdf"
+	ContentTypeDOC  = "application/msword"
+	ContentTypeDOCX = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+)
+
+// MaxUploadSize is the largest file that can be uploaded
+const MaxUploadSize = 10 * 1024 * 1024
+
+const maxFileNameLength = 200 // bytes, well below the 255 most file systems allow
+
+var fileExtensions = map[string]string{
+	ContentTypePDF:  ".pdf",
+	ContentTypeDOC:  ".doc",
+	ContentTypeDOCX: ".docx",
+}
+
+var (
+	pdfMagic = []byte("%PDF-")
+	zipMagic = []byte("PK\x03\x04")
+	oleMagic = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1} // compound file used by .doc, .xls and .ppt
+	// Name of the stream only Word documents have, as stored in the compound file directory (UTF-16LE)
+	wordDocumentStream = []byte("W\x00o\x00r\x00d\x00D\x00o\x00c\x00u\x00m\x00e\x00n\x00t\x00")
+)
+
+// DetectFileType identifies a PDF or Word document by its content; the name and the Content-Type
+// sent by the client are ignored because both are easy to fake
+func DetectFileType(data []byte) (string, error) {
+	switch {
+	case bytes.HasPrefix(data, pdfMagic):
+		return ContentTypePDF, nil
+	case bytes.HasPrefix(data, zipMagic):
+		// Any Office Open XML file is a ZIP archive; a Word document has word/document.xml
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return "", ErrUnsupportedFileType
+		}
+		for _, f := range zr.File {
+			if f.Name == "word/document.xml" {
+				return ContentTypeDOCX, nil
+			}
+		}
+	case bytes.HasPrefix(data, oleMagic):
+		if bytes.Contains(data, wordDocumentStream) {
+			return ContentTypeDOC, nil
+		}
+	}
+	return "", ErrUnsupportedFileType
+}
+
+// SanitizeFileName turns a client-supplied file name into a plain name that is safe to show and to send
+// in headers: no directories, control characters or reserved characters, at most maxFileNameLength bytes
+// and ending in the extension of the detected type
+func SanitizeFileName(name string, contentType string) string {
+	// Browsers on Windows may send the full path
+	name = strings.ReplaceAll(name, "\\", "/")
+	name = norm.NFC.String(filepath.Base("/" + name))
+	if name == "/" {
+		// Nothing but slashes (or no name at all)
+		name = ""
+	}
+
+	var sb strings.Builder
+	for _, r := range name {
+		switch {
+		case unicode.IsControl(r), strings.ContainsRune(`/:*?"<>|`, r):
+			sb.WriteRune('_')
+		case unicode.IsSpace(r):
+			sb.WriteRune(' ')
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	name = strings.Trim(sb.String(), " .")
+
+	ext := fileExtensions[contentType]
+	base := strings.TrimRight(strings.TrimSuffix(name, filepath.Ext(name)), " .")
+	if base == "" {
+		base = "document"
+	}
+	if len(base)+len(ext) > maxFileNameLength {
+		cut := maxFileNameLength - len(ext)
+		for cut > 0 && !utf8.RuneStart(base[cut]) {
+			cut--
+		}
+		base = strings.TrimRight(base[:cut], " .")
+	}
+	return base + ext
+}
+
+// EnsureUploadIndexes makes the content hash unique so identical uploads share one record
+func EnsureUploadIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := config.GetCollection("uploaded_files").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "hash", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// SaveUpload validates an uploaded file by its content and stores it under the SHA-256 of that content,
+// returning the record and the content. Uploading the same content again reuses the stored file,
+// whatever its name.
+func SaveUpload(file *multipart.FileHeader, uploadedBy string) (*models.StoredFile, []byte, error) {
+	if file.Size > MaxUploadSize {
+		return nil, nil, ErrFileTooLarge
+	}
+	src, err := file.Open()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer src.Close()
+
+	data, err := io.ReadAll(io.LimitReader(src, MaxUploadSize+1))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(data) > MaxUploadSize {
+		return nil, nil, ErrFileTooLarge
+	}
+	contentType, err := DetectFileType(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+	storageName := hash + fileExtensions[contentType]
+	fileName := SanitizeFileName(file.Filename, contentType)
+
+	existed, err := writeStoredFile(storageName, data, contentType)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	stored, err := recordStoredFile(hash, storageName, fileName, contentType, int64(len(data)), uploadedBy)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The trash purge may have removed an unused copy between the check above and the record
+	if existed {
+		if _, err := writeStoredFile(storageName, data, contentType); err != nil {
+			return nil, nil, err
+		}
+	}
+	stored.FileName = fileName
+	stored.Duplicate = existed || stored.UploadCount > 1
+	return stored, data, nil
+}
+
+// writeStoredFile stores data unless a file with that name, and so that content, is already there
+func writeStoredFile(storageName string, data []byte, contentType string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	if _, err := Files.Stat(ctx, storageName); err == nil {
+		return true, nil
+	} else if !errors.Is(err, ErrStoredFileNotFound) {
+		return false, err
+	}
+	return false, Files.Put(ctx, storageName, data, contentType)
+}
+
+// recordStoredFile adds or updates the metadata of a stored file; the first upload's name is kept
+func recordStoredFile(hash, storageName, fileName, contentType string, size int64, uploadedBy string) (*models.StoredFile, error) {
+	collection := config.GetCollection("uploaded_files")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	update := bson.M{
+		"$setOnInsert": bson.M{
+			"storage_name":  storageName,
+			"original_name": fileName,
+			"content_type":  contentType,
+			"size":          size,
+			"uploaded_by":   uploadedBy,
+			"created_at":    now,
+		},
+		"$inc": bson.M{"upload_count": 1},
+		"$set": bson.M{"updated_at": now},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var stored models.StoredFile
+	err := collection.FindOneAndUpdate(ctx, bson.M{"hash": hash}, update, opts).Decode(&stored)
+	if mongo.IsDuplicateKeyError(err) {
+		// Another upload of the same content inserted the record first
+		err = collection.FindOneAndUpdate(ctx, bson.M{"hash": hash}, update, opts).Decode(&stored)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &stored, nil
+}
+
+// deleteUnusedUploads removes stored files and their records once no procedure (including those in
+// the trash) or import refers to them any more. The same content is shared by every upload of it,
+// so a file can only go when its last user does.
+func deleteUnusedUploads(ctx context.Context, storageNames []string) error {
+	uploads := config.GetCollection("uploaded_files")
+	for _, name := range storageNames {
+		// Read before the references are checked, so an upload of the same content in between is noticed
+		var record models.StoredFile
+		err := uploads.FindOne(ctx, bson.M{"storage_name": name}).Decode(&record)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return err
+		}
+
+		used, err := config.GetCollection("procedures").CountDocuments(ctx, bson.M{"file_key": name}, options.Count().SetLimit(1))
+		if err != nil {
+			return err
+		}
+		if used == 0 {
+			used, err = config.GetCollection("procedure_imports").CountDocuments(ctx, bson.M{"storage_name": name}, options.Count().SetLimit(1))
+			if err != nil {
+				return err
+			}
+		}
+		if used > 0 {
+			continue
+		}
+
+		// The record goes first and only if nobody uploaded the content again since it was read;
+		// an upload after that writes the file again if it is gone (see SaveUpload)
+		if !record.ID.IsZero() {
+			result, err := uploads.DeleteOne(ctx, bson.M{"_id": record.ID, "upload_count": record.UploadCount})
+			if err != nil {
+				return err
+			}
+			if result.DeletedCount == 0 {
+				continue
+			}
+		}
+		if err := Files.Delete(ctx, name); err != nil {
+			return fmt.Errorf("delete %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// MigrateUploads moves every file from source into Files. PDF and Word files that are still stored
+// under their original name are renamed to their content hash and recorded like new uploads.
+// With keep the source files are left in place; with dryRun nothing is written.
+func MigrateUploads(source FileStorage, keep bool, dryRun bool) (*models.UploadMigrationReport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	report := &models.UploadMigrationReport{Source: source.String(), Target: Files.String(), DryRun: dryRun, Warnings: []string{}}
+	sameStorage := source.String() == Files.String()
+
+	var names []string
+	if err := source.List(ctx, func(info FileInfo) error {
+		names = append(names, info.Name)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+		if err := migrateUpload(ctx, source, name, sameStorage, keep, dryRun, report); err != nil {
+			report.Failed++
+			report.Warnings = append(report.Warnings, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+	return report, nil
+}
+
+func migrateUpload(ctx context.Context, source FileStorage, name string, sameStorage, keep, dryRun bool, report *models.UploadMigrationReport) error {
+	data, err := readStoredFile(ctx, source, name)
+	if err != nil {
+		return err
+	}
+
+	target := name
+	hash := ""
+	contentType, typeErr := DetectFileType(data)
+	if typeErr == nil {
+		sum := sha256.Sum256(data)
+		hash = hex.EncodeToString(sum[:])
+		target = hash + fileExtensions[contentType]
+	} else {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("%s is not a PDF or Word document; moved under the same name", name))
+	}
+
+	if sameStorage && target == name {
+		report.Skipped++
+		return nil
+	}
+
+	existing, err := Files.Stat(ctx, target)
+	if err != nil && !errors.Is(err, ErrStoredFileNotFound) {
+		return err
+	}
+	if existing != nil {
+		// Content-addressed names cannot hold different content; other names might
+		if typeErr != nil {
+			stored, err := readStoredFile(ctx, Files, target)
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(stored, data) {
+				report.Warnings = append(report.Warnings, fmt.Sprintf("kept %s: the target already has a different file with that name", name))
+				report.Skipped++
+				return nil
+			}
+		}
+		report.Skipped++
+	} else if !dryRun {
+		if err := Files.Put(ctx, target, data, contentType); err != nil {
+			return err
+		}
+	}
+
+	// The record is only written once the file is in place, so it never points at a missing file
+	if target != name && !dryRun {
+		if err := recordMigration(ctx, hash, target, SanitizeFileName(path.Base(name), contentType), contentType, int64(len(data))); err != nil {
+			return err
+		}
+	}
+	if existing == nil {
+		report.Moved++
+		if target != name {
+			report.Renamed++
+		}
+	}
+
+	if !keep && !dryRun {
+		return source.Delete(ctx, name)
+	}
+	return nil
+}
+
+// recordMigration stores the record of a renamed file; tests replace it to run without a database
+var recordMigration = recordMigratedFile
+
+// recordMigratedFile adds the metadata of a file that was uploaded before uploads were recorded
+func recordMigratedFile(ctx context.Context, hash, storageName, fileName, contentType string, size int64) error {
+	now := time.Now()
+	_, err := config.GetCollection("uploaded_files").UpdateOne(ctx, bson.M{"hash": hash}, bson.M{"$setOnInsert": bson.M{
+		"storage_name":  storageName,
+		"original_name": fileName,
+		"content_type":  contentType,
+		"size":          size,
+		"upload_count":  1,
+		"created_at":    now,
+		"updated_at":    now,
+	}}, options.Update().SetUpsert(true))
+	return err
+}
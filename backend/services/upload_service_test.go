@@ -0,0 +1,214 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestMigrateUploads(t *testing.T) {
+	pdf := []byte("%PDF-1.7\nreport")
+	notes := []byte("plain notes")
+	sum := sha256.Sum256(pdf)
+	hash := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name     string
+		dryRun   bool
+		migrated bool // the PDF is already in the target under its hash
+		moved    int
+		renamed  int
+		skipped  int
+		records  int
+	}{
+		{name: "move", moved: 2, renamed: 1, records: 1},
+		{name: "dry run", dryRun: true, moved: 2, renamed: 1},
+		{name: "already migrated", migrated: true, moved: 1, skipped: 1, records: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := t.Context()
+			source := NewMemoryStorage()
+			if err := source.Put(ctx, "report.pdf", pdf, ContentTypePDF); err != nil {
+				t.Fatal(err)
+			}
+			if err := source.Put(ctx, "notes.txt", notes, ""); err != nil {
+				t.Fatal(err)
+			}
+
+			target := NewMemoryStorage()
+			if tt.migrated {
+				if err := target.Put(ctx, hash+".pdf", pdf, ContentTypePDF); err != nil {
+					t.Fatal(err)
+				}
+			}
+			oldFiles, oldRecord := Files, recordMigration
+			t.Cleanup(func() { Files, recordMigration = oldFiles, oldRecord })
+			Files = target
+
+			records := 0
+			recordMigration = func(ctx context.Context, gotHash, storageName, fileName, contentType string, size int64) error {
+				records++
+				// The record must never point at a file that is not stored yet
+				if _, err := target.Stat(ctx, storageName); err != nil {
+					t.Errorf("record for %s written before the file: %v", storageName, err)
+				}
+				if gotHash != hash || storageName != hash+".pdf" || fileName != "report.pdf" || contentType != ContentTypePDF || size != int64(len(pdf)) {
+					t.Errorf("record = %s %s %s %s %d", gotHash, storageName, fileName, contentType, size)
+				}
+				return nil
+			}
+
+			report, err := MigrateUploads(source, false, tt.dryRun)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Moved != tt.moved || report.Renamed != tt.renamed || report.Skipped != tt.skipped || report.Failed != 0 || len(report.Warnings) != 1 {
+				t.Errorf("report = %+v", report)
+			}
+			if records != tt.records {
+				t.Errorf("records = %d, want %d", records, tt.records)
+			}
+
+			for _, name := range []string{hash + ".pdf", "notes.txt"} {
+				_, err := target.Stat(ctx, name)
+				if tt.dryRun && !errors.Is(err, ErrStoredFileNotFound) {
+					t.Errorf("dry run stored %s", name)
+				}
+				if !tt.dryRun && err != nil {
+					t.Errorf("%s not migrated: %v", name, err)
+				}
+			}
+			for _, name := range []string{"report.pdf", "notes.txt"} {
+				_, err := source.Stat(ctx, name)
+				if tt.dryRun && err != nil {
+					t.Errorf("dry run removed %s from the source", name)
+				}
+				if !tt.dryRun && !errors.Is(err, ErrStoredFileNotFound) {
+					t.Errorf("%s left in the source", name)
+				}
+			}
+		})
+	}
+}
+
+func zipFile(t *testing.T, names ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range names {
+		if _, err := zw.Create(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDetectFileType(t *testing.T) {
+	ole := append([]byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}, make([]byte, 64)...)
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{name: "pdf", data: []byte("%PDF-1.4\n"), want: ContentTypePDF},
+		{name: "docx", data: zipFile(t, "[Content_Types].xml", "word/document.xml"), want: ContentTypeDOCX},
+		{name: "doc", data: append(ole, wordDocumentStream...), want: ContentTypeDOC},
+		{name: "renamed windows executable", data: []byte("MZ\x90\x00\x03\x00\x00\x00\x04\x00")},
+		{name: "renamed linux executable", data: []byte("\x7fELF\x02\x01\x01\x00")},
+		{name: "spreadsheet", data: zipFile(t, "[Content_Types].xml", "xl/workbook.xml")},
+		{name: "other compound file", data: ole},
+		{name: "truncated zip", data: []byte("PK\x03\x04garbage")},
+		{name: "pdf marker not at start", data: []byte(" %PDF-1.4")},
+		{name: "empty"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DetectFileType(tt.data)
+			if tt.want == "" {
+				if !errors.Is(err, ErrUnsupportedFileType) {
+					t.Errorf("DetectFileType() = %q, %v; want ErrUnsupportedFileType", got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("DetectFileType() = %q, %v; want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeFileName(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		want        string
+	}{
+		{name: "Quy trình nghỉ phép.pdf", contentType: ContentTypePDF, want: "Quy trình nghỉ phép.pdf"},
+		{name: "setup.exe", contentType: ContentTypePDF, want: "setup.pdf"},
+		{name: "report.pdf.exe", contentType: ContentTypeDOCX, want: "report.pdf.docx"},
+		{name: `..\..\x.pdf`, contentType: ContentTypePDF, want: "x.pdf"},
+		{name: "../../etc/passwd", contentType: ContentTypeDOC, want: "passwd.doc"},
+		{name: `C:\Users\an\Desktop\hợp đồng.docx`, contentType: ContentTypeDOCX, want: "hợp đồng.docx"},
+		{name: "a\x00b\nc\x7f.pdf", contentType: ContentTypePDF, want: "a_b_c_.pdf"},
+		{name: "\u202eexe.pdf", contentType: ContentTypePDF, want: "\u202eexe.pdf"},
+		{name: `what?<is>"this"|.pdf`, contentType: ContentTypePDF, want: "what__is__this__.pdf"},
+		{name: "tab\there.pdf", contentType: ContentTypePDF, want: "tab_here.pdf"},
+		{name: "e\u0323\u0302.pdf", contentType: ContentTypePDF, want: "\u1ec7.pdf"},
+		{name: "..", contentType: ContentTypePDF, want: "document.pdf"},
+		{name: " . ", contentType: ContentTypePDF, want: "document.pdf"},
+		{name: `dir\`, contentType: ContentTypePDF, want: "dir.pdf"},
+		{name: "///", contentType: ContentTypePDF, want: "document.pdf"},
+		{name: "", contentType: ContentTypeDOCX, want: "document.docx"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SanitizeFileName(tt.name, tt.contentType); got != tt.want {
+				t.Errorf("SanitizeFileName(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeFileNameOverlong(t *testing.T) {
+	tests := []struct {
+		desc        string
+		name        string
+		contentType string
+	}{
+		{desc: "3-byte runes, cut falls inside one", name: strings.Repeat("ệ", 100) + ".pdf", contentType: ContentTypePDF},
+		{desc: "3-byte runes", name: strings.Repeat("文", 150) + ".docx", contentType: ContentTypeDOCX},
+		{desc: "4-byte runes", name: strings.Repeat("😀", 80) + ".doc", contentType: ContentTypeDOC},
+		{desc: "shifted by one byte", name: "a" + strings.Repeat("ệ", 100) + ".pdf", contentType: ContentTypePDF},
+		{desc: "trailing dots left by the cut", name: strings.Repeat("x", 194) + strings.Repeat(" .", 10) + "y.pdf", contentType: ContentTypePDF},
+		{desc: "shorter once normalized", name: strings.Repeat("e\u0323\u0302", 80) + ".pdf", contentType: ContentTypePDF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got := SanitizeFileName(tt.name, tt.contentType)
+			ext := fileExtensions[tt.contentType]
+			if len(got) > maxFileNameLength {
+				t.Errorf("len = %d, want at most %d", len(got), maxFileNameLength)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("%q is not valid UTF-8", got)
+			}
+			if !strings.HasSuffix(got, ext) {
+				t.Errorf("%q does not end in %s", got, ext)
+			}
+			if base := strings.TrimSuffix(got, ext); base == "" || strings.HasSuffix(base, ".") || strings.HasSuffix(base, " ") {
+				t.Errorf("base %q is empty or ends in a dot or space", base)
+			}
+		})
+	}
+}
@@ -11,6 +11,7 @@ import (
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 func RegisterUser(name, email, password string) (*models.User, error) {
@@ -45,6 +46,7 @@ func RegisterUser(name, email, password string) (*models.User, error) {
 	}
 
 	user.Password = "" // Ẩn password trả về
+	EmitWebhookEvent(models.WebhookEventUserCreated, userWebhookData(&user))
 	return &user, nil
 }
 
@@ -85,25 +87,71 @@ func GetAllUsers() ([]models.User, error) {
 	return users, nil
 }
 
-// UpdateUser cập nhật thông tin user
-func UpdateUser(id string, updateData models.User) error {
+// GetUserByID trả về user theo ID (không trả về password)
+func GetUserByID(id string) (*models.User, error) {
 	userCol := config.DB.Collection("users")
 	objID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return errors.New("ID không hợp lệ")
+		return nil, errors.New("ID không hợp lệ")
 	}
 
-	// Không cho phép cập nhật password qua API này
-	updateData.Password = ""
+	var user models.User
+	if err := userCol.FindOne(context.TODO(), bson.M{"_id": objID}).Decode(&user); err != nil {
+		return nil, errors.New("Không tìm thấy user")
+	}
+	user.Password = ""
+	return &user, nil
+}
 
-	update := bson.M{
-		"$set": bson.M{
-			"name":  updateData.Name,
-			"email": updateData.Email,
-			"role":  updateData.Role,
-		},
+// GetUserNames trả về tên hiển thị theo ID của các user; ID không hợp lệ hoặc không tồn tại bị bỏ qua
+func GetUserNames(ids []string) (map[string]string, error) {
+	userCol := config.DB.Collection("users")
+	var objIDs []primitive.ObjectID
+	for _, id := range ids {
+		if objID, err := primitive.ObjectIDFromHex(id); err == nil {
+			objIDs = append(objIDs, objID)
+		}
+	}
+	names := make(map[string]string)
+	if len(objIDs) == 0 {
+		return names, nil
 	}
 
+	opts := options.Find().SetProjection(bson.M{"name": 1})
+	cursor, err := userCol.Find(context.TODO(), bson.M{"_id": bson.M{"$in": objIDs}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.TODO())
+
+	for cursor.Next(context.TODO()) {
+		var user models.User
+		if err := cursor.Decode(&user); err == nil {
+			names[user.ID.Hex()] = user.Name
+		}
+	}
+	return names, nil
+}
+
+// UpdateUser cập nhật thông tin user (không đổi password qua API này)
+func UpdateUser(id string, updateData models.UpdateUserRequest) error {
+	userCol := config.DB.Collection("users")
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.New("ID không hợp lệ")
+	}
+
+	set := bson.M{
+		"name":  updateData.Name,
+		"email": updateData.Email,
+		"role":  updateData.Role,
+	}
+	// Phòng ban quyết định quyền xem quy trình, nên chỉ đổi khi được gửi lên
+	if updateData.Department != nil {
+		set["department"] = strings.TrimSpace(*updateData.Department)
+	}
+	update := bson.M{"$set": set}
+
 	result, err := userCol.UpdateOne(context.TODO(), bson.M{"_id": objID}, update)
 	if err != nil {
 		return err
@@ -114,6 +162,24 @@ func UpdateUser(id string, updateData models.User) error {
 	return nil
 }
 
+// UpdateUserLanguage lưu ngôn ngữ trả lời ưa thích của user
+func UpdateUserLanguage(id string, language string) error {
+	userCol := config.DB.Collection("users")
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.New("ID không hợp lệ")
+	}
+
+	result, err := userCol.UpdateOne(context.TODO(), bson.M{"_id": objID}, bson.M{"$set": bson.M{"language": language}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("Không tìm thấy user")
+	}
+	return nil
+}
+
 // DeleteUser xóa user theo ID
 func DeleteUser(id string) error {
 	userCol := config.DB.Collection("users")
@@ -160,5 +226,6 @@ func CreateUser(user models.User) (*models.User, error) {
 	}
 
 	user.Password = "" // Ẩn password trước khi trả về
+	EmitWebhookEvent(models.WebhookEventUserCreated, userWebhookData(&user))
 	return &user, nil
 }
@@ -0,0 +1,178 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"web_AI/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrInvalidVisibility wraps validation errors in procedure visibility settings
+var ErrInvalidVisibility = errors.New("invalid visibility")
+
+// systemViewer sees every published procedure; it is used by background jobs and shared indexes
+var systemViewer = models.Viewer{IsAdmin: true}
+
+// viewerForUser describes what a stored user may see, for checks made on someone else's behalf
+func viewerForUser(user *models.User) models.Viewer {
+	return models.Viewer{
+		UserID:        user.ID.Hex(),
+		Role:          user.Role,
+		Department:    user.Department,
+		Language:      user.Language,
+		Authenticated: true,
+		IsAdmin:       user.Role == "admin",
+	}
+}
+
+// visibilityFilter restricts procedures to those the viewer may see
+func visibilityFilter(viewer models.Viewer) bson.M {
+	if viewer.IsAdmin {
+		return bson.M{}
+	}
+
+	public := bson.M{"visibility": bson.M{"$in": bson.A{models.VisibilityPublic, "", nil}}}
+	if !viewer.Authenticated {
+		return public
+	}
+
+	allowed := []bson.M{
+		public,
+		{"visibility": models.VisibilityAuthenticated},
+	}
+	var grants []bson.M
+	if viewer.Department != "" {
+		grants = append(grants, bson.M{"visible_departments": viewer.Department})
+	}
+	if viewer.Role != "" {
+		grants = append(grants, bson.M{"visible_roles": viewer.Role})
+	}
+	if len(grants) > 0 {
+		allowed = append(allowed, bson.M{"visibility": models.VisibilityRestricted, "$or": grants})
+	}
+	return bson.M{"$or": allowed}
+}
+
+// visibleTo restricts a filter to published procedures the viewer may see
+func visibleTo(filter bson.M, viewer models.Viewer) bson.M {
+	published := publishedOnly(filter)
+	visibility := visibilityFilter(viewer)
+	if len(visibility) == 0 {
+		return published
+	}
+	return bson.M{"$and": []bson.M{published, visibility}}
+}
+
+// CanView reports whether the viewer may see a procedure with the given access settings
+func CanView(access models.ProcedureAccess, viewer models.Viewer) bool {
+	if viewer.IsAdmin {
+		return true
+	}
+	switch access.Visibility {
+	case "", models.VisibilityPublic:
+		return true
+	case models.VisibilityAuthenticated:
+		return viewer.Authenticated
+	case models.VisibilityRestricted:
+		if !viewer.Authenticated {
+			return false
+		}
+		return (viewer.Department != "" && containsString(access.VisibleDepartments, viewer.Department)) ||
+			(viewer.Role != "" && containsString(access.VisibleRoles, viewer.Role))
+	default:
+		return false
+	}
+}
+
+// normalizeAccess validates visibility settings and drops empty and duplicate entries
+func normalizeAccess(access *models.ProcedureAccess) error {
+	access.Visibility = strings.TrimSpace(access.Visibility)
+	access.VisibleDepartments = cleanList(access.VisibleDepartments)
+	access.VisibleRoles = cleanList(access.VisibleRoles)
+
+	switch access.Visibility {
+	case "", models.VisibilityPublic, models.VisibilityAuthenticated:
+		access.VisibleDepartments = nil
+		access.VisibleRoles = nil
+	case models.VisibilityRestricted:
+		if len(access.VisibleDepartments) == 0 && len(access.VisibleRoles) == 0 {
+			return fmt.Errorf("%w: restricted procedures need at least one department or role", ErrInvalidVisibility)
+		}
+	default:
+		return fmt.Errorf("%w: visibility must be public, authenticated or restricted", ErrInvalidVisibility)
+	}
+	return nil
+}
+
+func cleanList(values []string) []string {
+	var cleaned []string
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		if v != "" && !containsString(cleaned, v) {
+			cleaned = append(cleaned, v)
+		}
+	}
+	return cleaned
+}
+
+// UpdateProcedureVisibility changes who can see a procedure
+func UpdateProcedureVisibility(id string, req models.UpdateVisibilityRequest, actorID string) (*models.Procedure, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid procedure ID")
+	}
+
+	access := models.ProcedureAccess{
+		Visibility:         req.Visibility,
+		VisibleDepartments: req.VisibleDepartments,
+		VisibleRoles:       req.VisibleRoles,
+	}
+	if err := normalizeAccess(&access); err != nil {
+		return nil, err
+	}
+
+	set := bson.M{"visibility": access.Visibility}
+	unset := bson.M{}
+	if len(access.VisibleDepartments) > 0 {
+		set["visible_departments"] = access.VisibleDepartments
+	} else {
+		unset["visible_departments"] = ""
+	}
+	if len(access.VisibleRoles) > 0 {
+		set["visible_roles"] = access.VisibleRoles
+	} else {
+		unset["visible_roles"] = ""
+	}
+	set["updated_at"] = time.Now()
+	set["updated_by"] = actorID
+
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := ensureBaselineRevision(ctx, objID); err != nil {
+		return nil, err
+	}
+	if err := updateProcedureFields(objID, update); err != nil {
+		return nil, err
+	}
+
+	InvalidateSuggestIndex()
+	updated, err := GetProcedureByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := recordRevision(ctx, updated, models.RevisionActionAccess, actorID, 0); err != nil {
+		fmt.Printf("⚠️ Failed to record revision for procedure %s: %v\n", id, err)
+	}
+	EmitWebhookEvent(models.WebhookEventProcedureUpdated, procedureWebhookData(updated, models.RevisionActionAccess, actorID))
+	return updated, nil
+}
@@ -0,0 +1,600 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"web_AI/config"
+	"web_AI/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrInvalidWebhook   = errors.New("invalid webhook")
+	ErrDeliveryNotFound = errors.New("delivery not found")
+)
+
+var errWebhookDisabled = errors.New("webhook is disabled")
+
+// Headers sent with every delivery. The signature is "sha256=" followed by the hex HMAC-SHA256
+// of "<timestamp>.<body>" keyed with the webhook secret; see VerifyWebhookSignature.
+const (
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+const (
+	webhookTimeout      = 10 * time.Second
+	webhookRetryBase    = 30 * time.Second // first retry; each next one waits twice as long
+	webhookDeliveryLock = 2 * time.Minute  // how long an attempt owns a delivery
+)
+
+var webhookClient = &http.Client{Timeout: webhookTimeout}
+
+// webhookMaxAttempts is how often a delivery is tried before it fails (WEBHOOK_MAX_ATTEMPTS, default 6,
+// which retries for about 15 minutes)
+func webhookMaxAttempts() int {
+	if attempts, err := strconv.Atoi(os.Getenv("WEBHOOK_MAX_ATTEMPTS")); err == nil && attempts > 0 {
+		return attempts
+	}
+	return 6
+}
+
+// EnsureWebhookIndexes creates the indexes the retry worker and delivery log use
+func EnsureWebhookIndexes() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := config.GetCollection("webhook_deliveries").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_attempt_at", Value: 1}}},
+		{Keys: bson.D{{Key: "webhook_id", Value: 1}, {Key: "created_at", Value: -1}}},
+	})
+	return err
+}
+
+// validateWebhook checks the URL and events of a webhook
+func validateWebhook(webhook *models.Webhook) error {
+	webhook.URL = strings.TrimSpace(webhook.URL)
+	u, err := url.Parse(webhook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidWebhook)
+	}
+
+	if len(webhook.Events) == 0 {
+		return fmt.Errorf("%w: subscribe to at least one event", ErrInvalidWebhook)
+	}
+	var events []string
+	for _, event := range webhook.Events {
+		event = strings.TrimSpace(event)
+		if event != "*" && !containsString(models.WebhookEvents, event) {
+			return fmt.Errorf("%w: unknown event '%s'; events are %s or *", ErrInvalidWebhook, event, strings.Join(models.WebhookEvents, ", "))
+		}
+		if !containsString(events, event) {
+			events = append(events, event)
+		}
+	}
+	webhook.Events = events
+	return nil
+}
+
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// CreateWebhook adds a subscription; the returned webhook is the only one that includes the secret
+func CreateWebhook(req models.CreateWebhookRequest, actorID string) (*models.Webhook, error) {
+	collection := config.GetCollection("webhooks")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	webhook := &models.Webhook{
+		ID:          primitive.NewObjectID(),
+		URL:         req.URL,
+		Events:      req.Events,
+		Description: strings.TrimSpace(req.Description),
+		Secret:      strings.TrimSpace(req.Secret),
+		Active:      req.Active == nil || *req.Active,
+		CreatedBy:   actorID,
+		CreatedAt:   time.Now(),
+	}
+	webhook.UpdatedAt = webhook.CreatedAt
+	if err := validateWebhook(webhook); err != nil {
+		return nil, err
+	}
+	if webhook.Secret == "" {
+		secret, err := generateWebhookSecret()
+		if err != nil {
+			return nil, err
+		}
+		webhook.Secret = secret
+	}
+
+	if _, err := collection.InsertOne(ctx, webhook); err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// GetWebhooks lists all webhooks without their secrets
+func GetWebhooks() ([]models.Webhook, error) {
+	collection := config.GetCollection("webhooks")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{bson.E{Key: "created_at", Value: 1}})
+	cursor, err := collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	webhooks := []models.Webhook{}
+	if err = cursor.All(ctx, &webhooks); err != nil {
+		return nil, err
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	return webhooks, nil
+}
+
+// GetWebhook returns a webhook without its secret
+func GetWebhook(id string) (*models.Webhook, error) {
+	webhook, err := findWebhook(id)
+	if err != nil {
+		return nil, err
+	}
+	webhook.Secret = ""
+	return webhook, nil
+}
+
+func findWebhook(id string) (*models.Webhook, error) {
+	collection := config.GetCollection("webhooks")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrWebhookNotFound
+	}
+
+	var webhook models.Webhook
+	err = collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&webhook)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrWebhookNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// UpdateWebhook changes the URL, events, description or active flag of a webhook
+func UpdateWebhook(id string, req models.UpdateWebhookRequest) (*models.Webhook, error) {
+	collection := config.GetCollection("webhooks")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	webhook, err := findWebhook(id)
+	if err != nil {
+		return nil, err
+	}
+	if req.URL != nil {
+		webhook.URL = *req.URL
+	}
+	if req.Events != nil {
+		webhook.Events = req.Events
+	}
+	if req.Description != nil {
+		webhook.Description = strings.TrimSpace(*req.Description)
+	}
+	if req.Active != nil {
+		webhook.Active = *req.Active
+	}
+	if err := validateWebhook(webhook); err != nil {
+		return nil, err
+	}
+	webhook.UpdatedAt = time.Now()
+
+	_, err = collection.UpdateOne(ctx, bson.M{"_id": webhook.ID}, bson.M{"$set": bson.M{
+		"url":         webhook.URL,
+		"events":      webhook.Events,
+		"description": webhook.Description,
+		"active":      webhook.Active,
+		"updated_at":  webhook.UpdatedAt,
+	}})
+	if err != nil {
+		return nil, err
+	}
+
+	webhook.Secret = ""
+	return webhook, nil
+}
+
+// DeleteWebhook removes a webhook and its delivery log
+func DeleteWebhook(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	webhook, err := findWebhook(id)
+	if err != nil {
+		return err
+	}
+	if _, err := config.GetCollection("webhooks").DeleteOne(ctx, bson.M{"_id": webhook.ID}); err != nil {
+		return err
+	}
+	if _, err := config.GetCollection("webhook_deliveries").DeleteMany(ctx, bson.M{"webhook_id": webhook.ID}); err != nil {
+		fmt.Printf("⚠️ Failed to delete deliveries of webhook %s: %v\n", id, err)
+	}
+	return nil
+}
+
+// EmitWebhookEvent queues an event for every active webhook subscribed to it and delivers it in the
+// background; failed deliveries are retried by the webhook worker
+func EmitWebhookEvent(event string, data interface{}) {
+	payload := models.WebhookPayload{
+		ID:        primitive.NewObjectID().Hex(),
+		Event:     event,
+		CreatedAt: time.Now(),
+		Data:      data,
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		cursor, err := config.GetCollection("webhooks").Find(ctx, bson.M{"active": true, "events": bson.M{"$in": bson.A{event, "*"}}})
+		if err != nil {
+			fmt.Printf("⚠️ Failed to load webhooks for %s: %v\n", event, err)
+			return
+		}
+		var webhooks []models.Webhook
+		if err := cursor.All(ctx, &webhooks); err != nil {
+			fmt.Printf("⚠️ Failed to load webhooks for %s: %v\n", event, err)
+			return
+		}
+
+		for i := range webhooks {
+			delivery, err := queueDelivery(ctx, &webhooks[i], payload, nil)
+			if err != nil {
+				fmt.Printf("⚠️ Failed to queue %s for webhook %s: %v\n", event, webhooks[i].ID.Hex(), err)
+				continue
+			}
+			go attemptDelivery(delivery.ID)
+		}
+	}()
+}
+
+// queueDelivery stores a pending delivery of payload to webhook, due immediately
+func queueDelivery(ctx context.Context, webhook *models.Webhook, payload models.WebhookPayload, redeliveryOf *primitive.ObjectID) (*models.WebhookDelivery, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	delivery := &models.WebhookDelivery{
+		ID:            primitive.NewObjectID(),
+		WebhookID:     webhook.ID,
+		Event:         payload.Event,
+		EventID:       payload.ID,
+		Payload:       string(body),
+		Status:        models.DeliveryStatusPending,
+		NextAttemptAt: &now,
+		RedeliveryOf:  redeliveryOf,
+		CreatedAt:     now,
+	}
+	if _, err := config.GetCollection("webhook_deliveries").InsertOne(ctx, delivery); err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+// PingWebhook sends a test event to a webhook, whether or not it is active, and returns the delivery
+func PingWebhook(id string) (*models.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	webhook, err := findWebhook(id)
+	if err != nil {
+		return nil, err
+	}
+	payload := models.WebhookPayload{
+		ID:        primitive.NewObjectID().Hex(),
+		Event:     models.WebhookEventPing,
+		CreatedAt: time.Now(),
+		Data:      map[string]interface{}{"webhook_id": webhook.ID, "events": webhook.Events},
+	}
+	delivery, err := queueDelivery(ctx, webhook, payload, nil)
+	if err != nil {
+		return nil, err
+	}
+	attemptDelivery(delivery.ID)
+	return findDelivery(delivery.ID.Hex())
+}
+
+// RedeliverWebhookDelivery sends the payload of an earlier delivery again as a new delivery
+func RedeliverWebhookDelivery(id string) (*models.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	original, err := findDelivery(id)
+	if err != nil {
+		return nil, err
+	}
+	webhook, err := findWebhook(original.WebhookID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	delivery := &models.WebhookDelivery{
+		ID:            primitive.NewObjectID(),
+		WebhookID:     webhook.ID,
+		Event:         original.Event,
+		EventID:       original.EventID,
+		Payload:       original.Payload,
+		Status:        models.DeliveryStatusPending,
+		NextAttemptAt: &now,
+		RedeliveryOf:  &original.ID,
+		CreatedAt:     now,
+	}
+	if _, err := config.GetCollection("webhook_deliveries").InsertOne(ctx, delivery); err != nil {
+		return nil, err
+	}
+
+	attemptDelivery(delivery.ID)
+	return findDelivery(delivery.ID.Hex())
+}
+
+// GetWebhookDelivery returns one delivery with its payload and last response
+func GetWebhookDelivery(id string) (*models.WebhookDelivery, error) {
+	return findDelivery(id)
+}
+
+func findDelivery(id string) (*models.WebhookDelivery, error) {
+	collection := config.GetCollection("webhook_deliveries")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrDeliveryNotFound
+	}
+
+	var delivery models.WebhookDelivery
+	err = collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&delivery)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrDeliveryNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// GetWebhookDeliveries lists the latest deliveries of a webhook, optionally with one status
+func GetWebhookDeliveries(id string, status string, limit int64) ([]models.WebhookDelivery, error) {
+	collection := config.GetCollection("webhook_deliveries")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	webhook, err := findWebhook(id)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"webhook_id": webhook.ID}
+	if status != "" {
+		filter["status"] = status
+	}
+	opts := options.Find().SetSort(bson.D{bson.E{Key: "created_at", Value: -1}}).SetLimit(limit)
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	deliveries := []models.WebhookDelivery{}
+	if err = cursor.All(ctx, &deliveries); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// SignWebhookPayload returns the signature header value for a body sent at timestamp (Unix seconds)
+func SignWebhookPayload(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature checks a received signature in constant time; receivers should also reject old timestamps
+func VerifyWebhookSignature(secret string, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(SignWebhookPayload(secret, timestamp, body)), []byte(signature))
+}
+
+// attemptDelivery sends a due delivery once and records the outcome, scheduling a retry with
+// exponential backoff on failure. It does nothing if the delivery is not due or another attempt owns it.
+func attemptDelivery(id primitive.ObjectID) {
+	collection := config.GetCollection("webhook_deliveries")
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout+10*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	var delivery models.WebhookDelivery
+	err := collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": id, "status": models.DeliveryStatusPending, "next_attempt_at": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"next_attempt_at": now.Add(webhookDeliveryLock)}},
+	).Decode(&delivery)
+	if err == mongo.ErrNoDocuments {
+		return
+	} else if err != nil {
+		fmt.Printf("⚠️ Failed to load webhook delivery %s: %v\n", id.Hex(), err)
+		return
+	}
+
+	set := bson.M{"last_attempt_at": now}
+	unset := bson.M{}
+	attempts := delivery.Attempts + 1
+	set["attempts"] = attempts
+
+	webhook, err := findWebhook(delivery.WebhookID.Hex())
+	if err == nil && !webhook.Active && delivery.Event != models.WebhookEventPing {
+		err = errWebhookDisabled
+	}
+	status, body := 0, ""
+	if err == nil {
+		status, body, err = sendWebhook(ctx, webhook, &delivery)
+	}
+
+	switch {
+	case err == nil:
+		set["status"] = models.DeliveryStatusSucceeded
+		set["delivered_at"] = time.Now()
+		unset["next_attempt_at"] = ""
+		unset["error"] = ""
+	case attempts >= webhookMaxAttempts() || errors.Is(err, ErrWebhookNotFound) || errors.Is(err, errWebhookDisabled):
+		set["status"] = models.DeliveryStatusFailed
+		set["error"] = err.Error()
+		unset["next_attempt_at"] = ""
+	default:
+		set["error"] = err.Error()
+		set["next_attempt_at"] = time.Now().Add(webhookRetryDelay(attempts))
+	}
+	if status != 0 {
+		set["response_status"] = status
+		set["response_body"] = truncateText(body, 1000)
+	}
+
+	update := bson.M{"$set": set}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	if _, err := collection.UpdateOne(ctx, bson.M{"_id": delivery.ID}, update); err != nil {
+		fmt.Printf("⚠️ Failed to record webhook delivery %s: %v\n", delivery.ID.Hex(), err)
+	}
+}
+
+// webhookRetryDelay is the wait after the given failed attempt: 30s, 1m, 2m, ... up to a day
+func webhookRetryDelay(attempts int) time.Duration {
+	delay := webhookRetryBase
+	for i := 1; i < attempts && delay < 24*time.Hour; i++ {
+		delay *= 2
+	}
+	if delay > 24*time.Hour {
+		delay = 24 * time.Hour
+	}
+	return delay
+}
+
+// sendWebhook posts the signed payload; any status outside 2xx is an error
+func sendWebhook(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) (int, string, error) {
+	body := []byte(delivery.Payload)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "web_AI-Webhooks/1.0")
+	req.Header.Set(WebhookEventHeader, delivery.Event)
+	req.Header.Set(WebhookDeliveryHeader, delivery.ID.Hex())
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(webhook.Secret, timestamp, body))
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, string(respBody), fmt.Errorf("receiver answered %s", resp.Status)
+	}
+	return resp.StatusCode, string(respBody), nil
+}
+
+// StartWebhookRetryWorker periodically retries deliveries whose next attempt is due
+func StartWebhookRetryWorker(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := retryDueDeliveries(); err != nil {
+				fmt.Printf("⚠️ Webhook retry failed: %v\n", err)
+			}
+		}
+	}()
+}
+
+func retryDueDeliveries() error {
+	collection := config.GetCollection("webhook_deliveries")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetProjection(bson.M{"_id": 1}).SetLimit(100)
+	cursor, err := collection.Find(ctx, bson.M{"status": models.DeliveryStatusPending, "next_attempt_at": bson.M{"$lte": time.Now()}}, opts)
+	if err != nil {
+		return err
+	}
+	var due []models.WebhookDelivery
+	if err := cursor.All(ctx, &due); err != nil {
+		return err
+	}
+
+	for _, delivery := range due {
+		attemptDelivery(delivery.ID)
+	}
+	return nil
+}
+
+// procedureWebhookData describes a procedure in webhook payloads
+func procedureWebhookData(procedure *models.Procedure, action string, actorID string) models.WebhookProcedure {
+	return models.WebhookProcedure{
+		ID:          procedure.ID,
+		Title:       procedure.Title,
+		Category:    procedure.Category,
+		Status:      procedure.Status,
+		Version:     procedure.Version,
+		Action:      action,
+		PublishedAt: procedure.PublishedAt,
+		ActorID:     actorID,
+	}
+}
+
+// userWebhookData describes a user in webhook payloads, without the password
+func userWebhookData(user *models.User) models.WebhookUser {
+	return models.WebhookUser{
+		ID:         user.ID,
+		Name:       user.Name,
+		Email:      user.Email,
+		Role:       user.Role,
+		Department: user.Department,
+	}
+}
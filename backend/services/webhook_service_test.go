@@ -0,0 +1,60 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	secret, timestamp, body := "s3cret", "1700000000", []byte(`{"event":"procedure.published"}`)
+	signature := SignWebhookPayload(secret, timestamp, body)
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		body      string
+		signature string
+		want      bool
+	}{
+		{name: "valid", secret: secret, timestamp: timestamp, body: string(body), signature: signature, want: true},
+		{name: "changed body", secret: secret, timestamp: timestamp, body: `{"event":"procedure.deleted"}`, signature: signature},
+		{name: "replayed with a new timestamp", secret: secret, timestamp: "1700000060", body: string(body), signature: signature},
+		{name: "other secret", secret: "other", timestamp: timestamp, body: string(body), signature: signature},
+		{name: "missing prefix", secret: secret, timestamp: timestamp, body: string(body), signature: signature[len("sha256="):]},
+		{name: "empty signature", secret: secret, timestamp: timestamp, body: string(body)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyWebhookSignature(tt.secret, tt.timestamp, []byte(tt.body), tt.signature); got != tt.want {
+				t.Errorf("VerifyWebhookSignature = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Receivers in other languages check against the same HMAC-SHA256 of "timestamp.body"
+	const want = "sha256=1ba6b8171186efc613e8bcc0cbdab2748f24984d7c5a84faa2637afa0e40d224"
+	if got := SignWebhookPayload("key", "1", []byte("{}")); got != want {
+		t.Errorf("SignWebhookPayload = %q, want %q", got, want)
+	}
+}
+
+func TestWebhookRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{attempts: 1, want: 30 * time.Second},
+		{attempts: 2, want: time.Minute},
+		{attempts: 3, want: 2 * time.Minute},
+		{attempts: 4, want: 4 * time.Minute},
+		{attempts: 12, want: 30 * time.Second << 11},
+		{attempts: 13, want: 24 * time.Hour},
+		{attempts: 100, want: 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := webhookRetryDelay(tt.attempts); got != tt.want {
+			t.Errorf("webhookRetryDelay(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
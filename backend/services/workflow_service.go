@@ -0,0 +1,285 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"web_AI/config"
+	"web_AI/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	// ErrInvalidTransition is returned when a workflow action is not allowed from the current status
+	ErrInvalidTransition = errors.New("invalid workflow transition")
+	// ErrNotAssignedReviewer is returned when someone other than the assigned reviewer approves or rejects
+	ErrNotAssignedReviewer = errors.New("only the assigned reviewer can do this")
+	// ErrSelfApproval is returned when the author of the changes under review tries to approve them
+	ErrSelfApproval = errors.New("changes must be approved by someone other than their author")
+	// ErrAuthorAsReviewer is returned when an author of the changes is named as their reviewer
+	ErrAuthorAsReviewer = errors.New("the reviewer must be someone other than an author of the changes")
+)
+
+// workflowTransitions maps each action to the statuses it can start from and the status it leads to
+var workflowTransitions = map[string]struct {
+	from []string
+	to   string
+}{
+	models.WorkflowActionSubmit:  {from: []string{models.ProcedureStatusDraft}, to: models.ProcedureStatusInReview},
+	models.WorkflowActionApprove: {from: []string{models.ProcedureStatusInReview}, to: models.ProcedureStatusPublished},
+	models.WorkflowActionReject:  {from: []string{models.ProcedureStatusInReview}, to: models.ProcedureStatusDraft},
+	models.WorkflowActionArchive: {from: []string{models.ProcedureStatusDraft, models.ProcedureStatusPublished, ""}, to: models.ProcedureStatusArchived},
+	models.WorkflowActionReopen:  {from: []string{models.ProcedureStatusArchived}, to: models.ProcedureStatusDraft},
+}
+
+// TransitionProcedure applies a workflow action (submit, approve, reject, archive, reopen) to a procedure
+func TransitionProcedure(id string, action string, req models.WorkflowActionRequest, actorID string) (*models.Procedure, error) {
+	transition, ok := workflowTransitions[action]
+	if !ok {
+		return nil, fmt.Errorf("unknown workflow action '%s'", action)
+	}
+
+	procedure, err := GetProcedureByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !containsString(transition.from, procedure.Status) {
+		current := procedure.Status
+		if current == "" {
+			current = models.ProcedureStatusPublished
+		}
+		return nil, fmt.Errorf("%w: cannot %s a procedure that is %s", ErrInvalidTransition, action, current)
+	}
+
+	comment := strings.TrimSpace(req.Comment)
+	now := time.Now()
+	set := bson.M{
+		"status":     transition.to,
+		"updated_at": now,
+		"updated_by": actorID,
+	}
+	unset := bson.M{}
+
+	switch action {
+	case models.WorkflowActionSubmit:
+		reviewerID := req.ReviewerID
+		if reviewerID == "" {
+			reviewerID = procedure.ReviewerID
+		}
+		if reviewerID != "" {
+			// The submitter becomes an author of the changes under review
+			authors, err := procedureAuthors(procedure)
+			if err != nil {
+				return nil, err
+			}
+			if reviewerID == actorID || containsString(authors, reviewerID) {
+				return nil, ErrAuthorAsReviewer
+			}
+		}
+		if req.ReviewerID != "" {
+			set["reviewer_id"] = req.ReviewerID
+		}
+	case models.WorkflowActionApprove, models.WorkflowActionReject:
+		if procedure.ReviewerID != "" && procedure.ReviewerID != actorID {
+			return nil, ErrNotAssignedReviewer
+		}
+		// Also checked with an assigned reviewer, since authors can name themselves
+		if action == models.WorkflowActionApprove {
+			authors, err := procedureAuthors(procedure)
+			if err != nil {
+				return nil, err
+			}
+			if containsString(authors, actorID) {
+				return nil, ErrSelfApproval
+			}
+		}
+		if action == models.WorkflowActionReject && comment == "" {
+			return nil, fmt.Errorf("a comment is required when rejecting")
+		}
+		if action == models.WorkflowActionApprove {
+			publishedAt := now
+			if req.PublishAt != nil && req.PublishAt.After(now) {
+				publishedAt = *req.PublishAt
+				set["publish_at"] = publishedAt
+				set["publish_pending"] = true
+			} else {
+				unset["publish_at"] = ""
+				unset["publish_pending"] = ""
+			}
+			set["published_at"] = publishedAt
+			// Approving checks the content, so it also counts as a periodic review
+			set["last_reviewed_at"] = now
+			unset["stale_notified_at"] = ""
+		}
+	}
+
+	update := bson.M{
+		"$set": set,
+		"$inc": bson.M{"version": 1},
+		"$push": bson.M{"review_comments": models.ReviewComment{
+			AuthorID:  actorID,
+			Action:    action,
+			Comment:   comment,
+			CreatedAt: now,
+		}},
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	collection := config.GetCollection("procedures")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Match on the status we validated against so concurrent transitions cannot both succeed
+	filter := bson.M{"_id": procedure.ID, "status": statusMatch(procedure.Status), "deleted_at": nil}
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		return nil, fmt.Errorf("%w: procedure status changed, please reload", ErrInvalidTransition)
+	}
+
+	InvalidateSuggestIndex()
+	updated, err := GetProcedureByID(id)
+	if err != nil {
+		return nil, err
+	}
+	// A scheduled publication is announced by the publish scheduler once readers can see it
+	if action == models.WorkflowActionApprove && !updated.PublishPending {
+		EmitWebhookEvent(models.WebhookEventProcedurePublished, procedureWebhookData(updated, action, actorID))
+	}
+	return updated, nil
+}
+
+// StartPublishScheduler announces scheduled publications on every interval, once their publish_at has passed
+func StartPublishScheduler(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := announceDuePublications(); err != nil {
+				fmt.Printf("⚠️ Scheduled publication check failed: %v\n", err)
+			}
+		}
+	}()
+}
+
+// announceDuePublications sends procedure.published for approved procedures that just became visible.
+// Each one is claimed by clearing its flag, so it is announced once even with several servers.
+func announceDuePublications() error {
+	collection := config.GetCollection("procedures")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	filter := bson.M{
+		"publish_pending": true,
+		"publish_at":      bson.M{"$lte": time.Now()},
+		"status":          models.ProcedureStatusPublished,
+		"deleted_at":      nil,
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	for {
+		var procedure models.Procedure
+		err := collection.FindOneAndUpdate(ctx, filter, bson.M{"$unset": bson.M{"publish_pending": ""}}, opts).Decode(&procedure)
+		if err == mongo.ErrNoDocuments {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		InvalidateSuggestIndex()
+		EmitWebhookEvent(models.WebhookEventProcedurePublished, procedureWebhookData(&procedure, models.WorkflowActionApprove, procedure.UpdatedBy))
+	}
+}
+
+// procedureAuthors returns who is responsible for the changes under review: whoever last submitted the
+// procedure and whoever wrote its latest revision
+func procedureAuthors(procedure *models.Procedure) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var authors []string
+	for i := len(procedure.ReviewComments) - 1; i >= 0; i-- {
+		if procedure.ReviewComments[i].Action == models.WorkflowActionSubmit {
+			authors = append(authors, procedure.ReviewComments[i].AuthorID)
+			break
+		}
+	}
+	latest, err := latestRevision(ctx, procedure.ID)
+	if err != nil {
+		return nil, err
+	}
+	if latest != nil {
+		authors = append(authors, latest.AuthorID)
+	} else if !procedure.CreatedBy.IsZero() {
+		authors = append(authors, procedure.CreatedBy.Hex())
+	}
+	return authors, nil
+}
+
+// AssignProcedureReviewer sets the reviewer of a procedure that is not yet published
+func AssignProcedureReviewer(id string, reviewerID string, actorID string) (*models.Procedure, error) {
+	collection := config.GetCollection("procedures")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	procedure, err := GetProcedureByID(id)
+	if err != nil {
+		return nil, err
+	}
+	authors, err := procedureAuthors(procedure)
+	if err != nil {
+		return nil, err
+	}
+	if containsString(authors, reviewerID) {
+		return nil, ErrAuthorAsReviewer
+	}
+
+	filter := bson.M{
+		"_id":        procedure.ID,
+		"status":     bson.M{"$in": bson.A{models.ProcedureStatusDraft, models.ProcedureStatusInReview}},
+		"deleted_at": nil,
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"reviewer_id": reviewerID,
+			"updated_at":  time.Now(),
+			"updated_by":  actorID,
+		},
+		"$inc": bson.M{"version": 1},
+	}
+	result, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		return nil, fmt.Errorf("%w: reviewers can only be assigned to draft or in-review procedures", ErrInvalidTransition)
+	}
+
+	return GetProcedureByID(id)
+}
+
+// statusMatch builds a filter value that also matches legacy documents without a status
+func statusMatch(status string) interface{} {
+	if status == "" {
+		return nil
+	}
+	return status
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
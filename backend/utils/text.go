@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// RemoveDiacritics strips Vietnamese (and other Latin) accents, e.g. "Bảo hiểm" -> "Bao hiem"
+func RemoveDiacritics(s string) string {
+	var sb strings.Builder
+	sb.Grow(len(s))
+	for _, r := range norm.NFD.String(s) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		switch r {
+		case 'đ':
+			r = 'd'
+		case 'Đ':
+			r = 'D'
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// NormalizeText lowercases, strips diacritics and collapses everything that is not a letter or digit into single spaces
+func NormalizeText(s string) string {
+	s = strings.ToLower(RemoveDiacritics(s))
+	var sb strings.Builder
+	sb.Grow(len(s))
+	space := true
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			sb.WriteRune(r)
+			space = false
+			continue
+		}
+		if !space {
+			sb.WriteByte(' ')
+			space = true
+		}
+	}
+	return strings.TrimSpace(sb.String())
+}
+
+// Tokenize returns the normalized words of s
+func Tokenize(s string) []string {
+	return strings.Fields(NormalizeText(s))
+}
+
+// LevenshteinDistance returns the edit distance between a and b
+func LevenshteinDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// PrefixDistance returns the smallest edit distance between prefix and any prefix of word,
+// which lets "bao hie" or a mistyped "bao hei" still match "bao hiem"
+func PrefixDistance(prefix, word string) int {
+	rp, rw := []rune(prefix), []rune(word)
+	prev := make([]int, len(rw)+1)
+	curr := make([]int, len(rw)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(rp); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rw); j++ {
+			cost := 1
+			if rp[i-1] == rw[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	best := prev[0]
+	for _, d := range prev[1:] {
+		if d < best {
+			best = d
+		}
+	}
+	return best
+}